@@ -0,0 +1,535 @@
+package config
+
+import (
+	"bytes"
+	"crypto/rsa"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/karanbhomiagit/order-service/models"
+	"github.com/karanbhomiagit/order-service/order/logging"
+	"github.com/karanbhomiagit/order-service/order/tracing"
+	"gopkg.in/yaml.v3"
+)
+
+//Config holds every setting of the service. Each one is read, by increasing precedence, from its default,
+//the YAML or TOML file, the environment variable named after it, e.g. PAGE_SIZE, and the command line
+//flag, e.g. -page-size. In the file it is named in lower case, e.g. page_size.
+type Config struct {
+	//File is the YAML or TOML file the settings were read from, if any
+	File string `yaml:"-" toml:"-"`
+
+	Server     `yaml:",inline"`
+	Telemetry  `yaml:",inline"`
+	Mongo      `yaml:",inline"`
+	Orders     `yaml:",inline"`
+	Expiry     `yaml:",inline"`
+	Outbox     `yaml:",inline"`
+	Webhooks   `yaml:",inline"`
+	Auth       `yaml:",inline"`
+	JWT        `yaml:",inline"`
+	RateLimits `yaml:",inline"`
+	GraphQL    `yaml:",inline"`
+	Readiness  `yaml:",inline"`
+	Migrations `yaml:",inline"`
+}
+
+//Server configures the http and gRPC servers
+type Server struct {
+	Port                int           `yaml:"port" toml:"port"`
+	GRPCPort            int           `yaml:"grpc_port" toml:"grpc_port"`
+	HTTPReadTimeout     time.Duration `yaml:"http_read_timeout" toml:"http_read_timeout"`
+	HTTPWriteTimeout    time.Duration `yaml:"http_write_timeout" toml:"http_write_timeout"`
+	HTTPIdleTimeout     time.Duration `yaml:"http_idle_timeout" toml:"http_idle_timeout"`
+	ShutdownDelay       time.Duration `yaml:"shutdown_delay" toml:"shutdown_delay"`
+	ShutdownGracePeriod time.Duration `yaml:"shutdown_grace_period" toml:"shutdown_grace_period"`
+	//UnversionedSunset is when the unversioned paths stop being served, 6 months after their deprecation when zero
+	UnversionedSunset Date `yaml:"unversioned_sunset" toml:"unversioned_sunset"`
+}
+
+//Telemetry configures the logs and traces
+type Telemetry struct {
+	LogLevel      string `yaml:"log_level" toml:"log_level"`
+	TraceExporter string `yaml:"trace_exporter" toml:"trace_exporter"`
+}
+
+//Mongo configures the connection to the database
+type Mongo struct {
+	MongoURL string `yaml:"mongodb_url" toml:"mongodb_url"`
+	//DatabaseName defaults to the database of the url
+	DatabaseName        string        `yaml:"database_name" toml:"database_name"`
+	MongoPoolLimit      int           `yaml:"mongodb_pool_limit" toml:"mongodb_pool_limit"`
+	MongoConnectTimeout time.Duration `yaml:"mongodb_connect_timeout" toml:"mongodb_connect_timeout"`
+	MongoConnectBackoff time.Duration `yaml:"mongodb_connect_backoff" toml:"mongodb_connect_backoff"`
+	MongoSocketTimeout  time.Duration `yaml:"mongodb_socket_timeout" toml:"mongodb_socket_timeout"`
+	//MongoWriteConcern is the number of members acknowledging the writes, or a mode such as majority
+	MongoWriteConcern string        `yaml:"mongodb_write_concern" toml:"mongodb_write_concern"`
+	MongoWriteTimeout time.Duration `yaml:"mongodb_write_timeout" toml:"mongodb_write_timeout"`
+}
+
+//Google configures the Distance Matrix API of Google
+type Google struct {
+	GoogleAPIKey string `yaml:"google_api_key" toml:"google_api_key"`
+	//GoogleServerURL replaces the url of the Google APIs, e.g. with a stub
+	GoogleServerURL string `yaml:"google_server_url" toml:"google_server_url"`
+}
+
+//Orders configures the order usecase and the feed of order events
+type Orders struct {
+	PageSize         int `yaml:"page_size" toml:"page_size"`
+	StreamReplaySize int `yaml:"stream_replay_size" toml:"stream_replay_size"`
+	Google           `yaml:",inline"`
+}
+
+//Expiry configures the expiry worker
+type Expiry struct {
+	OrderTTL        time.Duration `yaml:"order_ttl" toml:"order_ttl"`
+	ExpiryInterval  time.Duration `yaml:"expiry_interval" toml:"expiry_interval"`
+	ExpiryBatchSize int           `yaml:"expiry_batch_size" toml:"expiry_batch_size"`
+}
+
+//Outbox configures the outbox relay
+type Outbox struct {
+	OutboxRelayInterval time.Duration `yaml:"outbox_relay_interval" toml:"outbox_relay_interval"`
+	OutboxBatchSize     int           `yaml:"outbox_batch_size" toml:"outbox_batch_size"`
+	OutboxMaxAttempts   int           `yaml:"outbox_max_attempts" toml:"outbox_max_attempts"`
+}
+
+//Webhooks configures the delivery of webhooks
+type Webhooks struct {
+	WebhookInterval    time.Duration `yaml:"webhook_interval" toml:"webhook_interval"`
+	WebhookBatchSize   int           `yaml:"webhook_batch_size" toml:"webhook_batch_size"`
+	WebhookMaxAttempts int           `yaml:"webhook_max_attempts" toml:"webhook_max_attempts"`
+	WebhookBackoff     time.Duration `yaml:"webhook_backoff" toml:"webhook_backoff"`
+	//WebhookAllowPrivate lets webhooks reach private, loopback and link-local addresses, for local development
+	WebhookAllowPrivate bool `yaml:"webhook_allow_private" toml:"webhook_allow_private"`
+}
+
+//Auth configures the API keys
+type Auth struct {
+	AdminAPIKey string `yaml:"admin_api_key" toml:"admin_api_key"`
+}
+
+//JWT configures the verification of the bearer tokens of end users, which are not accepted unless a secret
+//or a key set is set
+type JWT struct {
+	Secret       string        `yaml:"jwt_secret" toml:"jwt_secret"`
+	JWKSFile     string        `yaml:"jwt_jwks_file" toml:"jwt_jwks_file"`
+	JWKSURL      string        `yaml:"jwt_jwks_url" toml:"jwt_jwks_url"`
+	JWKSCacheTTL time.Duration `yaml:"jwt_jwks_cache_ttl" toml:"jwt_jwks_cache_ttl"`
+	Issuer       string        `yaml:"jwt_issuer" toml:"jwt_issuer"`
+	Audience     string        `yaml:"jwt_audience" toml:"jwt_audience"`
+	RolesClaim   string        `yaml:"jwt_roles_claim" toml:"jwt_roles_claim"`
+	//Keys are those of the JWKS file, loaded by Validate
+	Keys map[string]*rsa.PublicKey `yaml:"-" toml:"-"`
+}
+
+//RateLimits configures the rate limits of the http API. The limits are parsed by Validate.
+type RateLimits struct {
+	RateLimitStore  string `yaml:"rate_limit_store" toml:"rate_limit_store"`
+	RateLimit       string `yaml:"rate_limit" toml:"rate_limit"`
+	RateLimitRoutes string `yaml:"rate_limit_routes" toml:"rate_limit_routes"`
+	//AuthFailureLimit limits the failed authentications of each IP address
+	AuthFailureLimit string `yaml:"auth_failure_limit" toml:"auth_failure_limit"`
+	//FallbackLimit, RouteLimits and AuthFailureLimits are the parsed limits
+	FallbackLimit     models.RateLimit       `yaml:"-" toml:"-"`
+	RouteLimits       []models.RateLimitRule `yaml:"-" toml:"-"`
+	AuthFailureLimits models.RateLimit       `yaml:"-" toml:"-"`
+}
+
+//GraphQL configures the limits of the GraphQL queries
+type GraphQL struct {
+	GraphQLMaxDepth      int `yaml:"graphql_max_depth" toml:"graphql_max_depth"`
+	GraphQLMaxComplexity int `yaml:"graphql_max_complexity" toml:"graphql_max_complexity"`
+}
+
+//Readiness configures the checks of the dependencies
+type Readiness struct {
+	ReadinessTimeout time.Duration `yaml:"readiness_timeout" toml:"readiness_timeout"`
+	//ReadinessCheckDistance also checks the Google APIs, each check counting against the quota of the API key
+	ReadinessCheckDistance bool `yaml:"readiness_check_distance" toml:"readiness_check_distance"`
+}
+
+//Migrations configures the migrations of the database
+type Migrations struct {
+	MigrateOnStartup bool `yaml:"migrate_on_startup" toml:"migrate_on_startup"`
+	//MigrationLockTTL is how long a replica holds the lease of the migrations, renewed while it migrates
+	MigrationLockTTL time.Duration `yaml:"migration_lock_ttl" toml:"migration_lock_ttl"`
+}
+
+//MinSecretLength is the shortest admin key and HS256 secret accepted, the 256 bits HS256 requires
+const MinSecretLength = 32
+
+//Stores of the rate limits
+const (
+	RateLimitStoreMongo  = "mongo"
+	RateLimitStoreMemory = "memory"
+)
+
+//Default returns the settings used unless set otherwise
+func Default() Config {
+	return Config{
+		Server: Server{
+			Port:                8080,
+			GRPCPort:            9090,
+			HTTPReadTimeout:     10 * time.Second,
+			HTTPWriteTimeout:    30 * time.Second,
+			HTTPIdleTimeout:     2 * time.Minute,
+			ShutdownDelay:       5 * time.Second,
+			ShutdownGracePeriod: 20 * time.Second,
+		},
+		Telemetry: Telemetry{LogLevel: "info", TraceExporter: tracing.ExporterNone},
+		Mongo: Mongo{
+			MongoPoolLimit:      100,
+			MongoConnectTimeout: 10 * time.Second,
+			MongoConnectBackoff: time.Second,
+			MongoSocketTimeout:  time.Minute,
+			MongoWriteConcern:   "majority",
+			MongoWriteTimeout:   5 * time.Second,
+		},
+		Orders:   Orders{PageSize: 10, StreamReplaySize: 1000},
+		Expiry:   Expiry{OrderTTL: 24 * time.Hour, ExpiryInterval: time.Minute, ExpiryBatchSize: 100},
+		Outbox:   Outbox{OutboxRelayInterval: time.Second, OutboxBatchSize: 100, OutboxMaxAttempts: 10},
+		Webhooks: Webhooks{WebhookInterval: time.Second, WebhookBatchSize: 100, WebhookMaxAttempts: 5, WebhookBackoff: time.Second},
+		JWT:      JWT{JWKSCacheTTL: time.Hour, RolesClaim: "roles"},
+		RateLimits: RateLimits{
+			RateLimitStore: RateLimitStoreMongo,
+			RateLimit:      "300/1m",
+			//Each order calls the Google APIs
+			RateLimitRoutes:  "POST /orders=30/1m",
+			AuthFailureLimit: "10/1m",
+		},
+		GraphQL:    GraphQL{GraphQLMaxDepth: 15, GraphQLMaxComplexity: 1000},
+		Readiness:  Readiness{ReadinessTimeout: 2 * time.Second},
+		Migrations: Migrations{MigrateOnStartup: true, MigrationLockTTL: 10 * time.Minute},
+	}
+}
+
+//Load reads the settings from the file named by the -config flag or the CONFIG_FILE variable, the variables
+//looked up with lookupEnv and the flags of args, and validates them. Every invalid setting is reported.
+//It also returns the arguments following the flags, such as a command. It returns flag.ErrHelp when the
+//flags asked for the usage, which it writes to stderr.
+func Load(args []string, lookupEnv func(string) (string, bool)) (*Config, []string, error) {
+	//A first pass over the flags finds the file, whose settings the variables and flags override
+	cfg := Default()
+	if err := cfg.flagSet().Parse(args); err != nil {
+		return nil, nil, err
+	}
+	file := cfg.File
+	if file == "" {
+		file, _ = lookupEnv("CONFIG_FILE")
+	}
+	cfg = Default()
+	if file != "" {
+		if err := readFile(file, &cfg); err != nil {
+			return nil, nil, err
+		}
+	}
+	fs := cfg.flagSet()
+	//The first pass already reported the errors of the flags
+	fs.SetOutput(io.Discard)
+	var errs []error
+	fs.VisitAll(func(f *flag.Flag) {
+		name := EnvName(f.Name)
+		value, ok := lookupEnv(name)
+		if !ok || f.Name == "config" {
+			return
+		}
+		if err := fs.Set(f.Name, value); err != nil {
+			errs = append(errs, fmt.Errorf("invalid %s %q: %v", name, value, err))
+		}
+	})
+	if err := errors.Join(errs...); err != nil {
+		return nil, nil, err
+	}
+	if err := fs.Parse(args); err != nil {
+		return nil, nil, err
+	}
+	cfg.File = file
+	if err := cfg.Validate(); err != nil {
+		return nil, nil, err
+	}
+	return &cfg, fs.Args(), nil
+}
+
+//EnvName returns the name of the environment variable of the setting of the flag, e.g. PAGE_SIZE for page-size
+func EnvName(flagName string) string {
+	return strings.ToUpper(strings.ReplaceAll(flagName, "-", "_"))
+}
+
+//flagSet returns the flags of the settings, bound to the config and defaulting to its current settings
+func (c *Config) flagSet() *flag.FlagSet {
+	fs := flag.NewFlagSet("order-service", flag.ContinueOnError)
+	fs.StringVar(&c.File, "config", c.File, "YAML or TOML `file` of the settings")
+	fs.IntVar(&c.Port, "port", c.Port, "port of the http server")
+	fs.IntVar(&c.GRPCPort, "grpc-port", c.GRPCPort, "port of the gRPC server")
+	fs.DurationVar(&c.HTTPReadTimeout, "http-read-timeout", c.HTTPReadTimeout, "time to read a request")
+	fs.DurationVar(&c.HTTPWriteTimeout, "http-write-timeout", c.HTTPWriteTimeout, "time to write a response, except for the order streams")
+	fs.DurationVar(&c.HTTPIdleTimeout, "http-idle-timeout", c.HTTPIdleTimeout, "time an idle connection is kept open")
+	fs.DurationVar(&c.ShutdownDelay, "shutdown-delay", c.ShutdownDelay, "time spent serving once not ready, before draining the requests")
+	fs.DurationVar(&c.ShutdownGracePeriod, "shutdown-grace-period", c.ShutdownGracePeriod, "time to drain the requests and stop the workers")
+	fs.Var(&c.UnversionedSunset, "unversioned-sunset", "`date` the unversioned paths stop being served, e.g. 2027-04-18")
+	fs.StringVar(&c.LogLevel, "log-level", c.LogLevel, "level of the logs: debug, info, warn or error")
+	fs.StringVar(&c.TraceExporter, "trace-exporter", c.TraceExporter, "exporter of the spans: none, stdout or otlp")
+	fs.StringVar(&c.MongoURL, "mongodb-url", c.MongoURL, "`url` of the database")
+	fs.StringVar(&c.DatabaseName, "database-name", c.DatabaseName, "name of the database, the one of the url by default")
+	fs.IntVar(&c.MongoPoolLimit, "mongodb-pool-limit", c.MongoPoolLimit, "largest number of sockets to each member of the database")
+	fs.DurationVar(&c.MongoConnectTimeout, "mongodb-connect-timeout", c.MongoConnectTimeout, "time to connect to the database")
+	fs.DurationVar(&c.MongoConnectBackoff, "mongodb-connect-backoff", c.MongoConnectBackoff, "wait before connecting again at startup, doubled after every attempt")
+	fs.DurationVar(&c.MongoSocketTimeout, "mongodb-socket-timeout", c.MongoSocketTimeout, "time to wait for the database to answer an operation")
+	fs.StringVar(&c.MongoWriteConcern, "mongodb-write-concern", c.MongoWriteConcern, "number of members acknowledging the writes, or a mode such as majority")
+	fs.DurationVar(&c.MongoWriteTimeout, "mongodb-write-timeout", c.MongoWriteTimeout, "time to wait for the write concern")
+	fs.StringVar(&c.GoogleAPIKey, "google-api-key", c.GoogleAPIKey, "key of the Google APIs")
+	fs.StringVar(&c.GoogleServerURL, "google-server-url", c.GoogleServerURL, "`url` replacing the one of the Google APIs")
+	fs.IntVar(&c.PageSize, "page-size", c.PageSize, "largest number of orders per page")
+	fs.IntVar(&c.StreamReplaySize, "stream-replay-size", c.StreamReplaySize, "number of order events replayed to the streams")
+	fs.DurationVar(&c.OrderTTL, "order-ttl", c.OrderTTL, "time after which unassigned orders expire")
+	fs.DurationVar(&c.ExpiryInterval, "expiry-interval", c.ExpiryInterval, "time between the runs of the expiry worker")
+	fs.IntVar(&c.ExpiryBatchSize, "expiry-batch-size", c.ExpiryBatchSize, "number of orders expired per run")
+	fs.DurationVar(&c.OutboxRelayInterval, "outbox-relay-interval", c.OutboxRelayInterval, "time between the runs of the outbox relay")
+	fs.IntVar(&c.OutboxBatchSize, "outbox-batch-size", c.OutboxBatchSize, "number of events published per run")
+	fs.IntVar(&c.OutboxMaxAttempts, "outbox-max-attempts", c.OutboxMaxAttempts, "attempts to publish an event before giving up")
+	fs.DurationVar(&c.WebhookInterval, "webhook-interval", c.WebhookInterval, "time between the runs of the webhook deliveries")
+	fs.IntVar(&c.WebhookBatchSize, "webhook-batch-size", c.WebhookBatchSize, "number of webhook deliveries attempted at once")
+	fs.IntVar(&c.WebhookMaxAttempts, "webhook-max-attempts", c.WebhookMaxAttempts, "attempts to deliver an event before giving up")
+	fs.DurationVar(&c.WebhookBackoff, "webhook-backoff", c.WebhookBackoff, "wait before the second attempt, doubled after every other one")
+	fs.BoolVar(&c.WebhookAllowPrivate, "webhook-allow-private", c.WebhookAllowPrivate, "deliver webhooks to private, loopback and link-local addresses")
+	fs.StringVar(&c.AdminAPIKey, "admin-api-key", c.AdminAPIKey, "API key granted every scope")
+	fs.StringVar(&c.JWT.Secret, "jwt-secret", c.JWT.Secret, "secret of the HS256 bearer tokens")
+	fs.StringVar(&c.JWKSFile, "jwt-jwks-file", c.JWKSFile, "`file` of the JSON Web Key Set of the RS256 bearer tokens")
+	fs.StringVar(&c.JWKSURL, "jwt-jwks-url", c.JWKSURL, "`url` of the JSON Web Key Set of the RS256 bearer tokens")
+	fs.DurationVar(&c.JWKSCacheTTL, "jwt-jwks-cache-ttl", c.JWKSCacheTTL, "time the key set of the url is cached")
+	fs.StringVar(&c.Issuer, "jwt-issuer", c.Issuer, "issuer of the bearer tokens, not checked when empty")
+	fs.StringVar(&c.Audience, "jwt-audience", c.Audience, "audience of the bearer tokens, not checked when empty")
+	fs.StringVar(&c.RolesClaim, "jwt-roles-claim", c.RolesClaim, "claim of the bearer tokens listing the roles of the end user")
+	fs.StringVar(&c.RateLimitStore, "rate-limit-store", c.RateLimitStore, "store of the rate limits: mongo or memory")
+	fs.StringVar(&c.RateLimit, "rate-limit", c.RateLimit, "limit of the requests of each client, as <requests>/<period> or off")
+	fs.StringVar(&c.RateLimitRoutes, "rate-limit-routes", c.RateLimitRoutes, "limits of routes, as comma separated <method> <pattern>=<requests>/<period>")
+	fs.StringVar(&c.AuthFailureLimit, "auth-failure-limit", c.AuthFailureLimit, "limit of the failed authentications of each IP address, as <requests>/<period> or off")
+	fs.IntVar(&c.GraphQLMaxDepth, "graphql-max-depth", c.GraphQLMaxDepth, "deepest GraphQL query")
+	fs.IntVar(&c.GraphQLMaxComplexity, "graphql-max-complexity", c.GraphQLMaxComplexity, "most complex GraphQL query")
+	fs.DurationVar(&c.ReadinessTimeout, "readiness-timeout", c.ReadinessTimeout, "time to check each dependency")
+	fs.BoolVar(&c.ReadinessCheckDistance, "readiness-check-distance", c.ReadinessCheckDistance, "also check the Google APIs for readiness")
+	fs.BoolVar(&c.MigrateOnStartup, "migrate-on-startup", c.MigrateOnStartup, "apply the pending migrations of the database before serving")
+	fs.DurationVar(&c.MigrationLockTTL, "migration-lock-ttl", c.MigrationLockTTL, "time a replica holds the lease of the migrations, renewed while it migrates")
+	return fs
+}
+
+//readFile reads the settings of the YAML or TOML file, rejecting the settings it does not know
+func readFile(path string, cfg *Config) error {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("unable to read the config file: %v", err)
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		decoder := yaml.NewDecoder(bytes.NewReader(b))
+		decoder.KnownFields(true)
+		//An empty file sets nothing
+		if err := decoder.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+			return fmt.Errorf("invalid config file %s: %v", path, err)
+		}
+	case ".toml":
+		md, err := toml.Decode(string(b), cfg)
+		if err != nil {
+			return fmt.Errorf("invalid config file %s: %v", path, err)
+		}
+		if undecoded := md.Undecoded(); len(undecoded) > 0 {
+			return fmt.Errorf("invalid config file %s: unknown setting %s", path, undecoded[0])
+		}
+	default:
+		return fmt.Errorf("invalid config file %s: expected a .yaml, .yml or .toml file", path)
+	}
+	return nil
+}
+
+//ValidateServe checks the settings only the serve command needs, the other commands not calling the Google APIs
+func (c *Config) ValidateServe() error {
+	if c.GoogleAPIKey == "" {
+		return errors.New("invalid GOOGLE_API_KEY: the key of the Google APIs is required")
+	}
+	return nil
+}
+
+//Validate checks every setting, reporting all of those which are invalid. It parses the rate limits and
+//loads the keys of the JWKS file along the way.
+func (c *Config) Validate() error {
+	var errs []error
+	invalid := func(setting string, format string, args ...interface{}) {
+		errs = append(errs, fmt.Errorf("invalid %s: "+format, append([]interface{}{setting}, args...)...))
+	}
+	port := func(setting string, p int) {
+		if p <= 0 || p > 65535 {
+			invalid(setting, "%d is not a port", p)
+		}
+	}
+	positive := func(setting string, n int) {
+		if n <= 0 {
+			invalid(setting, "%d is not a positive number", n)
+		}
+	}
+	positiveDuration := func(setting string, d time.Duration) {
+		if d <= 0 {
+			invalid(setting, "%s is not a positive duration", d)
+		}
+	}
+	absoluteURL := func(setting string, s string) {
+		if u, err := url.Parse(s); err != nil || u.Scheme == "" || u.Host == "" {
+			invalid(setting, "%q is not an absolute url", s)
+		}
+	}
+	//Secrets are optional, but shorter ones can be guessed
+	secret := func(setting string, s string) {
+		if s != "" && len(s) < MinSecretLength {
+			invalid(setting, "must be at least %d characters long", MinSecretLength)
+		}
+	}
+	rateLimit := func(setting string, s string) models.RateLimit {
+		limit, err := models.ParseRateLimit(s)
+		if err != nil {
+			invalid(setting, "%v", err)
+		}
+		return limit
+	}
+
+	port("PORT", c.Port)
+	port("GRPC_PORT", c.GRPCPort)
+	if c.Port == c.GRPCPort {
+		invalid("GRPC_PORT", "the http and gRPC servers cannot share port %d", c.Port)
+	}
+	positiveDuration("HTTP_READ_TIMEOUT", c.HTTPReadTimeout)
+	positiveDuration("HTTP_WRITE_TIMEOUT", c.HTTPWriteTimeout)
+	positiveDuration("HTTP_IDLE_TIMEOUT", c.HTTPIdleTimeout)
+	if c.ShutdownDelay < 0 {
+		invalid("SHUTDOWN_DELAY", "%s is negative", c.ShutdownDelay)
+	}
+	positiveDuration("SHUTDOWN_GRACE_PERIOD", c.ShutdownGracePeriod)
+	if _, err := logging.ParseLevel(c.LogLevel); err != nil {
+		invalid("LOG_LEVEL", "%v", err)
+	}
+	switch c.TraceExporter {
+	case tracing.ExporterNone, tracing.ExporterStdout, tracing.ExporterOTLP:
+	default:
+		invalid("TRACE_EXPORTER", "%q, expected none, stdout or otlp", c.TraceExporter)
+	}
+	if c.MongoURL == "" {
+		invalid("MONGODB_URL", "the url of the database is required")
+	}
+	positive("MONGODB_POOL_LIMIT", c.MongoPoolLimit)
+	positiveDuration("MONGODB_CONNECT_TIMEOUT", c.MongoConnectTimeout)
+	positiveDuration("MONGODB_CONNECT_BACKOFF", c.MongoConnectBackoff)
+	positiveDuration("MONGODB_SOCKET_TIMEOUT", c.MongoSocketTimeout)
+	if w, err := strconv.Atoi(c.MongoWriteConcern); c.MongoWriteConcern == "" || err == nil && w <= 0 {
+		invalid("MONGODB_WRITE_CONCERN", "%q, expected a positive number of members or a mode such as majority", c.MongoWriteConcern)
+	}
+	positiveDuration("MONGODB_WRITE_TIMEOUT", c.MongoWriteTimeout)
+	if c.GoogleServerURL != "" {
+		absoluteURL("GOOGLE_SERVER_URL", c.GoogleServerURL)
+	}
+	positive("PAGE_SIZE", c.PageSize)
+	positive("STREAM_REPLAY_SIZE", c.StreamReplaySize)
+	positiveDuration("ORDER_TTL", c.OrderTTL)
+	positiveDuration("EXPIRY_INTERVAL", c.ExpiryInterval)
+	positive("EXPIRY_BATCH_SIZE", c.ExpiryBatchSize)
+	positiveDuration("OUTBOX_RELAY_INTERVAL", c.OutboxRelayInterval)
+	positive("OUTBOX_BATCH_SIZE", c.OutboxBatchSize)
+	positive("OUTBOX_MAX_ATTEMPTS", c.OutboxMaxAttempts)
+	positiveDuration("WEBHOOK_INTERVAL", c.WebhookInterval)
+	positive("WEBHOOK_BATCH_SIZE", c.WebhookBatchSize)
+	positive("WEBHOOK_MAX_ATTEMPTS", c.WebhookMaxAttempts)
+	positiveDuration("WEBHOOK_BACKOFF", c.WebhookBackoff)
+	secret("ADMIN_API_KEY", c.AdminAPIKey)
+	secret("JWT_SECRET", c.JWT.Secret)
+	if c.JWKSFile != "" && c.JWKSURL != "" {
+		invalid("JWT_JWKS_URL", "the key set is read from either JWT_JWKS_FILE or JWT_JWKS_URL, not both")
+	}
+	if c.JWKSFile != "" {
+		if keys, err := loadJWKS(c.JWKSFile); err != nil {
+			invalid("JWT_JWKS_FILE", "%v", err)
+		} else {
+			c.Keys = keys
+		}
+	}
+	if c.JWKSURL != "" {
+		absoluteURL("JWT_JWKS_URL", c.JWKSURL)
+	}
+	positiveDuration("JWT_JWKS_CACHE_TTL", c.JWKSCacheTTL)
+	if c.RolesClaim == "" {
+		invalid("JWT_ROLES_CLAIM", "the claim of the roles is required")
+	}
+	if c.RateLimitStore != RateLimitStoreMongo && c.RateLimitStore != RateLimitStoreMemory {
+		invalid("RATE_LIMIT_STORE", "%q, expected mongo or memory", c.RateLimitStore)
+	}
+	c.FallbackLimit = rateLimit("RATE_LIMIT", c.RateLimit)
+	if rules, err := models.ParseRateLimitRules(c.RateLimitRoutes); err != nil {
+		invalid("RATE_LIMIT_ROUTES", "%v", err)
+	} else {
+		c.RouteLimits = rules
+	}
+	c.AuthFailureLimits = rateLimit("AUTH_FAILURE_LIMIT", c.AuthFailureLimit)
+	positive("GRAPHQL_MAX_DEPTH", c.GraphQLMaxDepth)
+	positive("GRAPHQL_MAX_COMPLEXITY", c.GraphQLMaxComplexity)
+	positiveDuration("READINESS_TIMEOUT", c.ReadinessTimeout)
+	positiveDuration("MIGRATION_LOCK_TTL", c.MigrationLockTTL)
+	return errors.Join(errs...)
+}
+
+//loadJWKS returns the keys of the JSON Web Key Set stored in the file
+func loadJWKS(path string) (map[string]*rsa.PublicKey, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return models.ParseJWKS(b)
+}
+
+//Date is a day, written as 2006-01-02
+type Date struct {
+	time.Time
+}
+
+func (d *Date) String() string {
+	if d.IsZero() {
+		return ""
+	}
+	return d.Format(time.DateOnly)
+}
+
+func (d *Date) Set(s string) error {
+	return d.UnmarshalText([]byte(s))
+}
+
+func (d *Date) UnmarshalText(b []byte) error {
+	if len(b) == 0 {
+		d.Time = time.Time{}
+		return nil
+	}
+	t, err := time.Parse(time.DateOnly, string(b))
+	if err != nil {
+		return fmt.Errorf("%q is not a date such as 2006-01-02", b)
+	}
+	d.Time = t
+	return nil
+}
+
+//UnmarshalTOML reads the date from a string or a TOML local date
+func (d *Date) UnmarshalTOML(v interface{}) error {
+	switch v := v.(type) {
+	case time.Time:
+		d.Time = time.Date(v.Year(), v.Month(), v.Day(), 0, 0, 0, 0, time.UTC)
+		return nil
+	case string:
+		return d.Set(v)
+	default:
+		return fmt.Errorf("%v is not a date such as 2006-01-02", v)
+	}
+}
@@ -0,0 +1,307 @@
+package config
+
+import (
+	"flag"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/karanbhomiagit/order-service/models"
+	"github.com/stretchr/testify/assert"
+)
+
+//env returns a lookup of the variables, which always sets the required ones unless overridden
+func env(vars map[string]string) func(string) (string, bool) {
+	all := map[string]string{"MONGODB_URL": "mongodb://localhost/orders", "GOOGLE_API_KEY": "key"}
+	for name, value := range vars {
+		all[name] = value
+	}
+	return func(name string) (string, bool) {
+		value, ok := all[name]
+		return value, ok
+	}
+}
+
+//writeFile writes a config file named name in a temporary directory and returns its path
+func writeFile(t *testing.T, name string, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoad(t *testing.T) {
+	t.Run("Use the defaults when nothing is set", func(t *testing.T) {
+		assert := assert.New(t)
+		cfg, _, err := Load(nil, env(nil))
+		assert.NoError(err)
+		expected := Default()
+		expected.MongoURL = "mongodb://localhost/orders"
+		expected.GoogleAPIKey = "key"
+		expected.FallbackLimit = models.RateLimit{Requests: 300, Period: time.Minute}
+		expected.RouteLimits = []models.RateLimitRule{{Method: http.MethodPost, Pattern: "/orders", Limit: models.RateLimit{Requests: 30, Period: time.Minute}}}
+		expected.AuthFailureLimits = models.RateLimit{Requests: 10, Period: time.Minute}
+		assert.Equal(&expected, cfg)
+	})
+
+	t.Run("Read the settings from the variables", func(t *testing.T) {
+		assert := assert.New(t)
+		cfg, _, err := Load(nil, env(map[string]string{
+			"PAGE_SIZE":                "25",
+			"ORDER_TTL":                "2h",
+			"READINESS_CHECK_DISTANCE": "true",
+			"UNVERSIONED_SUNSET":       "2027-04-18",
+			"JWT_SECRET":               "0123456789abcdef0123456789abcdef",
+		}))
+		assert.NoError(err)
+		assert.Equal(25, cfg.PageSize)
+		assert.Equal(2*time.Hour, cfg.OrderTTL)
+		assert.True(cfg.ReadinessCheckDistance)
+		assert.Equal(time.Date(2027, 4, 18, 0, 0, 0, 0, time.UTC), cfg.UnversionedSunset.Time)
+		assert.Equal("0123456789abcdef0123456789abcdef", cfg.JWT.Secret)
+	})
+
+	t.Run("Read the settings from a YAML file", func(t *testing.T) {
+		assert := assert.New(t)
+		path := writeFile(t, "config.yaml", `
+port: 8000
+page_size: 20
+webhook_backoff: 3s
+google_server_url: http://localhost:9000
+unversioned_sunset: 2027-04-18
+`)
+		cfg, _, err := Load([]string{"-config", path}, env(nil))
+		assert.NoError(err)
+		assert.Equal(path, cfg.File)
+		assert.Equal(8000, cfg.Port)
+		assert.Equal(20, cfg.PageSize)
+		assert.Equal(3*time.Second, cfg.WebhookBackoff)
+		assert.Equal("http://localhost:9000", cfg.GoogleServerURL)
+		assert.Equal(time.Date(2027, 4, 18, 0, 0, 0, 0, time.UTC), cfg.UnversionedSunset.Time)
+		assert.Equal(Default().GRPCPort, cfg.GRPCPort)
+	})
+
+	t.Run("Read the settings from a TOML file named by CONFIG_FILE", func(t *testing.T) {
+		assert := assert.New(t)
+		path := writeFile(t, "config.toml", `
+port = 8000
+page_size = 20
+webhook_backoff = "3s"
+unversioned_sunset = 2027-04-18
+`)
+		cfg, _, err := Load(nil, env(map[string]string{"CONFIG_FILE": path}))
+		assert.NoError(err)
+		assert.Equal(path, cfg.File)
+		assert.Equal(8000, cfg.Port)
+		assert.Equal(20, cfg.PageSize)
+		assert.Equal(3*time.Second, cfg.WebhookBackoff)
+		assert.Equal(time.Date(2027, 4, 18, 0, 0, 0, 0, time.UTC), cfg.UnversionedSunset.Time)
+	})
+
+	t.Run("Override the file with the variables and the variables with the flags", func(t *testing.T) {
+		assert := assert.New(t)
+		path := writeFile(t, "config.yaml", "port: 8000\npage_size: 20\nlog_level: debug\n")
+		cfg, _, err := Load([]string{"-config", path, "-page-size", "40"}, env(map[string]string{"PORT": "8001", "PAGE_SIZE": "30"}))
+		assert.NoError(err)
+		assert.Equal(8001, cfg.Port)
+		assert.Equal(40, cfg.PageSize)
+		assert.Equal("debug", cfg.LogLevel)
+	})
+
+	t.Run("Return the arguments following the flags", func(t *testing.T) {
+		assert := assert.New(t)
+		cfg, args, err := Load([]string{"-page-size", "40", "migrate", "down", "1"}, env(nil))
+		assert.NoError(err)
+		assert.Equal(40, cfg.PageSize)
+		assert.Equal([]string{"migrate", "down", "1"}, args)
+	})
+
+	t.Run("Return error when a variable cannot be parsed", func(t *testing.T) {
+		assert := assert.New(t)
+		cfg, _, err := Load(nil, env(map[string]string{"PAGE_SIZE": "ten", "ORDER_TTL": "a day"}))
+		assert.Nil(cfg)
+		assert.ErrorContains(err, `invalid PAGE_SIZE "ten"`)
+		assert.ErrorContains(err, `invalid ORDER_TTL "a day"`)
+	})
+
+	t.Run("Return error listing every invalid setting", func(t *testing.T) {
+		assert := assert.New(t)
+		cfg, _, err := Load(nil, env(map[string]string{
+			"MONGODB_URL":           "",
+			"PAGE_SIZE":             "0",
+			"LOG_LEVEL":             "verbose",
+			"GOOGLE_SERVER_URL":     "localhost",
+			"GRPC_PORT":             "8080",
+			"MONGODB_WRITE_CONCERN": "0",
+		}))
+		assert.Nil(cfg)
+		assert.ErrorContains(err, "invalid MONGODB_URL")
+		assert.ErrorContains(err, "invalid PAGE_SIZE: 0 is not a positive number")
+		assert.ErrorContains(err, "invalid LOG_LEVEL")
+		assert.ErrorContains(err, `invalid GOOGLE_SERVER_URL: "localhost" is not an absolute url`)
+		assert.ErrorContains(err, "invalid GRPC_PORT: the http and gRPC servers cannot share port 8080")
+		assert.ErrorContains(err, `invalid MONGODB_WRITE_CONCERN: "0"`)
+	})
+
+	t.Run("Require the key of the Google APIs only to serve", func(t *testing.T) {
+		assert := assert.New(t)
+		cfg, _, err := Load(nil, env(map[string]string{"GOOGLE_API_KEY": ""}))
+		assert.NoError(err)
+		assert.EqualError(cfg.ValidateServe(), "invalid GOOGLE_API_KEY: the key of the Google APIs is required")
+		cfg, _, err = Load(nil, env(nil))
+		assert.NoError(err)
+		assert.NoError(cfg.ValidateServe())
+	})
+
+	t.Run("Return error when the admin key or the JWT secret is too short", func(t *testing.T) {
+		assert := assert.New(t)
+		cfg, _, err := Load(nil, env(map[string]string{"ADMIN_API_KEY": "admin", "JWT_SECRET": "secret"}))
+		assert.Nil(cfg)
+		assert.ErrorContains(err, "invalid ADMIN_API_KEY: must be at least 32 characters long")
+		assert.ErrorContains(err, "invalid JWT_SECRET: must be at least 32 characters long")
+	})
+
+	t.Run("Return error when both a key set file and url are set", func(t *testing.T) {
+		assert := assert.New(t)
+		cfg, _, err := Load(nil, env(map[string]string{"JWT_JWKS_FILE": "jwks.json", "JWT_JWKS_URL": "https://auth.example.com/jwks.json"}))
+		assert.Nil(cfg)
+		assert.ErrorContains(err, "invalid JWT_JWKS_URL")
+	})
+
+	t.Run("Parse the rate limits", func(t *testing.T) {
+		assert := assert.New(t)
+		cfg, _, err := Load(nil, env(map[string]string{
+			"RATE_LIMIT":         "100/30s",
+			"RATE_LIMIT_ROUTES":  "post /orders=10/1m, PATCH /orders/:id=60/30s",
+			"AUTH_FAILURE_LIMIT": "5/10m",
+		}))
+		assert.NoError(err)
+		assert.Equal(models.RateLimit{Requests: 100, Period: 30 * time.Second}, cfg.FallbackLimit)
+		assert.Equal([]models.RateLimitRule{
+			{Method: http.MethodPost, Pattern: "/orders", Limit: models.RateLimit{Requests: 10, Period: time.Minute}},
+			{Method: http.MethodPatch, Pattern: "/orders/:id", Limit: models.RateLimit{Requests: 60, Period: 30 * time.Second}},
+		}, cfg.RouteLimits)
+		assert.Equal(models.RateLimit{Requests: 5, Period: 10 * time.Minute}, cfg.AuthFailureLimits)
+	})
+
+	t.Run("Turn the rate limits off", func(t *testing.T) {
+		assert := assert.New(t)
+		cfg, _, err := Load(nil, env(map[string]string{
+			"RATE_LIMIT":         "off",
+			"RATE_LIMIT_ROUTES":  "POST /orders=OFF",
+			"AUTH_FAILURE_LIMIT": " off",
+		}))
+		assert.NoError(err)
+		assert.Equal(models.RateLimit{}, cfg.FallbackLimit)
+		assert.Equal([]models.RateLimitRule{{Method: http.MethodPost, Pattern: "/orders"}}, cfg.RouteLimits)
+		assert.Equal(models.RateLimit{}, cfg.AuthFailureLimits)
+	})
+
+	t.Run("Return error when a rate limit is invalid", func(t *testing.T) {
+		assert := assert.New(t)
+		cfg, _, err := Load(nil, env(map[string]string{
+			"RATE_LIMIT":         "0/1m",
+			"RATE_LIMIT_ROUTES":  "POST /orders",
+			"AUTH_FAILURE_LIMIT": "10",
+		}))
+		assert.Nil(cfg)
+		assert.ErrorContains(err, `invalid RATE_LIMIT: invalid rate limit "0/1m", requests and period must be positive`)
+		assert.ErrorContains(err, "invalid RATE_LIMIT_ROUTES")
+		assert.ErrorContains(err, "invalid AUTH_FAILURE_LIMIT")
+
+		_, _, err = Load(nil, env(map[string]string{"RATE_LIMIT_ROUTES": "POST /orders=10"}))
+		assert.ErrorContains(err, "invalid RATE_LIMIT_ROUTES")
+
+		_, _, err = Load(nil, env(map[string]string{"RATE_LIMIT_ROUTES": "POST /orders=10/-1m"}))
+		assert.ErrorContains(err, "invalid RATE_LIMIT_ROUTES")
+	})
+
+	t.Run("Load the keys of the key set file", func(t *testing.T) {
+		assert := assert.New(t)
+		path := writeFile(t, "jwks.json", `{"keys": [{"kty": "RSA", "kid": "key-1", "use": "sig", "n": "AQAB", "e": "AQAB"}]}`)
+		cfg, _, err := Load(nil, env(map[string]string{"JWT_JWKS_FILE": path}))
+		assert.NoError(err)
+		assert.Contains(cfg.Keys, "key-1")
+	})
+
+	t.Run("Return error when the key set file cannot be loaded", func(t *testing.T) {
+		assert := assert.New(t)
+		cfg, _, err := Load(nil, env(map[string]string{"JWT_JWKS_FILE": filepath.Join(t.TempDir(), "missing.json")}))
+		assert.Nil(cfg)
+		assert.ErrorContains(err, "invalid JWT_JWKS_FILE")
+
+		cfg, _, err = Load(nil, env(map[string]string{"JWT_JWKS_FILE": writeFile(t, "jwks.json", "keys")}))
+		assert.Nil(cfg)
+		assert.ErrorContains(err, "invalid JWT_JWKS_FILE")
+	})
+
+	t.Run("Return error when the file sets an unknown setting", func(t *testing.T) {
+		assert := assert.New(t)
+		yamlPath := writeFile(t, "config.yaml", "pagesize: 20\n")
+		cfg, _, err := Load([]string{"-config", yamlPath}, env(nil))
+		assert.Nil(cfg)
+		assert.ErrorContains(err, "pagesize")
+
+		tomlPath := writeFile(t, "config.toml", "pagesize = 20\n")
+		cfg, _, err = Load([]string{"-config", tomlPath}, env(nil))
+		assert.Nil(cfg)
+		assert.ErrorContains(err, "unknown setting pagesize")
+	})
+
+	t.Run("Return error when the file cannot be read", func(t *testing.T) {
+		assert := assert.New(t)
+		cfg, _, err := Load([]string{"-config", filepath.Join(t.TempDir(), "missing.yaml")}, env(nil))
+		assert.Nil(cfg)
+		assert.ErrorContains(err, "unable to read the config file")
+
+		cfg, _, err = Load([]string{"-config", writeFile(t, "config.json", "{}")}, env(nil))
+		assert.Nil(cfg)
+		assert.ErrorContains(err, "expected a .yaml, .yml or .toml file")
+	})
+
+	t.Run("Return flag.ErrHelp when asked for the usage", func(t *testing.T) {
+		assert := assert.New(t)
+		stderr := os.Stderr
+		os.Stderr, _ = os.Open(os.DevNull)
+		defer func() { os.Stderr = stderr }()
+		cfg, _, err := Load([]string{"-h"}, env(nil))
+		assert.Nil(cfg)
+		assert.ErrorIs(err, flag.ErrHelp)
+	})
+}
+
+func TestFlagSet(t *testing.T) {
+	t.Run("Name every setting the same way in the file, the variables and the flags", func(t *testing.T) {
+		assert := assert.New(t)
+		var cfg Config
+		keys := map[string]bool{}
+		collectKeys(reflect.TypeOf(cfg), keys)
+		flags := map[string]bool{}
+		cfg.flagSet().VisitAll(func(f *flag.Flag) {
+			if f.Name != "config" {
+				flags[strings.ReplaceAll(f.Name, "-", "_")] = true
+			}
+		})
+		assert.Equal(keys, flags)
+	})
+}
+
+//collectKeys adds the keys of the settings of the struct type in the file, recursing into the inlined ones
+func collectKeys(t reflect.Type, keys map[string]bool) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("yaml")
+		switch {
+		case tag == "-":
+		case tag == ",inline":
+			collectKeys(field.Type, keys)
+		default:
+			keys[tag] = true
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+
+	"github.com/karanbhomiagit/order-service/config"
+	orderRepo "github.com/karanbhomiagit/order-service/order/repository"
+)
+
+//indexes prints how the indexes of the database differ from those the repositories declare, without
+//building or dropping any. Like diff, it exits with 1 when indexes are missing or changed and 2 on trouble.
+func indexes(cfg *config.Config, _ []string, logger *slog.Logger) int {
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+	defer stop()
+	session, ok := connect(ctx, cfg, logger)
+	if !ok {
+		return 2
+	}
+	defer session.Close()
+	statuses, err := orderRepo.DiffIndexes(session)
+	if err != nil {
+		logger.Error("Unable to list the indexes", "error", err)
+		return 2
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "COLLECTION\tINDEX\tKEY\tSTATUS")
+	differs := false
+	for _, status := range statuses {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", status.Collection, status.Name, strings.Join(status.Key, ","), status.Status)
+		//Unmanaged indexes are only reported, the service never drops them
+		if status.Status == orderRepo.IndexMissing || status.Status == orderRepo.IndexChanged {
+			differs = true
+		}
+	}
+	w.Flush()
+	if differs {
+		return 1
+	}
+	return 0
+}
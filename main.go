@@ -1,47 +1,345 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
 	"fmt"
-	"log"
+	"log/slog"
+	"net"
 	"net/http"
 	"os"
+	"os/signal"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
 
-	mgo "gopkg.in/mgo.v2"
+	"google.golang.org/grpc"
 
+	"github.com/karanbhomiagit/order-service/config"
+	"github.com/karanbhomiagit/order-service/order"
+	graphqlDeliver "github.com/karanbhomiagit/order-service/order/delivery/graphql"
+	grpcDeliver "github.com/karanbhomiagit/order-service/order/delivery/grpc"
 	httpDeliver "github.com/karanbhomiagit/order-service/order/delivery/http"
+	"github.com/karanbhomiagit/order-service/order/logging"
+	orderPublisher "github.com/karanbhomiagit/order-service/order/publisher"
 	orderRepo "github.com/karanbhomiagit/order-service/order/repository"
+	"github.com/karanbhomiagit/order-service/order/tracing"
 	orderUsecase "github.com/karanbhomiagit/order-service/order/usecase"
 )
 
+//commands run by the service, serve by default. They are given the arguments following their name.
+var commands = map[string]func(cfg *config.Config, args []string, logger *slog.Logger) int{
+	"serve":   serve,
+	"indexes": indexes,
+	"migrate": migrate,
+	"orders":  orders,
+}
+
 func main() {
-	//Connect to the database
-	var db *mgo.Database
-	mongodbURL := os.Getenv("MONGODB_URL")
-	databaseName := os.Getenv("DATABASE_NAME")
-	session, err := mgo.Dial(mongodbURL)
+	//Read and validate every setting before starting anything
+	cfg, args, err := config.Load(os.Args[1:], os.LookupEnv)
+	if errors.Is(err, flag.ErrHelp) {
+		os.Exit(0)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid configuration:\n%v\n", err)
+		os.Exit(2)
+	}
+	name := "serve"
+	if len(args) > 0 {
+		name, args = args[0], args[1:]
+	}
+	command, ok := commands[name]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "Unknown command %q, expected serve, indexes, migrate or orders\n", name)
+		os.Exit(2)
+	}
+	if name == "serve" {
+		if err := cfg.ValidateServe(); err != nil {
+			fmt.Fprintf(os.Stderr, "Invalid configuration:\n%v\n", err)
+			os.Exit(2)
+		}
+	}
+
+	//Every layer logs lines of JSON to stdout, as does anything using the default logger. Other commands
+	//log to stderr, keeping stdout for their output.
+	level, _ := logging.ParseLevel(cfg.LogLevel)
+	logs := os.Stdout
+	if name != "serve" {
+		logs = os.Stderr
+	}
+	logger := logging.New(logs, level)
+	slog.SetDefault(logger)
+	os.Exit(command(cfg, args, logger))
+}
+
+//serve serves the http and gRPC APIs and runs the background workers until SIGTERM or SIGINT
+func serve(cfg *config.Config, _ []string, logger *slog.Logger) int {
+	//Export the spans of the http requests, usecase operations, MongoDB queries and Google API calls
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg.TraceExporter, "order-service")
+	if err != nil {
+		fatal("Unable to set up tracing", err)
+	}
+
+	//Stop on SIGTERM or SIGINT, even while still connecting to the database
+	signals, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+
+	//Connect to the database, waiting for it to be reachable
+	session, err := orderRepo.DialMongo(signals, cfg.Mongo, logger)
+	if errors.Is(err, context.Canceled) {
+		logger.Info("Shut down before connecting to the database")
+		return 0
+	}
 	if err != nil {
-		fmt.Println("Unable to connect to the database.")
-		log.Fatal(err)
+		fatal("Unable to connect to the database", err)
+	}
+
+	//Bring the database up to date before serving, while the other replicas wait
+	lr := orderRepo.NewMongoLeaseRepository(session)
+	if cfg.MigrateOnStartup {
+		migrator := orderRepo.NewMongoMigrator(session, lr, orderRepo.MongoMigrations, cfg.Migrations, logger)
+		err := migrator.Up(signals, 0)
+		if errors.Is(err, context.Canceled) {
+			session.Close()
+			logger.Info("Shut down before migrating the database")
+			return 0
+		}
+		if err != nil {
+			fatal("Unable to migrate the database", err)
+		}
 	}
-	db = session.DB(databaseName)
+
+	//The background workers run until the service shuts down
+	workers, stopWorkers := context.WithCancel(context.Background())
+	var workerGroup sync.WaitGroup
+
+	//The event log is only correct once its required indexes are built, before the relay appends to it
+	if err := orderRepo.EnsureRequiredIndexes(session, logger); err != nil {
+		fatal("Unable to build the required indexes", err)
+	}
+
+	//Build the indexes the repositories rely on, without holding up the service meanwhile
+	workerGroup.Add(1)
+	go func() {
+		defer workerGroup.Done()
+		if _, err := orderRepo.EnsureIndexes(session, logger); err != nil {
+			logger.Error("Unable to ensure the indexes", "error", err)
+		}
+	}()
 
 	//Initializing the repository
-	or := orderRepo.NewMongoOrderRepository(db)
+	or := orderRepo.NewMongoOrderRepository(session)
 
 	//Initializing the usecase
-	ou := orderUsecase.NewOrderUsecase(or)
+	ou := orderUsecase.NewOrderUsecase(or, cfg.Orders, logger)
+
+	//Starting the expiry worker for orders which are never assigned
+	ew := orderUsecase.NewExpiryWorker(or, lr, cfg.Expiry, logger)
+	workerGroup.Add(1)
+	go func() {
+		defer workerGroup.Done()
+		ew.Run(workers)
+	}()
+
+	//Starting the delivery of webhooks, queued for the events relayed from the outbox
+	wr := orderRepo.NewMongoWebhookRepository(session)
+	wu := orderUsecase.NewWebhookUsecase(wr, lr, cfg.Webhooks, logger)
+	workerGroup.Add(1)
+	go func() {
+		defer workerGroup.Done()
+		wu.Run(workers)
+	}()
+
+	//Starting the feed of order events streamed to clients, which every replica tails from the event log
+	elr := orderRepo.NewMongoEventLogRepository(session)
+	of := orderUsecase.NewOrderFeed(cfg.StreamReplaySize)
+	ft := orderUsecase.NewFeedTailer(elr, of.Append, cfg.Outbox, cfg.StreamReplaySize, logger)
+	workerGroup.Add(1)
+	go func() {
+		defer workerGroup.Done()
+		ft.Run(workers)
+	}()
+
+	//Starting the relay publishing the events recorded in the outbox
+	ep := orderPublisher.NewMultiPublisher(orderPublisher.NewLogPublisher(logger), orderPublisher.PublisherFunc(wu.Dispatch), orderPublisher.PublisherFunc(elr.Append))
+	outr := orderRepo.NewMongoOutboxRepository(session)
+	rw := orderUsecase.NewOutboxRelay(outr, lr, ep, cfg.Outbox, logger)
+	workerGroup.Add(1)
+	go func() {
+		defer workerGroup.Done()
+		rw.Run(workers)
+	}()
+
+	//Initializing the API keys authenticating clients, bootstrapped by the admin key
+	kr := orderRepo.NewMongoAPIKeyRepository(session)
+	aku := orderUsecase.NewAPIKeyUsecase(kr, cfg.AdminAPIKey)
+
+	//Initializing the readiness checks of the dependencies
+	hu := orderUsecase.NewHealthUsecase(healthCheckers(cfg, session), cfg.Readiness)
 
 	//Initializing the delivery
-	httpDeliver.NewOrderHttpHandler(ou)
+	router := httpDeliver.NewRouter()
+	router.Use(httpDeliver.Instrument(router))
+	router.Use(httpDeliver.Tracing(router))
+	router.Use(httpDeliver.Logging(router, logger))
+	tv := tokenVerifier(cfg.JWT, logger)
+	rls := rateLimitStore(cfg.RateLimitStore, session)
+	router.Use(httpDeliver.LimitFailedAuth(rls, cfg.AuthFailureLimits))
+	router.Use(httpDeliver.Authenticate(aku, tv))
+	router.Use(httpDeliver.RateLimit(rls, cfg.FallbackLimit, cfg.RouteLimits))
+	gh := graphqlDeliver.NewGraphqlHandler(ou, cfg.GraphQLMaxDepth, cfg.GraphQLMaxComplexity)
+	httpDeliver.Mount(router, ou, of, wu, aku, hu, gh, unversionedSunset(cfg.UnversionedSunset))
+
+	//Start the gRPC server on its own port
+	lis, err := net.Listen("tcp", ":"+strconv.Itoa(cfg.GRPCPort))
+	if err != nil {
+		fatal("Unable to listen on GRPC_PORT", err)
+	}
+	//Calls share the rate limits of the http API, the routes of the rules being http routes
+	grpcServer := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(
+			grpcDeliver.UnaryInstrument(),
+			grpcDeliver.UnaryLogging(logger),
+			grpcDeliver.UnaryLimitFailedAuth(rls, cfg.AuthFailureLimits),
+			grpcDeliver.UnaryAuth(aku, tv),
+			grpcDeliver.UnaryRateLimit(rls, cfg.FallbackLimit),
+		),
+		grpc.ChainStreamInterceptor(
+			grpcDeliver.StreamInstrument(),
+			grpcDeliver.StreamLogging(logger),
+			grpcDeliver.StreamLimitFailedAuth(rls, cfg.AuthFailureLimits),
+			grpcDeliver.StreamAuth(aku, tv),
+			grpcDeliver.StreamRateLimit(rls, cfg.FallbackLimit),
+		),
+	)
+	grpcDeliver.NewOrderGrpcServer(grpcServer, ou, of)
+	go func() {
+		if err := grpcServer.Serve(lis); err != nil {
+			fatal("The gRPC server stopped", err)
+		}
+	}()
 
 	//Start the server
-	log.Fatal(http.ListenAndServe(port(), nil))
+	server := &http.Server{
+		Addr:         ":" + strconv.Itoa(cfg.Port),
+		Handler:      router,
+		ReadTimeout:  cfg.HTTPReadTimeout,
+		WriteTimeout: cfg.HTTPWriteTimeout,
+		IdleTimeout:  cfg.HTTPIdleTimeout,
+	}
+	//End the order streams, which would otherwise keep the server from shutting down
+	server.RegisterOnShutdown(of.Close)
+	logger.Info("Serving", "port", cfg.Port, "grpc_port", cfg.GRPCPort, "config_file", cfg.File)
+	go func() {
+		if err := server.ListenAndServe(); err != http.ErrServerClosed {
+			fatal("The http server stopped", err)
+		}
+	}()
+
+	//Stop being ready on SIGTERM or SIGINT, giving the orchestrator time to stop sending requests
+	<-signals.Done()
+	stop()
+	hu.Shutdown()
+	logger.Info("Shutting down", "delay", cfg.ShutdownDelay.String(), "grace_period", cfg.ShutdownGracePeriod.String())
+	time.Sleep(cfg.ShutdownDelay)
+
+	//Drain the requests in flight, then stop the workers before closing the session they use
+	grace, cancel := context.WithTimeout(context.Background(), cfg.ShutdownGracePeriod)
+	defer cancel()
+	if err := server.Shutdown(grace); err != nil {
+		logger.Error("Unable to drain the http requests in flight", "error", err)
+	}
+	stopGRPC(grace, grpcServer)
+	stopWorkers()
+	if !waitFor(grace, workerGroup.Wait) {
+		logger.Error("Unable to stop the background workers", "error", grace.Err())
+	}
+	session.Close()
+	if err := shutdownTracing(grace); err != nil {
+		logger.Error("Unable to flush the spans", "error", err)
+	}
+	logger.Info("Shut down")
+	return 0
+}
+
+//stopGRPC stops the gRPC server once the calls in flight end, or cancels them once ctx is done
+func stopGRPC(ctx context.Context, server *grpc.Server) {
+	if !waitFor(ctx, server.GracefulStop) {
+		server.Stop()
+	}
+}
+
+//waitFor calls fn, returning false if ctx is done before it returns
+func waitFor(ctx context.Context, fn func()) bool {
+	done := make(chan struct{})
+	go func() {
+		fn()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+//connect connects a command other than serve to the database, giving up once ctx is done
+func connect(ctx context.Context, cfg *config.Config, logger *slog.Logger) (*orderRepo.MongoSession, bool) {
+	session, err := orderRepo.DialMongo(ctx, cfg.Mongo, logger)
+	if err != nil {
+		logger.Error("Unable to connect to the database", "error", err)
+		return nil, false
+	}
+	return session, true
+}
+
+//fatal logs the error which keeps the service from running and exits
+func fatal(msg string, err error) {
+	slog.Error(msg, "error", err)
+	os.Exit(1)
+}
+
+//tokenVerifier returns the verifier of the JWTs carried by end users, or nil when neither a secret nor
+//a key set is configured
+func tokenVerifier(cfg config.JWT, logger *slog.Logger) order.TokenVerifier {
+	var keys order.KeySet
+	if cfg.JWKSFile != "" {
+		keys = orderRepo.NewStaticKeySet(cfg.Keys)
+	} else if cfg.JWKSURL != "" {
+		keys = orderRepo.NewURLKeySet(cfg, logger)
+	}
+	if cfg.Secret == "" && keys == nil {
+		return nil
+	}
+	return orderUsecase.NewJWTVerifier(cfg, keys)
+}
+
+//healthCheckers returns the dependencies checked for readiness: the database, and the Google APIs when
+//READINESS_CHECK_DISTANCE is true as each check counts against the quota of the API key
+func healthCheckers(cfg *config.Config, session *orderRepo.MongoSession) map[string]order.HealthChecker {
+	checkers := map[string]order.HealthChecker{"mongo": orderRepo.NewMongoHealthChecker(session)}
+	if cfg.ReadinessCheckDistance {
+		checkers["distance"] = orderUsecase.NewDistanceHealthChecker(cfg.Google)
+	}
+	return checkers
+}
+
+//rateLimitStore returns the store of the rate limits, shared by the replicas unless it is "memory"
+func rateLimitStore(store string, session *orderRepo.MongoSession) order.RateLimitStore {
+	if store == config.RateLimitStoreMemory {
+		return orderRepo.NewMemoryRateLimitStore()
+	}
+	return orderRepo.NewMongoRateLimitStore(session)
 }
 
-func port() string {
-	port := os.Getenv("PORT")
-	if len(port) == 0 {
-		port = "8080"
+//unversionedSunset is when the deprecated unversioned paths stop being served, 6 months after their
+//deprecation unless set
+func unversionedSunset(sunset config.Date) time.Time {
+	if sunset.IsZero() {
+		return httpDeliver.UnversionedDeprecation.AddDate(0, 6, 0)
 	}
-	return ":" + port
+	return sunset.Time
 }
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/karanbhomiagit/order-service/config"
+	orderRepo "github.com/karanbhomiagit/order-service/order/repository"
+)
+
+const migrateUsage = `Usage: order-service [flags] migrate <action> [version]
+
+  status          lists the migrations and whether they were applied
+  up [version]    applies the pending migrations, up to the version if any
+  down [version]  rolls back the migrations above the version, only the last applied one by default
+`
+
+//migrate shows the status of the migrations of the database, applies them or rolls them back
+func migrate(cfg *config.Config, args []string, logger *slog.Logger) int {
+	if len(args) == 0 || len(args) > 2 {
+		fmt.Fprint(os.Stderr, migrateUsage)
+		return 2
+	}
+	action, target := args[0], 0
+	if len(args) == 2 {
+		version, err := strconv.Atoi(args[1])
+		if err != nil || version < 0 {
+			fmt.Fprintf(os.Stderr, "Invalid version %q\n\n%s", args[1], migrateUsage)
+			return 2
+		}
+		target = version
+	}
+	if action != "status" && action != "up" && action != "down" || action == "status" && len(args) == 2 {
+		fmt.Fprint(os.Stderr, migrateUsage)
+		return 2
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+	defer stop()
+	session, ok := connect(ctx, cfg, logger)
+	if !ok {
+		return 1
+	}
+	defer session.Close()
+	lr := orderRepo.NewMongoLeaseRepository(session)
+	migrator := orderRepo.NewMongoMigrator(session, lr, orderRepo.MongoMigrations, cfg.Migrations, logger)
+
+	var err error
+	switch action {
+	case "up":
+		err = migrator.Up(ctx, target)
+	case "down":
+		if len(args) == 1 {
+			target, err = previousVersion(migrator)
+		}
+		if err == nil {
+			err = migrator.Down(ctx, target)
+		}
+	}
+	if err != nil {
+		logger.Error("Unable to migrate the database", "error", err)
+		return 1
+	}
+	return printMigrations(migrator, logger)
+}
+
+//previousVersion returns the version preceding the last migration applied
+func previousVersion(migrator *orderRepo.MongoMigrator) (int, error) {
+	statuses, err := migrator.Status()
+	if err != nil {
+		return 0, err
+	}
+	previous, last := 0, 0
+	for _, status := range statuses {
+		if status.Status != orderRepo.MigrationPending {
+			previous, last = last, status.Version
+		}
+	}
+	return previous, nil
+}
+
+//printMigrations prints the status of every migration
+func printMigrations(migrator *orderRepo.MongoMigrator, logger *slog.Logger) int {
+	statuses, err := migrator.Status()
+	if err != nil {
+		logger.Error("Unable to list the migrations", "error", err)
+		return 1
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tDESCRIPTION\tSTATUS\tAPPLIED AT")
+	for _, status := range statuses {
+		appliedAt := ""
+		if !status.AppliedAt.IsZero() {
+			appliedAt = status.AppliedAt.Format(time.RFC3339)
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", status.Version, status.Description, status.Status, appliedAt)
+	}
+	w.Flush()
+	return 0
+}
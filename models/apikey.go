@@ -0,0 +1,24 @@
+package models
+
+import (
+	"time"
+
+	"gopkg.in/mgo.v2/bson"
+)
+
+//APIKey grants a client the listed scopes. Only the SHA-256 hash of the key is stored, the key itself
+//is only returned when it is issued.
+type APIKey struct {
+	ID        bson.ObjectId `bson:"_id" json:"id"`
+	Name      string        `bson:"name" json:"name"`
+	Key       string        `bson:"-" json:"key,omitempty"`
+	Hash      string        `bson:"hash" json:"-"`
+	Scopes    []string      `bson:"scopes" json:"scopes"`
+	CreatedAt time.Time     `bson:"createdAt" json:"createdAt"`
+	RevokedAt *time.Time    `bson:"revokedAt,omitempty" json:"revokedAt,omitempty"`
+}
+
+type APIKeyRequest struct {
+	Name   string   `json:"name"`
+	Scopes []string `json:"scopes"`
+}
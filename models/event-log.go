@@ -0,0 +1,11 @@
+package models
+
+import "time"
+
+//LoggedEvent is an event relayed from the outbox, numbered in the order it was relayed so that every replica
+//can follow the log
+type LoggedEvent struct {
+	Seq      int64      `bson:"_id"`
+	Event    OrderEvent `bson:"event"`
+	LoggedAt time.Time  `bson:"loggedAt"`
+}
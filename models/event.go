@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+const (
+	EventOrderCreated       = "order.created"
+	EventOrderAssigned      = "order.assigned"
+	EventOrderStatusChanged = "order.status_changed"
+
+	//EventSchemaVersion is bumped on any incompatible change to the event JSON
+	EventSchemaVersion = 1
+)
+
+//OrderEvent is the stable JSON schema of the events emitted when an order changes
+type OrderEvent struct {
+	ID         string         `bson:"id" json:"id"`
+	Type       string         `bson:"type" json:"type"`
+	Version    int            `bson:"version" json:"version"`
+	OccurredAt time.Time      `bson:"occurredAt" json:"occurredAt"`
+	Data       OrderEventData `bson:"data" json:"data"`
+}
+
+type OrderEventData struct {
+	OrderID        string `bson:"orderId" json:"orderId"`
+	Status         string `bson:"status" json:"status"`
+	PreviousStatus string `bson:"previousStatus,omitempty" json:"previousStatus,omitempty"`
+	Distance       int    `bson:"distance" json:"distance"`
+	//Owner routes the event to the webhooks of the client owning the order, it is not part of the schema
+	Owner string `bson:"owner,omitempty" json:"-"`
+}
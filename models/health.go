@@ -0,0 +1,21 @@
+package models
+
+//Statuses of the service and of the checks of its dependencies
+const (
+	HealthOK           = "ok"
+	HealthFailing      = "failing"
+	HealthShuttingDown = "shutting_down"
+)
+
+//Health is the readiness of the service along with the outcome of the check of each of its dependencies
+type Health struct {
+	Status string                 `json:"status"`
+	Checks map[string]HealthCheck `json:"checks,omitempty"`
+}
+
+//HealthCheck is the outcome of checking a dependency
+type HealthCheck struct {
+	Status    string  `json:"status"`
+	Error     string  `json:"error,omitempty"`
+	LatencyMs float64 `json:"latencyMs"`
+}
@@ -1,14 +1,30 @@
 package models
 
-import "gopkg.in/mgo.v2/bson"
+import (
+	"time"
+
+	"gopkg.in/mgo.v2/bson"
+)
 
 type Order struct {
 	ID       bson.ObjectId `bson:"_id" json:"id"`
 	Distance int           `bson:"distance" json:"distance"`
 	Status   string        `bson:"status" json:"status"`
+	//CreatedAt and UpdatedAt are zero for the orders stored before they were recorded, until migrated
+	CreatedAt time.Time `bson:"createdAt,omitempty" json:"createdAt"`
+	UpdatedAt time.Time `bson:"updatedAt,omitempty" json:"updatedAt"`
+	//Owner is the subject of the client which placed the order, empty for the orders placed before it was recorded
+	Owner string `bson:"owner,omitempty" json:"-"`
 }
 
 type OrderRequest struct {
 	Origin      []string `json:"origin"`
 	Destination []string `json:"destination"`
 }
+
+//OrderFilter narrows down a list of orders, zero values do not filter
+type OrderFilter struct {
+	Statuses    []string
+	MinDistance int
+	MaxDistance int
+}
@@ -0,0 +1,22 @@
+package models
+
+import (
+	"time"
+
+	"gopkg.in/mgo.v2/bson"
+)
+
+const (
+	OutboxPending = "PENDING"
+	OutboxDead    = "DEAD"
+)
+
+//OutboxEntry is an event stored alongside the order change it describes, waiting to be relayed
+type OutboxEntry struct {
+	ID            bson.ObjectId `bson:"_id"`
+	Event         OrderEvent    `bson:"event"`
+	Status        string        `bson:"status"`
+	Attempts      int           `bson:"attempts"`
+	NextAttemptAt time.Time     `bson:"nextAttemptAt"`
+	LastError     string        `bson:"lastError,omitempty"`
+}
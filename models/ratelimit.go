@@ -0,0 +1,81 @@
+package models
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//RateLimit allows Requests per Period to each client, in bursts of up to Requests
+type RateLimit struct {
+	Requests int
+	Period   time.Duration
+}
+
+//RateLimitRule limits the requests with the method to the routes matching the pattern, e.g. "/orders/:id".
+//Patterns apply under /v1 and to the unversioned aliases alike, which share the same limit.
+type RateLimitRule struct {
+	Method  string
+	Pattern string
+	Limit   RateLimit
+}
+
+//RateLimitStatus is the state of the token bucket of a client after a request took a token from it
+type RateLimitStatus struct {
+	Allowed   bool
+	Remaining int
+	//Reset is the time left until the bucket is full again
+	Reset time.Duration
+	//RetryAfter is the time left until the next token, when the request was not allowed
+	RetryAfter time.Duration
+}
+
+//RateLimitOff is the limit turning a rate limit off, letting every request through
+const RateLimitOff = "off"
+
+//ParseRateLimit parses a limit written as "<requests>/<period>", e.g. "10/1m", of positive requests and period,
+//or "off" which returns the zero RateLimit, letting every request through
+func ParseRateLimit(s string) (RateLimit, error) {
+	if strings.EqualFold(strings.TrimSpace(s), RateLimitOff) {
+		return RateLimit{}, nil
+	}
+	requests, period, ok := strings.Cut(strings.TrimSpace(s), "/")
+	if !ok {
+		return RateLimit{}, fmt.Errorf("invalid rate limit %q, expected <requests>/<period> or off", s)
+	}
+	n, err := strconv.Atoi(requests)
+	if err != nil {
+		return RateLimit{}, fmt.Errorf("invalid requests of rate limit %q: %v", s, err)
+	}
+	d, err := time.ParseDuration(period)
+	if err != nil {
+		return RateLimit{}, fmt.Errorf("invalid period of rate limit %q: %v", s, err)
+	}
+	if n <= 0 || d <= 0 {
+		return RateLimit{}, fmt.Errorf("invalid rate limit %q, requests and period must be positive", s)
+	}
+	return RateLimit{Requests: n, Period: d}, nil
+}
+
+//ParseRateLimitRules parses comma separated rules written as "<method> <pattern>=<requests>/<period>",
+//e.g. "POST /orders=10/1m, PATCH /orders/:id=60/1m"
+func ParseRateLimitRules(s string) ([]RateLimitRule, error) {
+	var rules []RateLimitRule
+	for _, spec := range strings.Split(s, ",") {
+		if strings.TrimSpace(spec) == "" {
+			continue
+		}
+		route, limit, ok := strings.Cut(spec, "=")
+		fields := strings.Fields(route)
+		if !ok || len(fields) != 2 {
+			return nil, fmt.Errorf("invalid rate limit rule %q, expected <method> <pattern>=<requests>/<period>", spec)
+		}
+		l, err := ParseRateLimit(limit)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, RateLimitRule{Method: strings.ToUpper(fields[0]), Pattern: fields[1], Limit: l})
+	}
+	return rules, nil
+}
@@ -0,0 +1,52 @@
+package models
+
+import (
+	"time"
+
+	"gopkg.in/mgo.v2/bson"
+)
+
+const (
+	//EventWebhookTest is the type of the sample event sent by the webhook test endpoint
+	EventWebhookTest = "webhook.test"
+)
+
+//Webhook is a merchant's subscription to order events. An empty Events list subscribes to every event.
+type Webhook struct {
+	ID        bson.ObjectId `bson:"_id" json:"id"`
+	URL       string        `bson:"url" json:"url"`
+	Events    []string      `bson:"events" json:"events"`
+	Secret    string        `bson:"secret" json:"secret,omitempty"`
+	CreatedAt time.Time     `bson:"createdAt" json:"createdAt"`
+	//Owner is the subject of the client which created the webhook, which only receives the events of its orders
+	Owner string `bson:"owner,omitempty" json:"-"`
+}
+
+type WebhookRequest struct {
+	URL    string   `json:"url"`
+	Events []string `json:"events"`
+}
+
+//WebhookDelivery records a single attempt to deliver an event to a webhook
+type WebhookDelivery struct {
+	ID          bson.ObjectId `bson:"_id" json:"id"`
+	WebhookID   bson.ObjectId `bson:"webhookId" json:"webhookId"`
+	EventID     string        `bson:"eventId" json:"eventId"`
+	EventType   string        `bson:"eventType" json:"eventType"`
+	Attempt     int           `bson:"attempt" json:"attempt"`
+	StatusCode  int           `bson:"statusCode,omitempty" json:"statusCode,omitempty"`
+	Error       string        `bson:"error,omitempty" json:"error,omitempty"`
+	Success     bool          `bson:"success" json:"success"`
+	DeliveredAt time.Time     `bson:"deliveredAt" json:"deliveredAt"`
+}
+
+//PendingDelivery is an event waiting to be delivered to a webhook. It is stored, so deliveries survive
+//restarts, until the webhook accepts the event or the last attempt fails.
+type PendingDelivery struct {
+	//ID is made of the ids of the webhook and of the event, so an event is only queued once per webhook
+	ID            string        `bson:"_id"`
+	WebhookID     bson.ObjectId `bson:"webhookId"`
+	Event         OrderEvent    `bson:"event"`
+	Attempts      int           `bson:"attempts"`
+	NextAttemptAt time.Time     `bson:"nextAttemptAt"`
+}
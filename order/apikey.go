@@ -0,0 +1,23 @@
+package order
+
+import (
+	"time"
+
+	"github.com/karanbhomiagit/order-service/models"
+)
+
+//APIKeyRepository represents the API keys' storage/retrieval as an interface
+type APIKeyRepository interface {
+	FetchByHash(string) (*models.APIKey, error)
+	FetchAll() ([]models.APIKey, error)
+	Store(*models.APIKey) (*models.APIKey, error)
+	RevokeByID(string, time.Time) error
+}
+
+//APIKeyUsecase represents the issuing and verification of API keys as an interface
+type APIKeyUsecase interface {
+	Authenticate(string) (*Identity, error)
+	FetchAll() ([]models.APIKey, error)
+	Issue(*models.APIKeyRequest) (*models.APIKey, error)
+	RevokeByID(string) error
+}
@@ -0,0 +1,234 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/karanbhomiagit/order-service/models"
+	"github.com/karanbhomiagit/order-service/order"
+	"github.com/karanbhomiagit/order-service/order/logging"
+)
+
+//ErrUsage is returned when the command was not invoked as documented, after writing its usage
+var ErrUsage = errors.New("invalid usage")
+
+//Output formats
+const (
+	OutputTable = "table"
+	OutputJSON  = "json"
+)
+
+const orderUsage = `Usage: order-service [flags] orders <action> [-output table|json] [arguments]
+
+  get <id>                    shows an order
+  list [-page n]              lists a page of orders
+  filter [-page n] [filters]  lists a page of the orders matching the filters
+  transition <id> <status>    forces an order to UNASSIGNED, TAKEN, EXPIRED or CANCELLED
+  cancel <id>                 cancels an order
+  export [filters]            writes every order matching the filters, one JSON object per line with -output json
+
+Filters: -status TAKEN,UNASSIGNED -min-distance 1000 -max-distance 5000
+`
+
+//OrderCommand lets support staff operate on orders from the command line. It goes through the usecase like
+//the APIs do, so the business rules apply and the changes are recorded as events.
+type OrderCommand struct {
+	oUsecase order.Usecase
+	pageSize int
+	out      io.Writer
+	errOut   io.Writer
+	logger   *slog.Logger
+}
+
+//NewOrderCommand returns the command writing its output to out and its usage to errOut. Lists are fetched
+//pageSize orders at a time.
+func NewOrderCommand(ou order.Usecase, pageSize int, out io.Writer, errOut io.Writer, logger *slog.Logger) *OrderCommand {
+	return &OrderCommand{
+		oUsecase: ou,
+		pageSize: pageSize,
+		out:      out,
+		errOut:   errOut,
+		logger:   logger,
+	}
+}
+
+//invocation is an action along with its flags and operands
+type invocation struct {
+	action   string
+	output   string
+	page     int
+	filter   *models.OrderFilter
+	operands []string
+}
+
+//arity is the number of operands of every action
+var arity = map[string]int{"get": 1, "list": 0, "filter": 0, "transition": 2, "cancel": 1, "export": 0}
+
+//statuses are the statuses of the orders the filters accept
+var statuses = []string{"UNASSIGNED", "TAKEN", "EXPIRED", "CANCELLED"}
+
+//CheckOrderArgs checks the action of args is invoked as documented without running it, writing the usage to
+//errOut otherwise, so mistakes are reported before connecting to the database
+func CheckOrderArgs(args []string, errOut io.Writer) error {
+	_, err := parse(args, errOut)
+	return err
+}
+
+//Run runs the action of args, e.g. get <id>, on behalf of the support staff
+func (c *OrderCommand) Run(ctx context.Context, args []string) error {
+	inv, err := parse(args, c.errOut)
+	if err != nil {
+		return err
+	}
+
+	//Changes are made as an internal caller, which is not restricted by the roles of end users
+	ctx = order.NewContext(ctx, &order.Identity{Subject: "cli", Scopes: order.Scopes})
+	ctx = logging.NewContext(ctx, c.logger, &logging.Request{ID: logging.NewRequestID(), Route: "orders " + inv.action, Start: time.Now()})
+	switch inv.action {
+	case "get":
+		res, err := c.oUsecase.FetchByID(ctx, inv.operands[0])
+		return c.write(inv.output, res, err)
+	case "list":
+		res, err := c.oUsecase.FetchByRange(ctx, inv.page, c.pageSize)
+		return c.write(inv.output, res, err)
+	case "filter":
+		res, err := c.oUsecase.FetchByFilter(ctx, inv.filter, inv.page, c.pageSize)
+		return c.write(inv.output, res, err)
+	case "transition":
+		res, err := c.oUsecase.TransitionByID(ctx, inv.operands[0], strings.ToUpper(inv.operands[1]))
+		return c.write(inv.output, res, err)
+	case "cancel":
+		res, err := c.oUsecase.CancelByID(ctx, inv.operands[0])
+		return c.write(inv.output, res, err)
+	default:
+		return c.export(ctx, inv.output, inv.filter)
+	}
+}
+
+//parse parses the action of args with its flags, writing the usage to errOut when not invoked as documented
+func parse(args []string, errOut io.Writer) (*invocation, error) {
+	if len(args) == 0 {
+		return nil, usage(errOut)
+	}
+	inv := &invocation{action: args[0], filter: &models.OrderFilter{}}
+	operands, ok := arity[inv.action]
+	if !ok {
+		return nil, usage(errOut)
+	}
+	fs := flag.NewFlagSet("orders "+inv.action, flag.ContinueOnError)
+	fs.SetOutput(errOut)
+	fs.Usage = func() { fmt.Fprint(errOut, orderUsage) }
+	fs.StringVar(&inv.output, "output", OutputTable, "format of the output: table or json")
+	fs.IntVar(&inv.page, "page", 1, "page of the orders to list")
+	filterStatuses := fs.String("status", "", "comma separated statuses of the orders")
+	fs.IntVar(&inv.filter.MinDistance, "min-distance", 0, "shortest distance of the orders")
+	fs.IntVar(&inv.filter.MaxDistance, "max-distance", 0, "longest distance of the orders")
+	if err := fs.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil, err
+		}
+		return nil, ErrUsage
+	}
+	inv.operands = fs.Args()
+	if len(inv.operands) != operands || inv.output != OutputTable && inv.output != OutputJSON || inv.page < 1 {
+		return nil, usage(errOut)
+	}
+	if *filterStatuses != "" {
+		//Statuses are upper-cased like the status of transition
+		for _, status := range strings.Split(*filterStatuses, ",") {
+			status = strings.ToUpper(strings.TrimSpace(status))
+			if !slices.Contains(statuses, status) {
+				fmt.Fprintf(errOut, "Unknown status %q\n\n", status)
+				return nil, usage(errOut)
+			}
+			inv.filter.Statuses = append(inv.filter.Statuses, status)
+		}
+	}
+	return inv, nil
+}
+
+//export writes every order matching the filter, page by page, so they are written as they are fetched. Each page
+//follows the last order of the previous one, so orders created during the export do not shift the pages.
+func (c *OrderCommand) export(ctx context.Context, output string, filter *models.OrderFilter) error {
+	table := tabwriter.NewWriter(c.out, 0, 4, 2, ' ', 0)
+	encoder := json.NewEncoder(c.out)
+	if output == OutputTable {
+		writeHeader(table)
+	}
+	after := ""
+	for {
+		orders, err := c.oUsecase.FetchByFilterAfter(ctx, filter, after, c.pageSize)
+		if err != nil {
+			return err
+		}
+		for _, o := range orders {
+			if output == OutputJSON {
+				if err := encoder.Encode(o); err != nil {
+					return err
+				}
+			} else {
+				writeRow(table, o)
+			}
+		}
+		if err := table.Flush(); err != nil {
+			return err
+		}
+		if len(orders) < c.pageSize {
+			return nil
+		}
+		after = orders[len(orders)-1].ID.Hex()
+	}
+}
+
+//write writes the order or orders in the format of the output, unless the usecase failed
+func (c *OrderCommand) write(output string, res interface{}, err error) error {
+	if err != nil {
+		return err
+	}
+	if output == OutputJSON {
+		encoder := json.NewEncoder(c.out)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(res)
+	}
+	table := tabwriter.NewWriter(c.out, 0, 4, 2, ' ', 0)
+	writeHeader(table)
+	switch res := res.(type) {
+	case *models.Order:
+		writeRow(table, *res)
+	case []models.Order:
+		for _, o := range res {
+			writeRow(table, o)
+		}
+	}
+	return table.Flush()
+}
+
+func usage(errOut io.Writer) error {
+	fmt.Fprint(errOut, orderUsage)
+	return ErrUsage
+}
+
+func writeHeader(w io.Writer) {
+	fmt.Fprintln(w, "ID\tSTATUS\tDISTANCE\tCREATED AT\tUPDATED AT")
+}
+
+func writeRow(w io.Writer, o models.Order) {
+	fmt.Fprintf(w, "%s\t%s\t%d\t%s\t%s\n", o.ID.Hex(), o.Status, o.Distance, formatTime(o.CreatedAt), formatTime(o.UpdatedAt))
+}
+
+//formatTime formats the time, left blank for the orders stored before it was recorded
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
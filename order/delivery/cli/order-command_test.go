@@ -0,0 +1,232 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/karanbhomiagit/order-service/models"
+	"github.com/karanbhomiagit/order-service/order"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"gopkg.in/mgo.v2/bson"
+)
+
+type MockedOrderUsecase struct {
+	mock.Mock
+}
+
+func (ou *MockedOrderUsecase) AssignByID(ctx context.Context, id string, status string) (*map[string]string, error) {
+	args := ou.Called(id, status)
+	return args.Get(0).(*map[string]string), args.Error(1)
+}
+
+func (ou *MockedOrderUsecase) FetchByRange(ctx context.Context, page int, limit int) ([]models.Order, error) {
+	args := ou.Called(page, limit)
+	return args.Get(0).([]models.Order), args.Error(1)
+}
+
+func (ou *MockedOrderUsecase) CancelByID(ctx context.Context, id string) (*models.Order, error) {
+	args := ou.Called(id)
+	return args.Get(0).(*models.Order), args.Error(1)
+}
+
+func (ou *MockedOrderUsecase) FetchByID(ctx context.Context, id string) (*models.Order, error) {
+	args := ou.Called(id)
+	return args.Get(0).(*models.Order), args.Error(1)
+}
+
+func (ou *MockedOrderUsecase) FetchByFilter(ctx context.Context, filter *models.OrderFilter, page int, limit int) ([]models.Order, error) {
+	args := ou.Called(filter, page, limit)
+	return args.Get(0).([]models.Order), args.Error(1)
+}
+
+func (ou *MockedOrderUsecase) FetchByFilterAfter(ctx context.Context, filter *models.OrderFilter, after string, limit int) ([]models.Order, error) {
+	args := ou.Called(filter, after, limit)
+	return args.Get(0).([]models.Order), args.Error(1)
+}
+
+func (ou *MockedOrderUsecase) Store(ctx context.Context, orderReq *models.OrderRequest) (*models.Order, error) {
+	args := ou.Called(orderReq)
+	return args.Get(0).(*models.Order), args.Error(1)
+}
+
+func (ou *MockedOrderUsecase) TransitionByID(ctx context.Context, id string, status string) (*models.Order, error) {
+	args := ou.Called(id, status)
+	return args.Get(0).(*models.Order), args.Error(1)
+}
+
+//identityRecordingUsecase records the identity orders are cancelled with
+type identityRecordingUsecase struct {
+	MockedOrderUsecase
+	identity *order.Identity
+}
+
+func (ou *identityRecordingUsecase) CancelByID(ctx context.Context, id string) (*models.Order, error) {
+	ou.identity, _ = order.IdentityFrom(ctx)
+	return ou.MockedOrderUsecase.CancelByID(ctx, id)
+}
+
+var createdAt = time.Date(2026, 10, 19, 9, 30, 0, 0, time.UTC)
+
+func testOrder(id string, status string) models.Order {
+	return models.Order{ID: bson.ObjectIdHex(id), Distance: 1200, Status: status, CreatedAt: createdAt, UpdatedAt: createdAt}
+}
+
+//run runs the command with a page size of 2, returning what it wrote to stdout and stderr
+func run(ou order.Usecase, args ...string) (string, string, error) {
+	var out, errOut bytes.Buffer
+	command := NewOrderCommand(ou, 2, &out, &errOut, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	err := command.Run(context.Background(), args)
+	return out.String(), errOut.String(), err
+}
+
+func TestRun(t *testing.T) {
+	t.Run("Should print the order as a table", func(t *testing.T) {
+		testObj := new(MockedOrderUsecase)
+		o := testOrder("5b9a7d5e1c9d440000a1b2c3", "UNASSIGNED")
+		testObj.On("FetchByID", "5b9a7d5e1c9d440000a1b2c3").Return(&o, nil)
+
+		out, _, err := run(testObj, "get", "5b9a7d5e1c9d440000a1b2c3")
+
+		assert.NoError(t, err)
+		assert.Equal(t, "ID                        STATUS      DISTANCE  CREATED AT            UPDATED AT\n"+
+			"5b9a7d5e1c9d440000a1b2c3  UNASSIGNED  1200      2026-10-19T09:30:00Z  2026-10-19T09:30:00Z\n", out)
+		testObj.AssertExpectations(t)
+	})
+
+	t.Run("Should print the order as JSON", func(t *testing.T) {
+		testObj := new(MockedOrderUsecase)
+		o := testOrder("5b9a7d5e1c9d440000a1b2c3", "TAKEN")
+		testObj.On("FetchByID", "5b9a7d5e1c9d440000a1b2c3").Return(&o, nil)
+
+		out, _, err := run(testObj, "get", "-output", "json", "5b9a7d5e1c9d440000a1b2c3")
+
+		assert.NoError(t, err)
+		assert.JSONEq(t, `{"id":"5b9a7d5e1c9d440000a1b2c3","distance":1200,"status":"TAKEN","createdAt":"2026-10-19T09:30:00Z","updatedAt":"2026-10-19T09:30:00Z"}`, out)
+		testObj.AssertExpectations(t)
+	})
+
+	t.Run("Should list the requested page of orders", func(t *testing.T) {
+		testObj := new(MockedOrderUsecase)
+		testObj.On("FetchByRange", 3, 2).Return([]models.Order{testOrder("5b9a7d5e1c9d440000a1b2c3", "TAKEN")}, nil)
+
+		out, _, err := run(testObj, "list", "-page", "3")
+
+		assert.NoError(t, err)
+		assert.Contains(t, out, "5b9a7d5e1c9d440000a1b2c3  TAKEN")
+		testObj.AssertExpectations(t)
+	})
+
+	t.Run("Should filter the orders by status and distance", func(t *testing.T) {
+		testObj := new(MockedOrderUsecase)
+		filter := &models.OrderFilter{Statuses: []string{"TAKEN", "UNASSIGNED"}, MinDistance: 1000, MaxDistance: 5000}
+		testObj.On("FetchByFilter", filter, 1, 2).Return([]models.Order{}, nil)
+
+		out, _, err := run(testObj, "filter", "-status", "TAKEN,UNASSIGNED", "-min-distance", "1000", "-max-distance", "5000")
+
+		assert.NoError(t, err)
+		assert.Equal(t, "ID  STATUS  DISTANCE  CREATED AT  UPDATED AT\n", out)
+		testObj.AssertExpectations(t)
+	})
+
+	t.Run("Should upper-case the statuses of the filters", func(t *testing.T) {
+		testObj := new(MockedOrderUsecase)
+		filter := &models.OrderFilter{Statuses: []string{"TAKEN", "CANCELLED"}}
+		testObj.On("FetchByFilter", filter, 1, 2).Return([]models.Order{}, nil)
+
+		_, _, err := run(testObj, "filter", "-status", "taken, Cancelled")
+
+		assert.NoError(t, err)
+		testObj.AssertExpectations(t)
+	})
+
+	t.Run("Should print the usage for an unknown status", func(t *testing.T) {
+		testObj := new(MockedOrderUsecase)
+
+		_, errOut, err := run(testObj, "export", "-status", "TAKEN,DELIVERED")
+
+		assert.ErrorIs(t, err, ErrUsage)
+		assert.Contains(t, errOut, `Unknown status "DELIVERED"`)
+		assert.Contains(t, errOut, "Usage: order-service")
+		testObj.AssertNotCalled(t, "FetchByFilterAfter", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Should force the transition of the order to the status", func(t *testing.T) {
+		testObj := new(MockedOrderUsecase)
+		o := testOrder("5b9a7d5e1c9d440000a1b2c3", "UNASSIGNED")
+		testObj.On("TransitionByID", "5b9a7d5e1c9d440000a1b2c3", "UNASSIGNED").Return(&o, nil)
+
+		_, _, err := run(testObj, "transition", "5b9a7d5e1c9d440000a1b2c3", "unassigned")
+
+		assert.NoError(t, err)
+		testObj.AssertExpectations(t)
+	})
+
+	t.Run("Should cancel the order as an internal caller", func(t *testing.T) {
+		testObj := new(identityRecordingUsecase)
+		o := testOrder("5b9a7d5e1c9d440000a1b2c3", "CANCELLED")
+		testObj.On("CancelByID", "5b9a7d5e1c9d440000a1b2c3").Return(&o, nil)
+
+		_, _, err := run(testObj, "cancel", "5b9a7d5e1c9d440000a1b2c3")
+
+		assert.NoError(t, err)
+		assert.Equal(t, "cli", testObj.identity.Subject)
+		assert.Empty(t, testObj.identity.Roles)
+		testObj.AssertExpectations(t)
+	})
+
+	t.Run("Should return the error of the usecase without printing anything", func(t *testing.T) {
+		testObj := new(MockedOrderUsecase)
+		testObj.On("CancelByID", "5b9a7d5e1c9d440000a1b2c3").Return(&models.Order{}, order.NewConflict("order_already_assigned", "Order is already assigned"))
+
+		out, _, err := run(testObj, "cancel", "5b9a7d5e1c9d440000a1b2c3")
+
+		assert.EqualError(t, err, "Order is already assigned")
+		assert.Empty(t, out)
+		testObj.AssertExpectations(t)
+	})
+
+	t.Run("Should export every page of the orders as lines of JSON", func(t *testing.T) {
+		testObj := new(MockedOrderUsecase)
+		filter := &models.OrderFilter{Statuses: []string{"TAKEN"}}
+		testObj.On("FetchByFilterAfter", filter, "", 2).Return([]models.Order{testOrder("5b9a7d5e1c9d440000a1b2c1", "TAKEN"), testOrder("5b9a7d5e1c9d440000a1b2c2", "TAKEN")}, nil)
+		testObj.On("FetchByFilterAfter", filter, "5b9a7d5e1c9d440000a1b2c2", 2).Return([]models.Order{testOrder("5b9a7d5e1c9d440000a1b2c3", "TAKEN")}, nil)
+
+		out, _, err := run(testObj, "export", "-output", "json", "-status", "TAKEN")
+
+		assert.NoError(t, err)
+		assert.Equal(t, 3, bytes.Count([]byte(out), []byte("\n")))
+		assert.Contains(t, out, `{"id":"5b9a7d5e1c9d440000a1b2c3","distance":1200,"status":"TAKEN"`)
+		testObj.AssertExpectations(t)
+	})
+
+	t.Run("Should print the usage for an unknown action or missing arguments", func(t *testing.T) {
+		testObj := new(MockedOrderUsecase)
+
+		for _, args := range [][]string{{}, {"delete", "5b9a7d5e1c9d440000a1b2c3"}, {"get"}, {"transition", "5b9a7d5e1c9d440000a1b2c3"}, {"list", "-output", "xml"}, {"list", "-page", "0"}} {
+			_, errOut, err := run(testObj, args...)
+
+			assert.ErrorIs(t, err, ErrUsage, args)
+			assert.Contains(t, errOut, "Usage: order-service", args)
+		}
+		testObj.AssertExpectations(t)
+	})
+}
+
+func TestCheckOrderArgs(t *testing.T) {
+	t.Run("Should accept the actions invoked as documented", func(t *testing.T) {
+		var errOut bytes.Buffer
+		assert.NoError(t, CheckOrderArgs([]string{"filter", "-status", "taken", "-page", "2"}, &errOut))
+		assert.Empty(t, errOut.String())
+	})
+
+	t.Run("Should print the usage for the others", func(t *testing.T) {
+		var errOut bytes.Buffer
+		assert.ErrorIs(t, CheckOrderArgs([]string{"cancel"}, &errOut), ErrUsage)
+		assert.Contains(t, errOut.String(), "Usage: order-service")
+	})
+}
@@ -0,0 +1,48 @@
+package graphql
+
+import (
+	"strings"
+
+	"github.com/vektah/gqlparser/v2/ast"
+)
+
+//complexity returns the cost of the operation: one for each field, the fields selected under a list
+//counting once for each item it may return according to its limit argument. Introspection is free.
+func complexity(doc *ast.QueryDocument, operationName string, variables map[string]interface{}) int {
+	var op *ast.OperationDefinition
+	if operationName == "" && len(doc.Operations) == 1 {
+		op = doc.Operations[0]
+	} else {
+		op = doc.Operations.ForName(operationName)
+	}
+	if op == nil {
+		return 0
+	}
+	return selectionComplexity(op.SelectionSet, variables)
+}
+
+func selectionComplexity(selections ast.SelectionSet, variables map[string]interface{}) int {
+	total := 0
+	for _, selection := range selections {
+		switch sel := selection.(type) {
+		case *ast.Field:
+			if strings.HasPrefix(sel.Name, "__") {
+				continue
+			}
+			items := 1
+			if sel.Definition != nil && sel.Definition.Type.Elem != nil {
+				if limit, ok := sel.ArgumentMap(variables)["limit"].(int64); ok && limit > 0 {
+					items = int(limit)
+				}
+			}
+			total += 1 + items*selectionComplexity(sel.SelectionSet, variables)
+		case *ast.InlineFragment:
+			total += selectionComplexity(sel.SelectionSet, variables)
+		case *ast.FragmentSpread:
+			if sel.Definition != nil {
+				total += selectionComplexity(sel.Definition.SelectionSet, variables)
+			}
+		}
+	}
+	return total
+}
@@ -0,0 +1,88 @@
+package graphql
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	gql "github.com/graph-gophers/graphql-go"
+	gqlerrors "github.com/graph-gophers/graphql-go/errors"
+	"github.com/karanbhomiagit/order-service/order"
+	"github.com/karanbhomiagit/order-service/order/logging"
+	"github.com/vektah/gqlparser/v2"
+	"github.com/vektah/gqlparser/v2/ast"
+)
+
+//Schema is the GraphQL schema of the orders
+//
+//go:embed schema.graphql
+var Schema string
+
+type GraphqlHandler struct {
+	schema        *gql.Schema
+	querySchema   *ast.Schema
+	maxComplexity int
+}
+
+type graphqlRequest struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+//NewGraphqlHandler returns the handler executing GraphQL requests against the usecase, rejecting
+//queries nested deeper than maxDepth or costing more than maxComplexity
+func NewGraphqlHandler(ou order.Usecase, maxDepth int, maxComplexity int) http.Handler {
+	return &GraphqlHandler{
+		schema:        gql.MustParseSchema(Schema, &resolver{orderUsecase: ou}, gql.MaxDepth(maxDepth)),
+		querySchema:   gqlparser.MustLoadSchema(&ast.Source{Name: "schema.graphql", Input: Schema}),
+		maxComplexity: maxComplexity,
+	}
+}
+
+func (h *GraphqlHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	var req graphqlRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		logging.FromContext(r.Context()).Debug("Unable to decode the request body", "error", err)
+		respond(w, r, http.StatusBadRequest, &gql.Response{Errors: []*gqlerrors.QueryError{graphqlError("invalid_payload", "Invalid request payload")}})
+		return
+	}
+	//The complexity of a query which does not validate cannot be told, so it is not executed
+	doc, errs := gqlparser.LoadQuery(h.querySchema, req.Query)
+	if errs != nil {
+		queryErrs := make([]*gqlerrors.QueryError, 0, len(errs))
+		for _, err := range errs {
+			queryErrs = append(queryErrs, graphqlError("invalid_query", err.Message))
+		}
+		respond(w, r, http.StatusOK, &gql.Response{Errors: queryErrs})
+		return
+	}
+	if cost := complexity(doc, req.OperationName, req.Variables); cost > h.maxComplexity {
+		message := fmt.Sprintf("Query complexity %d exceeds the maximum of %d", cost, h.maxComplexity)
+		respond(w, r, http.StatusOK, &gql.Response{Errors: []*gqlerrors.QueryError{graphqlError("query_too_complex", message)}})
+		return
+	}
+	res := h.schema.Exec(r.Context(), req.Query, req.OperationName, req.Variables)
+	respond(w, r, http.StatusOK, res)
+}
+
+func graphqlError(code string, message string) *gqlerrors.QueryError {
+	return &gqlerrors.QueryError{
+		Message:    message,
+		Extensions: map[string]interface{}{"code": code},
+	}
+}
+
+func respond(w http.ResponseWriter, r *http.Request, status int, res *gql.Response) {
+	b, err := json.Marshal(res)
+	if err != nil {
+		logging.FromContext(r.Context()).Error("Unable to marshal the response", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Add("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	w.Write(b)
+}
@@ -0,0 +1,218 @@
+package graphql
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/karanbhomiagit/order-service/models"
+	"github.com/karanbhomiagit/order-service/order"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"gopkg.in/mgo.v2/bson"
+)
+
+type MockedOrderUsecase struct {
+	mock.Mock
+}
+
+func (ou *MockedOrderUsecase) AssignByID(ctx context.Context, id string, status string) (*map[string]string, error) {
+	args := ou.Called(id, status)
+	return args.Get(0).(*map[string]string), args.Error(1)
+}
+
+func (ou *MockedOrderUsecase) CancelByID(ctx context.Context, id string) (*models.Order, error) {
+	args := ou.Called(id)
+	return args.Get(0).(*models.Order), args.Error(1)
+}
+
+func (ou *MockedOrderUsecase) FetchByID(ctx context.Context, id string) (*models.Order, error) {
+	args := ou.Called(id)
+	return args.Get(0).(*models.Order), args.Error(1)
+}
+
+func (ou *MockedOrderUsecase) FetchByRange(ctx context.Context, page int, limit int) ([]models.Order, error) {
+	args := ou.Called(page, limit)
+	return args.Get(0).([]models.Order), args.Error(1)
+}
+
+func (ou *MockedOrderUsecase) FetchByFilter(ctx context.Context, filter *models.OrderFilter, page int, limit int) ([]models.Order, error) {
+	args := ou.Called(filter, page, limit)
+	return args.Get(0).([]models.Order), args.Error(1)
+}
+
+func (ou *MockedOrderUsecase) FetchByFilterAfter(ctx context.Context, filter *models.OrderFilter, after string, limit int) ([]models.Order, error) {
+	args := ou.Called(filter, after, limit)
+	return args.Get(0).([]models.Order), args.Error(1)
+}
+
+func (ou *MockedOrderUsecase) Store(ctx context.Context, orderReq *models.OrderRequest) (*models.Order, error) {
+	args := ou.Called(orderReq)
+	return args.Get(0).(*models.Order), args.Error(1)
+}
+
+func (ou *MockedOrderUsecase) TransitionByID(ctx context.Context, id string, status string) (*models.Order, error) {
+	args := ou.Called(id, status)
+	return args.Get(0).(*models.Order), args.Error(1)
+}
+
+//query posts the GraphQL request to the handler as an admin and returns the response body
+func query(handler http.Handler, body string) (int, string) {
+	return queryAs(handler, &order.Identity{Subject: "admin", Scopes: order.Scopes}, body)
+}
+
+//queryAs posts the GraphQL request to the handler on behalf of the identity, anonymously when nil
+func queryAs(handler http.Handler, identity *order.Identity, body string) (int, string) {
+	req, _ := http.NewRequest(http.MethodPost, "/graphql", strings.NewReader(body))
+	if identity != nil {
+		req = req.WithContext(order.NewContext(req.Context(), identity))
+	}
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	res, _ := ioutil.ReadAll(rec.Body)
+	return rec.Code, string(res)
+}
+
+/*
+	Actual test functions
+*/
+
+func TestGraphqlHandler(t *testing.T) {
+	id := "5c2b2aaf4530558539f91859"
+	taken := models.Order{ID: bson.ObjectIdHex(id), Distance: 12345, Status: "TAKEN"}
+
+	t.Run("Should resolve an order by id with the selected fields", func(t *testing.T) {
+		assert := assert.New(t)
+		testObj := new(MockedOrderUsecase)
+		testObj.On("FetchByID", id).Return(&taken, nil)
+
+		code, body := query(NewGraphqlHandler(testObj, 5, 100), `{"query":"{ order(id: \"`+id+`\") { id status } }"}`)
+		assert.Equal(http.StatusOK, code)
+		assert.JSONEq(`{"data":{"order":{"id":"`+id+`","status":"TAKEN"}}}`, body)
+		testObj.AssertExpectations(t)
+	})
+
+	t.Run("Should list orders with filters and pagination from variables", func(t *testing.T) {
+		assert := assert.New(t)
+		testObj := new(MockedOrderUsecase)
+		filter := &models.OrderFilter{Statuses: []string{"TAKEN", "EXPIRED"}, MinDistance: 1000}
+		testObj.On("FetchByFilter", filter, 2, 5).Return([]models.Order{taken}, nil)
+
+		code, body := query(NewGraphqlHandler(testObj, 5, 100), `{
+			"query": "query List($filter: OrderFilter, $page: Int!) { orders(filter: $filter, page: $page, limit: 5) { id distance } }",
+			"variables": {"filter": {"status": ["TAKEN", "EXPIRED"], "minDistance": 1000}, "page": 2}
+		}`)
+		assert.Equal(http.StatusOK, code)
+		assert.JSONEq(`{"data":{"orders":[{"id":"`+id+`","distance":12345}]}}`, body)
+		testObj.AssertExpectations(t)
+	})
+
+	t.Run("Should create an order", func(t *testing.T) {
+		assert := assert.New(t)
+		testObj := new(MockedOrderUsecase)
+		created := models.Order{ID: bson.ObjectIdHex(id), Distance: 5000, Status: "UNASSIGNED"}
+		testObj.On("Store", &models.OrderRequest{Origin: []string{"22.3193", "114.1694"}, Destination: []string{"22.2783", "114.1747"}}).Return(&created, nil)
+
+		code, body := query(NewGraphqlHandler(testObj, 5, 100), `{"query":"mutation { createOrder(input: {origin: {latitude: \"22.3193\", longitude: \"114.1694\"}, destination: {latitude: \"22.2783\", longitude: \"114.1747\"}}) { id distance status } }"}`)
+		assert.Equal(http.StatusOK, code)
+		assert.JSONEq(`{"data":{"createOrder":{"id":"`+id+`","distance":5000,"status":"UNASSIGNED"}}}`, body)
+		testObj.AssertExpectations(t)
+	})
+
+	t.Run("Should assign an order and return it", func(t *testing.T) {
+		assert := assert.New(t)
+		testObj := new(MockedOrderUsecase)
+		testObj.On("AssignByID", id, "TAKEN").Return(&map[string]string{"status": "SUCCESS"}, nil)
+		testObj.On("FetchByID", id).Return(&taken, nil)
+
+		code, body := query(NewGraphqlHandler(testObj, 5, 100), `{"query":"mutation { assignOrder(id: \"`+id+`\") { status } }"}`)
+		assert.Equal(http.StatusOK, code)
+		assert.JSONEq(`{"data":{"assignOrder":{"status":"TAKEN"}}}`, body)
+		testObj.AssertExpectations(t)
+	})
+
+	t.Run("Should report the error code of a failed mutation", func(t *testing.T) {
+		assert := assert.New(t)
+		testObj := new(MockedOrderUsecase)
+		testObj.On("CancelByID", id).Return((*models.Order)(nil), order.NewConflict("order_expired", "Order has expired"))
+
+		code, body := query(NewGraphqlHandler(testObj, 5, 100), `{"query":"mutation { cancelOrder(id: \"`+id+`\") { status } }"}`)
+		assert.Equal(http.StatusOK, code)
+		assert.JSONEq(`{"data":null,"errors":[{"message":"Order has expired","path":["cancelOrder"],"extensions":{"code":"order_expired"}}]}`, body)
+		testObj.AssertExpectations(t)
+	})
+
+	t.Run("Should report unauthenticated for anonymous requests", func(t *testing.T) {
+		assert := assert.New(t)
+		testObj := new(MockedOrderUsecase)
+
+		code, body := queryAs(NewGraphqlHandler(testObj, 5, 100), nil, `{"query":"{ order(id: \"`+id+`\") { status } }"}`)
+		assert.Equal(http.StatusOK, code)
+		assert.JSONEq(`{"data":null,"errors":[{"message":"Authentication is required","path":["order"],"extensions":{"code":"unauthenticated"}}]}`, body)
+		testObj.AssertExpectations(t)
+	})
+
+	t.Run("Should report forbidden when the scope of a mutation was not granted", func(t *testing.T) {
+		assert := assert.New(t)
+		testObj := new(MockedOrderUsecase)
+		identity := &order.Identity{Subject: "merchant", Scopes: []string{order.ScopeOrdersRead}}
+
+		code, body := queryAs(NewGraphqlHandler(testObj, 5, 100), identity, `{"query":"mutation { cancelOrder(id: \"`+id+`\") { status } }"}`)
+		assert.Equal(http.StatusOK, code)
+		assert.JSONEq(`{"data":null,"errors":[{"message":"Missing scope orders:cancel","path":["cancelOrder"],"extensions":{"code":"forbidden"}}]}`, body)
+		testObj.AssertExpectations(t)
+	})
+
+	t.Run("Should reject queries deeper than the maximum depth", func(t *testing.T) {
+		assert := assert.New(t)
+		testObj := new(MockedOrderUsecase)
+
+		code, body := query(NewGraphqlHandler(testObj, 1, 100), `{"query":"{ orders { id } }"}`)
+		assert.Equal(http.StatusOK, code)
+		assert.Contains(body, "exceeds max depth 1")
+		testObj.AssertExpectations(t)
+	})
+
+	t.Run("Should reject queries more complex than the maximum", func(t *testing.T) {
+		assert := assert.New(t)
+		testObj := new(MockedOrderUsecase)
+
+		//1 for orders and 3 fields for each of the 10 orders
+		code, body := query(NewGraphqlHandler(testObj, 5, 30), `{"query":"{ orders { id distance status } }"}`)
+		assert.Equal(http.StatusOK, code)
+		assert.JSONEq(`{"errors":[{"message":"Query complexity 31 exceeds the maximum of 30","extensions":{"code":"query_too_complex"}}]}`, body)
+		testObj.AssertExpectations(t)
+	})
+
+	t.Run("Should reject queries which do not validate against the schema", func(t *testing.T) {
+		assert := assert.New(t)
+		testObj := new(MockedOrderUsecase)
+
+		code, body := query(NewGraphqlHandler(testObj, 5, 100), `{"query":"{ orders { id unknown } }"}`)
+		assert.Equal(http.StatusOK, code)
+		assert.JSONEq(`{"errors":[{"message":"Cannot query field \"unknown\" on type \"Order\".","extensions":{"code":"invalid_query"}}]}`, body)
+		testObj.AssertExpectations(t)
+	})
+
+	t.Run("Should reject a page lower than 1", func(t *testing.T) {
+		assert := assert.New(t)
+		testObj := new(MockedOrderUsecase)
+
+		code, body := query(NewGraphqlHandler(testObj, 5, 100), `{"query":"{ orders(page: 0) { id } }"}`)
+		assert.Equal(http.StatusOK, code)
+		assert.JSONEq(`{"data":null,"errors":[{"message":"page should be at least 1","path":["orders"],"extensions":{"code":"invalid_page"}}]}`, body)
+		testObj.AssertExpectations(t)
+	})
+
+	t.Run("Should respond with 400 when request body is not correct", func(t *testing.T) {
+		assert := assert.New(t)
+		testObj := new(MockedOrderUsecase)
+
+		code, _ := query(NewGraphqlHandler(testObj, 5, 100), `{"query":`)
+		assert.Equal(http.StatusBadRequest, code)
+		testObj.AssertExpectations(t)
+	})
+}
@@ -0,0 +1,157 @@
+package graphql
+
+import (
+	"context"
+
+	gql "github.com/graph-gophers/graphql-go"
+	"github.com/karanbhomiagit/order-service/models"
+	"github.com/karanbhomiagit/order-service/order"
+	"github.com/karanbhomiagit/order-service/order/logging"
+)
+
+//resolver resolves the queries and mutations of the schema against the usecase
+type resolver struct {
+	orderUsecase order.Usecase
+}
+
+type orderFilterInput struct {
+	Status      *[]string
+	MinDistance *int32
+	MaxDistance *int32
+}
+
+type locationInput struct {
+	Latitude  string
+	Longitude string
+}
+
+type createOrderInput struct {
+	Origin      locationInput
+	Destination locationInput
+}
+
+func (r *resolver) Order(ctx context.Context, args struct{ ID gql.ID }) (*orderResolver, error) {
+	if err := order.Authorize(ctx, order.ScopeOrdersRead); err != nil {
+		return nil, resolverError(ctx, err)
+	}
+	res, err := r.orderUsecase.FetchByID(ctx, string(args.ID))
+	if err != nil {
+		return nil, resolverError(ctx, err)
+	}
+	return &orderResolver{*res}, nil
+}
+
+func (r *resolver) Orders(ctx context.Context, args struct {
+	Filter *orderFilterInput
+	Page   int32
+	Limit  int32
+}) ([]*orderResolver, error) {
+	if err := order.Authorize(ctx, order.ScopeOrdersRead); err != nil {
+		return nil, resolverError(ctx, err)
+	}
+	//Pages are numbered from 1, a lower one would skip a negative number of orders
+	if args.Page < 1 {
+		return nil, resolverError(ctx, order.NewInvalidArgument("invalid_page", "page should be at least 1"))
+	}
+	filter := &models.OrderFilter{}
+	if args.Filter != nil {
+		if args.Filter.Status != nil {
+			filter.Statuses = *args.Filter.Status
+		}
+		if args.Filter.MinDistance != nil {
+			filter.MinDistance = int(*args.Filter.MinDistance)
+		}
+		if args.Filter.MaxDistance != nil {
+			filter.MaxDistance = int(*args.Filter.MaxDistance)
+		}
+	}
+	res, err := r.orderUsecase.FetchByFilter(ctx, filter, int(args.Page), int(args.Limit))
+	if err != nil {
+		return nil, resolverError(ctx, err)
+	}
+	orders := make([]*orderResolver, 0, len(res))
+	for _, o := range res {
+		orders = append(orders, &orderResolver{o})
+	}
+	return orders, nil
+}
+
+func (r *resolver) CreateOrder(ctx context.Context, args struct{ Input createOrderInput }) (*orderResolver, error) {
+	if err := order.Authorize(ctx, order.ScopeOrdersCreate); err != nil {
+		return nil, resolverError(ctx, err)
+	}
+	res, err := r.orderUsecase.Store(ctx, &models.OrderRequest{
+		Origin:      []string{args.Input.Origin.Latitude, args.Input.Origin.Longitude},
+		Destination: []string{args.Input.Destination.Latitude, args.Input.Destination.Longitude},
+	})
+	if err != nil {
+		return nil, resolverError(ctx, err)
+	}
+	return &orderResolver{*res}, nil
+}
+
+//AssignOrder assigns the order, then reads it back as the usecase only reports success
+func (r *resolver) AssignOrder(ctx context.Context, args struct{ ID gql.ID }) (*orderResolver, error) {
+	if err := order.Authorize(ctx, order.ScopeOrdersAssign); err != nil {
+		return nil, resolverError(ctx, err)
+	}
+	if _, err := r.orderUsecase.AssignByID(ctx, string(args.ID), "TAKEN"); err != nil {
+		return nil, resolverError(ctx, err)
+	}
+	res, err := r.orderUsecase.FetchByID(ctx, string(args.ID))
+	if err != nil {
+		return nil, resolverError(ctx, err)
+	}
+	return &orderResolver{*res}, nil
+}
+
+func (r *resolver) CancelOrder(ctx context.Context, args struct{ ID gql.ID }) (*orderResolver, error) {
+	if err := order.Authorize(ctx, order.ScopeOrdersCancel); err != nil {
+		return nil, resolverError(ctx, err)
+	}
+	res, err := r.orderUsecase.CancelByID(ctx, string(args.ID))
+	if err != nil {
+		return nil, resolverError(ctx, err)
+	}
+	return &orderResolver{*res}, nil
+}
+
+type orderResolver struct {
+	order models.Order
+}
+
+func (o *orderResolver) ID() gql.ID {
+	return gql.ID(o.order.ID.Hex())
+}
+
+func (o *orderResolver) Distance() int32 {
+	return int32(o.order.Distance)
+}
+
+func (o *orderResolver) Status() string {
+	return o.order.Status
+}
+
+//Error is a GraphQL error carrying the stable error code of the http API in its extensions
+type Error struct {
+	Message string
+	Code    string
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func (e *Error) Extensions() map[string]interface{} {
+	return map[string]interface{}{"code": e.Code}
+}
+
+//resolverError maps errors returned by the usecase layer to GraphQL errors
+func resolverError(ctx context.Context, err error) error {
+	if order.KindOf(err) == order.KindInternal {
+		//Unexpected errors may carry internal details which clients should not see
+		logging.FromContext(ctx).Error("Internal error", "error", err)
+		return &Error{Message: "Internal error", Code: "internal"}
+	}
+	return &Error{Message: err.Error(), Code: order.CodeOf(err)}
+}
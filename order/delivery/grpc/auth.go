@@ -0,0 +1,85 @@
+package grpc
+
+import (
+	"context"
+	"strings"
+
+	"github.com/karanbhomiagit/order-service/order"
+	"github.com/karanbhomiagit/order-service/order/delivery/grpc/pb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+//APIKeyKey is the metadata key carrying the API key, the counterpart of the X-API-Key header
+const APIKeyKey = "x-api-key"
+
+//methodScopes maps each method to the scope it requires, the same as its http route
+var methodScopes = map[string]string{
+	pb.OrderService_CreateOrder_FullMethodName: order.ScopeOrdersCreate,
+	pb.OrderService_ListOrders_FullMethodName:  order.ScopeOrdersRead,
+	pb.OrderService_AssignOrder_FullMethodName: order.ScopeOrdersAssign,
+	pb.OrderService_WatchOrders_FullMethodName: order.ScopeOrdersRead,
+}
+
+var errTokensDisabled = order.NewUnauthenticated("invalid_token", "Bearer tokens are not accepted")
+
+//UnaryAuth returns an interceptor authenticating every call with the API key or the bearer token of its
+//metadata, like the http API, and checking the caller was granted the scope of the method. The identity of
+//the caller is attached to the context. The token verifier may be nil when bearer tokens are not accepted.
+func UnaryAuth(aku order.APIKeyUsecase, tv order.TokenVerifier) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ctx, err := authorize(ctx, info.FullMethod, aku, tv)
+		if err != nil {
+			return nil, toStatus(ctx, err)
+		}
+		return handler(ctx, req)
+	}
+}
+
+//StreamAuth is the counterpart of UnaryAuth for streaming calls
+func StreamAuth(aku order.APIKeyUsecase, tv order.TokenVerifier) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := authorize(ss.Context(), info.FullMethod, aku, tv)
+		if err != nil {
+			return toStatus(ctx, err)
+		}
+		return handler(srv, &contextStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+//authorize returns a copy of ctx carrying the identity of the caller, or an error unless the caller was
+//granted the scope of the method. Unlike over http no method is public, so calls without credentials fail.
+func authorize(ctx context.Context, method string, aku order.APIKeyUsecase, tv order.TokenVerifier) (context.Context, error) {
+	identity, err := authenticate(ctx, aku, tv)
+	if err != nil {
+		return ctx, err
+	}
+	if identity != nil {
+		ctx = order.NewContext(ctx, identity)
+	}
+	scope, ok := methodScopes[method]
+	if !ok {
+		return ctx, order.NewPermissionDenied("forbidden", "Method "+method+" is not allowed")
+	}
+	return ctx, order.Authorize(ctx, scope)
+}
+
+//authenticate returns the identity of the credentials of the call, or nil when there are none
+func authenticate(ctx context.Context, aku order.APIKeyUsecase, tv order.TokenVerifier) (*order.Identity, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	if keys := md.Get(APIKeyKey); len(keys) > 0 && keys[0] != "" {
+		return aku.Authenticate(keys[0])
+	}
+	authorization := md.Get("authorization")
+	if len(authorization) == 0 {
+		return nil, nil
+	}
+	scheme, token, ok := strings.Cut(authorization[0], " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return nil, nil
+	}
+	if tv == nil {
+		return nil, errTokensDisabled
+	}
+	return tv.Verify(strings.TrimSpace(token))
+}
@@ -0,0 +1,77 @@
+package grpc
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/karanbhomiagit/order-service/order/logging"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+//RequestIDKey is the metadata key carrying the request id, the counterpart of the X-Request-ID header
+const RequestIDKey = "x-request-id"
+
+//UnaryLogging returns an interceptor giving every call an id, taken from the x-request-id metadata when
+//the client set one and sent back in the header, and writing a line when the call is served
+func UnaryLogging(logger *slog.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ctx = withRequest(ctx, logger, info.FullMethod)
+		grpc.SetHeader(ctx, metadata.Pairs(RequestIDKey, requestID(ctx)))
+		res, err := handler(ctx, req)
+		logServed(ctx, err)
+		return res, err
+	}
+}
+
+//StreamLogging is the counterpart of UnaryLogging for streaming calls
+func StreamLogging(logger *slog.Logger) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx := withRequest(ss.Context(), logger, info.FullMethod)
+		ss.SetHeader(metadata.Pairs(RequestIDKey, requestID(ctx)))
+		err := handler(srv, &contextStream{ServerStream: ss, ctx: ctx})
+		logServed(ctx, err)
+		return err
+	}
+}
+
+//withRequest returns a copy of ctx carrying the logger and the request of the call
+func withRequest(ctx context.Context, logger *slog.Logger, method string) context.Context {
+	id := ""
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if ids := md.Get(RequestIDKey); len(ids) > 0 && len(ids[0]) <= 128 {
+			id = ids[0]
+		}
+	}
+	if id == "" {
+		id = logging.NewRequestID()
+	}
+	return logging.NewContext(ctx, logger, &logging.Request{ID: id, Route: method, Start: time.Now()})
+}
+
+func requestID(ctx context.Context) string {
+	req, _ := logging.RequestFrom(ctx)
+	return req.ID
+}
+
+func logServed(ctx context.Context, err error) {
+	code := status.Code(err)
+	level := slog.LevelInfo
+	if code == codes.Internal || code == codes.Unavailable || code == codes.Unknown {
+		level = slog.LevelError
+	}
+	logging.FromContext(ctx).Log(ctx, level, "Call served", "code", code.String())
+}
+
+//contextStream replaces the context of the stream with one carrying the request or the caller
+type contextStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *contextStream) Context() context.Context {
+	return s.ctx
+}
@@ -0,0 +1,31 @@
+package grpc
+
+import (
+	"context"
+	"time"
+
+	"github.com/karanbhomiagit/order-service/order/metrics"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+)
+
+//UnaryInstrument returns an interceptor recording the count and latency of the calls by method and status
+//code. It should be the outermost interceptor of the server, so rejected calls are recorded too.
+func UnaryInstrument() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		start := time.Now()
+		res, err := handler(ctx, req)
+		metrics.ObserveGRPCCall(info.FullMethod, status.Code(err).String(), time.Since(start))
+		return res, err
+	}
+}
+
+//StreamInstrument is the counterpart of UnaryInstrument for streaming calls, timed until the stream ends
+func StreamInstrument() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		start := time.Now()
+		err := handler(srv, ss)
+		metrics.ObserveGRPCCall(info.FullMethod, status.Code(err).String(), time.Since(start))
+		return err
+	}
+}
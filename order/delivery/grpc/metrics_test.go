@@ -0,0 +1,53 @@
+package grpc
+
+import (
+	"context"
+	"testing"
+
+	"github.com/karanbhomiagit/order-service/models"
+	"github.com/karanbhomiagit/order-service/order/delivery/grpc/pb"
+	"github.com/karanbhomiagit/order-service/order/metrics"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+)
+
+/*
+	Actual test functions
+*/
+
+func TestInstrument(t *testing.T) {
+	dial := func(t *testing.T, testObj *MockedOrderUsecase, key string) pb.OrderServiceClient {
+		server := grpc.NewServer(
+			grpc.ChainUnaryInterceptor(UnaryInstrument(), UnaryAuth(keys, nil)),
+			grpc.ChainStreamInterceptor(StreamInstrument(), StreamAuth(keys, nil)),
+		)
+		return dialServer(t, server, testObj, nil, key)
+	}
+
+	t.Run("Should count calls by method and status code", func(t *testing.T) {
+		assert := assert.New(t)
+		testObj := new(MockedOrderUsecase)
+		testObj.On("FetchByRange", 1, 10).Return([]models.Order{}, nil)
+		client := dial(t, testObj, "reader-key")
+		counter := metrics.GRPCCalls.WithLabelValues("/order.v1.OrderService/ListOrders", "OK")
+		before := testutil.ToFloat64(counter)
+
+		_, err := client.ListOrders(context.Background(), &pb.ListOrdersRequest{})
+		assert.NoError(err)
+		_, err = client.ListOrders(context.Background(), &pb.ListOrdersRequest{})
+		assert.NoError(err)
+		assert.Equal(before+2, testutil.ToFloat64(counter))
+	})
+
+	t.Run("Should count the calls rejected by later interceptors", func(t *testing.T) {
+		assert := assert.New(t)
+		client := dial(t, new(MockedOrderUsecase), "")
+		counter := metrics.GRPCCalls.WithLabelValues("/order.v1.OrderService/ListOrders", "Unauthenticated")
+		before := testutil.ToFloat64(counter)
+
+		_, err := client.ListOrders(context.Background(), &pb.ListOrdersRequest{})
+		assert.Error(err)
+		assert.Equal(before+1, testutil.ToFloat64(counter))
+	})
+}
@@ -0,0 +1,172 @@
+package grpc
+
+//go:generate protoc -I pb --go_out=pb --go_opt=paths=source_relative --go-grpc_out=pb --go-grpc_opt=paths=source_relative pb/order.proto
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/karanbhomiagit/order-service/models"
+	"github.com/karanbhomiagit/order-service/order"
+	"github.com/karanbhomiagit/order-service/order/delivery/grpc/pb"
+	"github.com/karanbhomiagit/order-service/order/logging"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+//ErrorDomain is the domain of the ErrorInfo details attached to errors
+const ErrorDomain = "order-service"
+
+type OrderGrpcServer struct {
+	pb.UnimplementedOrderServiceServer
+	orderUsecase order.Usecase
+	orderFeed    order.Feed
+}
+
+func NewOrderGrpcServer(server *grpc.Server, ou order.Usecase, of order.Feed) {
+	pb.RegisterOrderServiceServer(server, &OrderGrpcServer{
+		orderUsecase: ou,
+		orderFeed:    of,
+	})
+}
+
+//CreateOrder stores an order through the usecase layer
+func (s *OrderGrpcServer) CreateOrder(ctx context.Context, req *pb.CreateOrderRequest) (*pb.Order, error) {
+	if req.GetOrigin() == nil || req.GetDestination() == nil {
+		return nil, toStatus(ctx, order.NewInvalidArgument("invalid_payload", "origin and destination are required"))
+	}
+	res, err := s.orderUsecase.Store(ctx, &models.OrderRequest{
+		Origin:      []string{req.Origin.Latitude, req.Origin.Longitude},
+		Destination: []string{req.Destination.Latitude, req.Destination.Longitude},
+	})
+	if err != nil {
+		return nil, toStatus(ctx, err)
+	}
+	return toOrder(*res), nil
+}
+
+//ListOrders returns a page of orders, using the same defaults as GET /orders
+func (s *OrderGrpcServer) ListOrders(ctx context.Context, req *pb.ListOrdersRequest) (*pb.ListOrdersResponse, error) {
+	page := int(req.GetPage())
+	if page == 0 {
+		page = 1
+	}
+	limit := int(req.GetLimit())
+	if limit == 0 {
+		limit = 10
+	}
+	res, err := s.orderUsecase.FetchByRange(ctx, page, limit)
+	if err != nil {
+		return nil, toStatus(ctx, err)
+	}
+	orders := make([]*pb.Order, 0, len(res))
+	for _, o := range res {
+		orders = append(orders, toOrder(o))
+	}
+	return &pb.ListOrdersResponse{Orders: orders}, nil
+}
+
+//AssignOrder assigns an order through the usecase layer
+func (s *OrderGrpcServer) AssignOrder(ctx context.Context, req *pb.AssignOrderRequest) (*pb.AssignOrderResponse, error) {
+	res, err := s.orderUsecase.AssignByID(ctx, req.GetId(), req.GetStatus())
+	if err != nil {
+		return nil, toStatus(ctx, err)
+	}
+	return &pb.AssignOrderResponse{Status: (*res)["status"]}, nil
+}
+
+//WatchOrders streams the events of the feed, replaying those missed after the last event id,
+//until the client goes away
+func (s *OrderGrpcServer) WatchOrders(req *pb.WatchOrdersRequest, stream grpc.ServerStreamingServer[pb.OrderEvent]) error {
+	statuses := make(map[string]bool)
+	for _, st := range req.GetStatuses() {
+		statuses[strings.ToUpper(st)] = true
+	}
+	send := func(event models.OrderEvent) error {
+		if len(statuses) > 0 && !statuses[event.Data.Status] {
+			return nil
+		}
+		return stream.Send(toOrderEvent(event))
+	}
+
+	replay, events, unsubscribe := s.orderFeed.Subscribe(req.GetLastEventId())
+	defer unsubscribe()
+	for _, event := range replay {
+		if err := send(event); err != nil {
+			return err
+		}
+	}
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case event, ok := <-events:
+			if !ok {
+				//The feed dropped this subscriber for falling behind, the client resumes from its last event
+				return status.Error(codes.Unavailable, "Subscriber fell behind, resume from the last event")
+			}
+			if err := send(event); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+//statusCodes maps each kind of domain error to its gRPC status code
+var statusCodes = map[order.Kind]codes.Code{
+	order.KindNotFound:         codes.NotFound,
+	order.KindInvalidArgument:  codes.InvalidArgument,
+	order.KindConflict:         codes.FailedPrecondition,
+	order.KindUnavailable:      codes.Unavailable,
+	order.KindUnauthenticated:  codes.Unauthenticated,
+	order.KindPermissionDenied: codes.PermissionDenied,
+}
+
+//toStatus maps errors returned by the usecase layer to gRPC statuses carrying their error code
+func toStatus(ctx context.Context, err error) error {
+	code, ok := statusCodes[order.KindOf(err)]
+	if !ok {
+		//Unexpected errors may carry internal details which clients should not see
+		logging.FromContext(ctx).Error("Internal error", "error", err)
+		return status.Error(codes.Internal, "Internal error")
+	}
+	//Calls abandoned by their client or out of time are not failures of the service
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		code = status.FromContextError(err).Code()
+	}
+	st, detailsErr := status.New(code, err.Error()).WithDetails(&errdetails.ErrorInfo{
+		Reason: order.CodeOf(err),
+		Domain: ErrorDomain,
+	})
+	if detailsErr != nil {
+		return status.Error(code, err.Error())
+	}
+	return st.Err()
+}
+
+func toOrder(o models.Order) *pb.Order {
+	return &pb.Order{
+		Id:       o.ID.Hex(),
+		Distance: int64(o.Distance),
+		Status:   o.Status,
+	}
+}
+
+func toOrderEvent(event models.OrderEvent) *pb.OrderEvent {
+	return &pb.OrderEvent{
+		Id:         event.ID,
+		Type:       event.Type,
+		Version:    int32(event.Version),
+		OccurredAt: timestamppb.New(event.OccurredAt),
+		Data: &pb.OrderEventData{
+			OrderId:        event.Data.OrderID,
+			Status:         event.Data.Status,
+			PreviousStatus: event.Data.PreviousStatus,
+			Distance:       int64(event.Data.Distance),
+		},
+	}
+}
@@ -0,0 +1,445 @@
+package grpc
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/karanbhomiagit/order-service/models"
+	"github.com/karanbhomiagit/order-service/order"
+	"github.com/karanbhomiagit/order-service/order/delivery/grpc/pb"
+	"github.com/karanbhomiagit/order-service/order/usecase"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"gopkg.in/mgo.v2/bson"
+)
+
+type MockedOrderUsecase struct {
+	mock.Mock
+}
+
+func (ou *MockedOrderUsecase) AssignByID(ctx context.Context, id string, status string) (*map[string]string, error) {
+	args := ou.Called(id, status)
+	return args.Get(0).(*map[string]string), args.Error(1)
+}
+
+func (ou *MockedOrderUsecase) FetchByRange(ctx context.Context, page int, limit int) ([]models.Order, error) {
+	args := ou.Called(page, limit)
+	return args.Get(0).([]models.Order), args.Error(1)
+}
+
+func (ou *MockedOrderUsecase) CancelByID(ctx context.Context, id string) (*models.Order, error) {
+	args := ou.Called(id)
+	return args.Get(0).(*models.Order), args.Error(1)
+}
+
+func (ou *MockedOrderUsecase) FetchByID(ctx context.Context, id string) (*models.Order, error) {
+	args := ou.Called(id)
+	return args.Get(0).(*models.Order), args.Error(1)
+}
+
+func (ou *MockedOrderUsecase) FetchByFilter(ctx context.Context, filter *models.OrderFilter, page int, limit int) ([]models.Order, error) {
+	args := ou.Called(filter, page, limit)
+	return args.Get(0).([]models.Order), args.Error(1)
+}
+
+func (ou *MockedOrderUsecase) FetchByFilterAfter(ctx context.Context, filter *models.OrderFilter, after string, limit int) ([]models.Order, error) {
+	args := ou.Called(filter, after, limit)
+	return args.Get(0).([]models.Order), args.Error(1)
+}
+
+func (ou *MockedOrderUsecase) Store(ctx context.Context, orderReq *models.OrderRequest) (*models.Order, error) {
+	args := ou.Called(orderReq)
+	return args.Get(0).(*models.Order), args.Error(1)
+}
+
+func (ou *MockedOrderUsecase) TransitionByID(ctx context.Context, id string, status string) (*models.Order, error) {
+	args := ou.Called(id, status)
+	return args.Get(0).(*models.Order), args.Error(1)
+}
+
+//testKeys authenticates the API keys of the tests
+type testKeys map[string]*order.Identity
+
+func (k testKeys) Authenticate(key string) (*order.Identity, error) {
+	identity, ok := k[key]
+	if !ok {
+		return nil, order.NewUnauthenticated("invalid_api_key", "Invalid API key")
+	}
+	return identity, nil
+}
+
+func (k testKeys) FetchAll() ([]models.APIKey, error) {
+	return nil, nil
+}
+
+func (k testKeys) Issue(*models.APIKeyRequest) (*models.APIKey, error) {
+	return nil, nil
+}
+
+func (k testKeys) RevokeByID(string) error {
+	return nil
+}
+
+var keys = testKeys{
+	"admin-key":  {Subject: order.AdminSubject, Scopes: order.Scopes},
+	"reader-key": {Subject: "reader", Scopes: []string{order.ScopeOrdersRead}},
+}
+
+//identityRecordingUsecase records the identity orders are listed with
+type identityRecordingUsecase struct {
+	MockedOrderUsecase
+	identity *order.Identity
+}
+
+func (ou *identityRecordingUsecase) FetchByRange(ctx context.Context, page int, limit int) ([]models.Order, error) {
+	ou.identity, _ = order.IdentityFrom(ctx)
+	return ou.MockedOrderUsecase.FetchByRange(ctx, page, limit)
+}
+
+//dial serves the usecase and feed over an in-process listener and returns a client connected to it with the admin key
+func dial(t *testing.T, ou order.Usecase, of order.Feed) pb.OrderServiceClient {
+	return dialWithKey(t, ou, of, "admin-key")
+}
+
+//dialWithKey is dial with a client sending the API key, none when it is empty
+func dialWithKey(t *testing.T, ou order.Usecase, of order.Feed, key string) pb.OrderServiceClient {
+	logger := slog.New(slog.DiscardHandler)
+	server := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(UnaryLogging(logger), UnaryAuth(keys, nil)),
+		grpc.ChainStreamInterceptor(StreamLogging(logger), StreamAuth(keys, nil)),
+	)
+	return dialServer(t, server, ou, of, key)
+}
+
+//dialServer serves the usecase and feed with the server over an in-process listener and returns a client
+//connected to it, sending the API key unless it is empty
+func dialServer(t *testing.T, server *grpc.Server, ou order.Usecase, of order.Feed, key string) pb.OrderServiceClient {
+	lis := bufconn.Listen(1024 * 1024)
+	NewOrderGrpcServer(server, ou, of)
+	go server.Serve(lis)
+	t.Cleanup(server.Stop)
+
+	withKey := func(ctx context.Context) context.Context {
+		if key == "" {
+			return ctx
+		}
+		return metadata.AppendToOutgoingContext(ctx, APIKeyKey, key)
+	}
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithUnaryInterceptor(func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+			return invoker(withKey(ctx), method, req, reply, cc, opts...)
+		}),
+		grpc.WithStreamInterceptor(func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+			return streamer(withKey(ctx), desc, cc, method, opts...)
+		}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return pb.NewOrderServiceClient(conn)
+}
+
+//reasonOf returns the error code carried by the ErrorInfo detail of a gRPC error
+func reasonOf(err error) string {
+	for _, detail := range status.Convert(err).Details() {
+		if info, ok := detail.(*errdetails.ErrorInfo); ok {
+			return info.Reason
+		}
+	}
+	return ""
+}
+
+func newEvent(status string) models.OrderEvent {
+	return models.OrderEvent{
+		ID:         bson.NewObjectId().Hex(),
+		Type:       models.EventOrderStatusChanged,
+		Version:    models.EventSchemaVersion,
+		OccurredAt: time.Now().UTC(),
+		Data:       models.OrderEventData{OrderID: bson.NewObjectId().Hex(), Status: status, Distance: 100},
+	}
+}
+
+/*
+	Actual test functions
+*/
+
+func TestCreateOrder(t *testing.T) {
+
+	t.Run("Should create the order through the usecase layer", func(t *testing.T) {
+		assert := assert.New(t)
+		testObj := new(MockedOrderUsecase)
+		id := bson.NewObjectId()
+		testObj.On("Store", &models.OrderRequest{Origin: []string{"22.3193", "114.1694"}, Destination: []string{"22.2783", "114.1747"}}).Return(&models.Order{ID: id, Distance: 5000, Status: "UNASSIGNED"}, nil)
+		client := dial(t, testObj, nil)
+
+		res, err := client.CreateOrder(context.Background(), &pb.CreateOrderRequest{
+			Origin:      &pb.Location{Latitude: "22.3193", Longitude: "114.1694"},
+			Destination: &pb.Location{Latitude: "22.2783", Longitude: "114.1747"},
+		})
+		assert.NoError(err)
+		assert.Equal(id.Hex(), res.Id)
+		assert.Equal(int64(5000), res.Distance)
+		assert.Equal("UNASSIGNED", res.Status)
+		testObj.AssertExpectations(t)
+	})
+
+	t.Run("Should return InvalidArgument without locations", func(t *testing.T) {
+		assert := assert.New(t)
+		testObj := new(MockedOrderUsecase)
+		client := dial(t, testObj, nil)
+
+		_, err := client.CreateOrder(context.Background(), &pb.CreateOrderRequest{})
+		assert.Equal(codes.InvalidArgument, status.Code(err))
+		assert.Equal("invalid_payload", reasonOf(err))
+		testObj.AssertExpectations(t)
+	})
+
+	t.Run("Should return Unavailable with the error code if the distance provider fails", func(t *testing.T) {
+		assert := assert.New(t)
+		testObj := new(MockedOrderUsecase)
+		testObj.On("Store", mock.Anything).Return((*models.Order)(nil), order.NewUnavailable("distance_unavailable", "Unable to fetch distance from Google APIs", io.EOF))
+		client := dial(t, testObj, nil)
+
+		_, err := client.CreateOrder(context.Background(), &pb.CreateOrderRequest{
+			Origin:      &pb.Location{Latitude: "22.3193", Longitude: "114.1694"},
+			Destination: &pb.Location{Latitude: "22.2783", Longitude: "114.1747"},
+		})
+		assert.Equal(codes.Unavailable, status.Code(err))
+		assert.Equal("Unable to fetch distance from Google APIs", status.Convert(err).Message())
+		assert.Equal("distance_unavailable", reasonOf(err))
+		testObj.AssertExpectations(t)
+	})
+
+	t.Run("Should return DeadlineExceeded if the call runs out of time", func(t *testing.T) {
+		assert := assert.New(t)
+		testObj := new(MockedOrderUsecase)
+		testObj.On("Store", mock.Anything).Return((*models.Order)(nil), order.NewCanceled(context.DeadlineExceeded))
+		client := dial(t, testObj, nil)
+
+		_, err := client.CreateOrder(context.Background(), &pb.CreateOrderRequest{
+			Origin:      &pb.Location{Latitude: "22.3193", Longitude: "114.1694"},
+			Destination: &pb.Location{Latitude: "22.2783", Longitude: "114.1747"},
+		})
+		assert.Equal(codes.DeadlineExceeded, status.Code(err))
+		assert.Equal("deadline_exceeded", reasonOf(err))
+		testObj.AssertExpectations(t)
+	})
+}
+
+func TestListOrders(t *testing.T) {
+
+	t.Run("Should default to the first page of ten orders", func(t *testing.T) {
+		assert := assert.New(t)
+		testObj := new(MockedOrderUsecase)
+		testObj.On("FetchByRange", 1, 10).Return([]models.Order{{ID: bson.NewObjectId(), Distance: 100, Status: "TAKEN"}}, nil)
+		client := dial(t, testObj, nil)
+
+		res, err := client.ListOrders(context.Background(), &pb.ListOrdersRequest{})
+		assert.NoError(err)
+		assert.Len(res.Orders, 1)
+		assert.Equal("TAKEN", res.Orders[0].Status)
+		testObj.AssertExpectations(t)
+	})
+
+	t.Run("Should hide the details of unexpected errors", func(t *testing.T) {
+		assert := assert.New(t)
+		testObj := new(MockedOrderUsecase)
+		testObj.On("FetchByRange", 2, 5).Return([]models.Order(nil), io.ErrUnexpectedEOF)
+		client := dial(t, testObj, nil)
+
+		_, err := client.ListOrders(context.Background(), &pb.ListOrdersRequest{Page: 2, Limit: 5})
+		assert.Equal(codes.Internal, status.Code(err))
+		assert.Equal("Internal error", status.Convert(err).Message())
+		testObj.AssertExpectations(t)
+	})
+}
+
+func TestLogging(t *testing.T) {
+
+	t.Run("Should send back the request id of the call", func(t *testing.T) {
+		assert := assert.New(t)
+		testObj := new(MockedOrderUsecase)
+		testObj.On("FetchByRange", 1, 10).Return([]models.Order{}, nil)
+		client := dial(t, testObj, nil)
+
+		var header metadata.MD
+		ctx := metadata.AppendToOutgoingContext(context.Background(), RequestIDKey, "req-1234")
+		_, err := client.ListOrders(ctx, &pb.ListOrdersRequest{}, grpc.Header(&header))
+		assert.NoError(err)
+		assert.Equal([]string{"req-1234"}, header.Get(RequestIDKey))
+		testObj.AssertExpectations(t)
+	})
+
+	t.Run("Should give an id to calls without one", func(t *testing.T) {
+		assert := assert.New(t)
+		testObj := new(MockedOrderUsecase)
+		testObj.On("FetchByRange", 1, 10).Return([]models.Order{}, nil)
+		client := dial(t, testObj, nil)
+
+		var header metadata.MD
+		_, err := client.ListOrders(context.Background(), &pb.ListOrdersRequest{}, grpc.Header(&header))
+		assert.NoError(err)
+		if assert.Len(header.Get(RequestIDKey), 1) {
+			assert.Len(header.Get(RequestIDKey)[0], 32)
+		}
+		testObj.AssertExpectations(t)
+	})
+}
+
+func TestAuth(t *testing.T) {
+
+	t.Run("Should reject calls without credentials", func(t *testing.T) {
+		assert := assert.New(t)
+		testObj := new(MockedOrderUsecase)
+		client := dialWithKey(t, testObj, nil, "")
+
+		_, err := client.ListOrders(context.Background(), &pb.ListOrdersRequest{})
+		assert.Equal(codes.Unauthenticated, status.Code(err))
+		assert.Equal("unauthenticated", reasonOf(err))
+		testObj.AssertNotCalled(t, "FetchByRange", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Should reject calls with an invalid API key", func(t *testing.T) {
+		assert := assert.New(t)
+		client := dialWithKey(t, new(MockedOrderUsecase), nil, "stolen-key")
+
+		_, err := client.ListOrders(context.Background(), &pb.ListOrdersRequest{})
+		assert.Equal(codes.Unauthenticated, status.Code(err))
+		assert.Equal("invalid_api_key", reasonOf(err))
+	})
+
+	t.Run("Should reject calls missing the scope of the method", func(t *testing.T) {
+		assert := assert.New(t)
+		testObj := new(MockedOrderUsecase)
+		client := dialWithKey(t, testObj, nil, "reader-key")
+
+		_, err := client.CreateOrder(context.Background(), &pb.CreateOrderRequest{
+			Origin:      &pb.Location{Latitude: "22.3193", Longitude: "114.1694"},
+			Destination: &pb.Location{Latitude: "22.2783", Longitude: "114.1747"},
+		})
+		assert.Equal(codes.PermissionDenied, status.Code(err))
+		assert.Equal("forbidden", reasonOf(err))
+		testObj.AssertNotCalled(t, "Store", mock.Anything)
+	})
+
+	t.Run("Should reject streams without credentials", func(t *testing.T) {
+		assert := assert.New(t)
+		client := dialWithKey(t, new(MockedOrderUsecase), usecase.NewOrderFeed(10), "")
+
+		stream, err := client.WatchOrders(context.Background(), &pb.WatchOrdersRequest{})
+		assert.NoError(err)
+		_, err = stream.Recv()
+		assert.Equal(codes.Unauthenticated, status.Code(err))
+	})
+
+	t.Run("Should call the usecase with the identity of the caller", func(t *testing.T) {
+		assert := assert.New(t)
+		testObj := new(identityRecordingUsecase)
+		testObj.On("FetchByRange", 1, 10).Return([]models.Order{}, nil)
+		client := dialWithKey(t, testObj, nil, "reader-key")
+
+		_, err := client.ListOrders(context.Background(), &pb.ListOrdersRequest{})
+		assert.NoError(err)
+		if assert.NotNil(testObj.identity) {
+			assert.Equal("reader", testObj.identity.Subject)
+		}
+		testObj.AssertExpectations(t)
+	})
+}
+
+func TestAssignOrder(t *testing.T) {
+
+	t.Run("Should assign the order through the usecase layer", func(t *testing.T) {
+		assert := assert.New(t)
+		testObj := new(MockedOrderUsecase)
+		testObj.On("AssignByID", "1234", "TAKEN").Return(&map[string]string{"status": "SUCCESS"}, nil)
+		client := dial(t, testObj, nil)
+
+		res, err := client.AssignOrder(context.Background(), &pb.AssignOrderRequest{Id: "1234", Status: "TAKEN"})
+		assert.NoError(err)
+		assert.Equal("SUCCESS", res.Status)
+		testObj.AssertExpectations(t)
+	})
+
+	t.Run("Should return FailedPrecondition if the order is already assigned", func(t *testing.T) {
+		assert := assert.New(t)
+		testObj := new(MockedOrderUsecase)
+		testObj.On("AssignByID", "1234", "TAKEN").Return((*map[string]string)(nil), order.NewConflict("order_already_assigned", "Order is already assigned"))
+		client := dial(t, testObj, nil)
+
+		_, err := client.AssignOrder(context.Background(), &pb.AssignOrderRequest{Id: "1234", Status: "TAKEN"})
+		assert.Equal(codes.FailedPrecondition, status.Code(err))
+		assert.Equal("order_already_assigned", reasonOf(err))
+		testObj.AssertExpectations(t)
+	})
+
+	t.Run("Should return NotFound if the order does not exist", func(t *testing.T) {
+		assert := assert.New(t)
+		testObj := new(MockedOrderUsecase)
+		testObj.On("AssignByID", "1234", "TAKEN").Return((*map[string]string)(nil), order.NewNotFound("order_not_found", "Invalid Id"))
+		client := dial(t, testObj, nil)
+
+		_, err := client.AssignOrder(context.Background(), &pb.AssignOrderRequest{Id: "1234", Status: "TAKEN"})
+		assert.Equal(codes.NotFound, status.Code(err))
+		testObj.AssertExpectations(t)
+	})
+}
+
+func TestWatchOrders(t *testing.T) {
+
+	t.Run("Should stream events as they are appended", func(t *testing.T) {
+		assert := assert.New(t)
+		feed := usecase.NewOrderFeed(10)
+		seen := newEvent("UNASSIGNED")
+		feed.Append(seen)
+		client := dial(t, new(MockedOrderUsecase), feed)
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		stream, err := client.WatchOrders(ctx, &pb.WatchOrdersRequest{LastEventId: seen.ID})
+		assert.NoError(err)
+		//Resuming after a known event, the next one arrives whether it is appended before or after the subscription
+		event := newEvent("TAKEN")
+		feed.Append(event)
+
+		res, err := stream.Recv()
+		assert.NoError(err)
+		assert.Equal(event.ID, res.Id)
+		assert.Equal(event.Data.OrderID, res.Data.OrderId)
+		assert.Equal("TAKEN", res.Data.Status)
+		assert.True(event.OccurredAt.Equal(res.OccurredAt.AsTime()))
+	})
+
+	t.Run("Should resume after the last event id and apply status filters", func(t *testing.T) {
+		assert := assert.New(t)
+		feed := usecase.NewOrderFeed(10)
+		first, taken, expired := newEvent("TAKEN"), newEvent("TAKEN"), newEvent("EXPIRED")
+		feed.Append(first)
+		feed.Append(taken)
+		feed.Append(expired)
+		client := dial(t, new(MockedOrderUsecase), feed)
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		stream, err := client.WatchOrders(ctx, &pb.WatchOrdersRequest{LastEventId: first.ID, Statuses: []string{"expired"}})
+		assert.NoError(err)
+		res, err := stream.Recv()
+		assert.NoError(err)
+		assert.Equal(expired.ID, res.Id)
+	})
+}
@@ -0,0 +1,163 @@
+package grpc
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/karanbhomiagit/order-service/models"
+	"github.com/karanbhomiagit/order-service/order"
+	"github.com/karanbhomiagit/order-service/order/logging"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+//UnaryRateLimit returns an interceptor limiting the calls of each client with token buckets kept in the store,
+//to be used after UnaryAuth. Clients are the authenticated callers, which share the bucket of the fallback limit
+//of the http API when the store is the same. Calls are let through when the store fails.
+func UnaryRateLimit(store order.RateLimitStore, limit models.RateLimit) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if err := takeToken(ctx, store, limit); err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+//StreamRateLimit is the counterpart of UnaryRateLimit for streaming calls, which take a token when they start
+func StreamRateLimit(store order.RateLimitStore, limit models.RateLimit) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if err := takeToken(ss.Context(), store, limit); err != nil {
+			return err
+		}
+		return handler(srv, ss)
+	}
+}
+
+//takeToken takes a token from the bucket of the caller, returning ResourceExhausted once it is empty
+func takeToken(ctx context.Context, store order.RateLimitStore, limit models.RateLimit) error {
+	if limit.Requests <= 0 || limit.Period <= 0 {
+		return nil
+	}
+	//The same key as the fallback limit of the http API
+	bucket, err := store.Take(clientKey(ctx)+" *", limit, time.Now())
+	if err != nil {
+		logging.FromContext(ctx).Error("Unable to take a token, letting the call through", "error", err)
+		return nil
+	}
+	if !bucket.Allowed {
+		return rateLimited(fmt.Sprintf("Too many requests, retry in %d seconds", seconds(bucket.RetryAfter)), bucket.RetryAfter)
+	}
+	return nil
+}
+
+//UnaryLimitFailedAuth returns an interceptor limiting the failed authentications of each IP address with token
+//buckets kept in the store, to be used before UnaryAuth. Every call carrying credentials which fails with
+//Unauthenticated takes a token; once the bucket of the address is empty its calls carrying credentials fail with
+//ResourceExhausted without being authenticated. The buckets are those of the http API when the store is the same.
+//Calls are let through when the store fails.
+func UnaryLimitFailedAuth(store order.RateLimitStore, limit models.RateLimit) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		var res interface{}
+		err := limitFailedAuth(ctx, store, limit, func() error {
+			var err error
+			res, err = handler(ctx, req)
+			return err
+		})
+		return res, err
+	}
+}
+
+//StreamLimitFailedAuth is the counterpart of UnaryLimitFailedAuth for streaming calls
+func StreamLimitFailedAuth(store order.RateLimitStore, limit models.RateLimit) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		return limitFailedAuth(ss.Context(), store, limit, func() error {
+			return handler(srv, ss)
+		})
+	}
+}
+
+//limitFailedAuth makes the call unless the address of the caller failed to authenticate too often, and records
+//the failed authentication of the call
+func limitFailedAuth(ctx context.Context, store order.RateLimitStore, limit models.RateLimit, call func() error) error {
+	if limit.Requests <= 0 || limit.Period <= 0 || !hasCredentials(ctx) {
+		return call()
+	}
+	key := "ip:" + clientIP(ctx) + " auth"
+	bucket, err := store.Check(key, limit, time.Now())
+	if err != nil {
+		logging.FromContext(ctx).Error("Unable to check the failed authentications, letting the call through", "error", err)
+		return call()
+	}
+	if !bucket.Allowed {
+		return rateLimited(fmt.Sprintf("Too many failed authentications, retry in %d seconds", seconds(bucket.RetryAfter)), bucket.RetryAfter)
+	}
+	err = call()
+	if status.Code(err) != codes.Unauthenticated {
+		return err
+	}
+	if _, err := store.Take(key, limit, time.Now()); err != nil {
+		logging.FromContext(ctx).Error("Unable to record the failed authentication", "error", err)
+	}
+	return err
+}
+
+//rateLimited returns a ResourceExhausted status telling the client when to retry
+func rateLimited(message string, retryAfter time.Duration) error {
+	st, err := status.New(codes.ResourceExhausted, message).WithDetails(
+		&errdetails.ErrorInfo{Reason: "rate_limited", Domain: ErrorDomain},
+		&errdetails.RetryInfo{RetryDelay: durationpb.New(retryAfter)},
+	)
+	if err != nil {
+		return status.Error(codes.ResourceExhausted, message)
+	}
+	return st.Err()
+}
+
+//hasCredentials reports whether the call carries an API key or a bearer token
+func hasCredentials(ctx context.Context) bool {
+	md, _ := metadata.FromIncomingContext(ctx)
+	if keys := md.Get(APIKeyKey); len(keys) > 0 && keys[0] != "" {
+		return true
+	}
+	authorization := md.Get("authorization")
+	if len(authorization) == 0 {
+		return false
+	}
+	scheme, _, ok := strings.Cut(authorization[0], " ")
+	return ok && strings.EqualFold(scheme, "Bearer")
+}
+
+//clientKey identifies the client making the call, the same way as over http
+func clientKey(ctx context.Context) string {
+	if identity, ok := order.IdentityFrom(ctx); ok {
+		return "subject:" + identity.Subject
+	}
+	return "ip:" + clientIP(ctx)
+}
+
+//clientIP returns the IP address the call comes from
+func clientIP(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return ""
+	}
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		return p.Addr.String()
+	}
+	return host
+}
+
+//seconds rounds the duration up to whole seconds
+func seconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
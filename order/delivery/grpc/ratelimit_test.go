@@ -0,0 +1,159 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/karanbhomiagit/order-service/models"
+	"github.com/karanbhomiagit/order-service/order"
+	"github.com/karanbhomiagit/order-service/order/delivery/grpc/pb"
+	"github.com/karanbhomiagit/order-service/order/repository"
+	"github.com/karanbhomiagit/order-service/order/usecase"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type MockedRateLimitStore struct {
+	mock.Mock
+}
+
+func (s *MockedRateLimitStore) Take(key string, limit models.RateLimit, now time.Time) (*models.RateLimitStatus, error) {
+	args := s.Called(key, limit)
+	return args.Get(0).(*models.RateLimitStatus), args.Error(1)
+}
+
+func (s *MockedRateLimitStore) Check(key string, limit models.RateLimit, now time.Time) (*models.RateLimitStatus, error) {
+	args := s.Called(key, limit)
+	return args.Get(0).(*models.RateLimitStatus), args.Error(1)
+}
+
+//dialLimited is dialWithKey with the calls limited by the store, limit failing authentications and calls alike
+func dialLimited(t *testing.T, ou order.Usecase, of order.Feed, key string, store order.RateLimitStore, limit models.RateLimit) pb.OrderServiceClient {
+	server := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(UnaryLimitFailedAuth(store, limit), UnaryAuth(keys, nil), UnaryRateLimit(store, limit)),
+		grpc.ChainStreamInterceptor(StreamLimitFailedAuth(store, limit), StreamAuth(keys, nil), StreamRateLimit(store, limit)),
+	)
+	return dialServer(t, server, ou, of, key)
+}
+
+//retryDelayOf returns the delay carried by the RetryInfo detail of a gRPC error
+func retryDelayOf(err error) time.Duration {
+	for _, detail := range status.Convert(err).Details() {
+		if info, ok := detail.(*errdetails.RetryInfo); ok {
+			return info.RetryDelay.AsDuration()
+		}
+	}
+	return 0
+}
+
+/*
+	Actual test functions
+*/
+
+func TestRateLimit(t *testing.T) {
+
+	t.Run("Should return ResourceExhausted once the bucket of the caller is empty", func(t *testing.T) {
+		assert := assert.New(t)
+		testObj := new(MockedOrderUsecase)
+		testObj.On("FetchByRange", 1, 10).Return([]models.Order{}, nil).Twice()
+		client := dialLimited(t, testObj, nil, "reader-key", repository.NewMemoryRateLimitStore(), models.RateLimit{Requests: 2, Period: time.Minute})
+
+		for i := 0; i < 2; i++ {
+			_, err := client.ListOrders(context.Background(), &pb.ListOrdersRequest{})
+			assert.NoError(err)
+		}
+		_, err := client.ListOrders(context.Background(), &pb.ListOrdersRequest{})
+		assert.Equal(codes.ResourceExhausted, status.Code(err))
+		assert.Equal("rate_limited", reasonOf(err))
+		assert.InDelta(30*time.Second, retryDelayOf(err), float64(time.Second))
+		testObj.AssertExpectations(t)
+	})
+
+	t.Run("Should take a token when a stream starts", func(t *testing.T) {
+		assert := assert.New(t)
+		testObj := new(MockedOrderUsecase)
+		testObj.On("FetchByRange", 1, 10).Return([]models.Order{}, nil).Once()
+		client := dialLimited(t, testObj, usecase.NewOrderFeed(10), "reader-key", repository.NewMemoryRateLimitStore(), models.RateLimit{Requests: 1, Period: time.Minute})
+
+		//Streams share the bucket of unary calls
+		_, err := client.ListOrders(context.Background(), &pb.ListOrdersRequest{})
+		assert.NoError(err)
+		stream, err := client.WatchOrders(context.Background(), &pb.WatchOrdersRequest{})
+		assert.NoError(err)
+		_, err = stream.Recv()
+		assert.Equal(codes.ResourceExhausted, status.Code(err))
+		testObj.AssertExpectations(t)
+	})
+
+	t.Run("Should let calls through when the store fails", func(t *testing.T) {
+		assert := assert.New(t)
+		testObj := new(MockedOrderUsecase)
+		testObj.On("FetchByRange", 1, 10).Return([]models.Order{}, nil)
+		storeObj := new(MockedRateLimitStore)
+		storeObj.On("Check", mock.Anything, mock.Anything).Return((*models.RateLimitStatus)(nil), errors.New("connection lost"))
+		storeObj.On("Take", "subject:reader *", models.RateLimit{Requests: 1, Period: time.Minute}).Return((*models.RateLimitStatus)(nil), errors.New("connection lost"))
+		client := dialLimited(t, testObj, nil, "reader-key", storeObj, models.RateLimit{Requests: 1, Period: time.Minute})
+
+		_, err := client.ListOrders(context.Background(), &pb.ListOrdersRequest{})
+		assert.NoError(err)
+		testObj.AssertExpectations(t)
+		storeObj.AssertExpectations(t)
+	})
+}
+
+func TestLimitFailedAuth(t *testing.T) {
+
+	t.Run("Should return ResourceExhausted to repeated bad keys, without checking the keys", func(t *testing.T) {
+		assert := assert.New(t)
+		store := repository.NewMemoryRateLimitStore()
+		limit := models.RateLimit{Requests: 2, Period: time.Minute}
+		client := dialLimited(t, new(MockedOrderUsecase), nil, "osk_guess", store, limit)
+
+		for i := 0; i < 2; i++ {
+			_, err := client.ListOrders(context.Background(), &pb.ListOrdersRequest{})
+			assert.Equal(codes.Unauthenticated, status.Code(err))
+		}
+		_, err := client.ListOrders(context.Background(), &pb.ListOrdersRequest{})
+		assert.Equal(codes.ResourceExhausted, status.Code(err))
+		assert.Equal("rate_limited", reasonOf(err))
+		assert.Contains(status.Convert(err).Message(), "Too many failed authentications")
+
+		//Even the right key is not checked from the address
+		testObj := new(MockedOrderUsecase)
+		client = dialLimited(t, testObj, nil, "reader-key", store, limit)
+		_, err = client.ListOrders(context.Background(), &pb.ListOrdersRequest{})
+		assert.Equal(codes.ResourceExhausted, status.Code(err))
+		testObj.AssertNotCalled(t, "FetchByRange", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Should count the failed authentications of streams", func(t *testing.T) {
+		assert := assert.New(t)
+		client := dialLimited(t, new(MockedOrderUsecase), usecase.NewOrderFeed(10), "osk_guess", repository.NewMemoryRateLimitStore(), models.RateLimit{Requests: 1, Period: time.Minute})
+
+		stream, err := client.WatchOrders(context.Background(), &pb.WatchOrdersRequest{})
+		assert.NoError(err)
+		_, err = stream.Recv()
+		assert.Equal(codes.Unauthenticated, status.Code(err))
+		stream, err = client.WatchOrders(context.Background(), &pb.WatchOrdersRequest{})
+		assert.NoError(err)
+		_, err = stream.Recv()
+		assert.Equal(codes.ResourceExhausted, status.Code(err))
+	})
+
+	t.Run("Should not count successful authentications", func(t *testing.T) {
+		assert := assert.New(t)
+		testObj := new(MockedOrderUsecase)
+		testObj.On("FetchByRange", 1, 10).Return([]models.Order{}, nil)
+		client := dialLimited(t, testObj, nil, "reader-key", repository.NewMemoryRateLimitStore(), models.RateLimit{Requests: 1, Period: time.Minute})
+
+		_, err := client.ListOrders(context.Background(), &pb.ListOrdersRequest{})
+		assert.NoError(err)
+		testObj.AssertExpectations(t)
+	})
+}
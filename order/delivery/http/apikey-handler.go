@@ -0,0 +1,57 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/karanbhomiagit/order-service/models"
+	"github.com/karanbhomiagit/order-service/order"
+	"github.com/karanbhomiagit/order-service/order/logging"
+)
+
+type APIKeyHttpHandler struct {
+	apiKeyUsecase order.APIKeyUsecase
+}
+
+func NewAPIKeyHttpHandler(router *Router, aku order.APIKeyUsecase) {
+	handler := &APIKeyHttpHandler{
+		apiKeyUsecase: aku,
+	}
+	handler.routes(router)
+}
+
+//routes registers the entrypoints for the "/admin/keys" paths
+func (h *APIKeyHttpHandler) routes(router *Router) {
+	router.Handle(http.MethodGet, "/admin/keys", requireScope(order.ScopeKeysManage, h.getKeys))
+	router.Handle(http.MethodPost, "/admin/keys", requireScope(order.ScopeKeysManage, h.postKey))
+	router.Handle(http.MethodDelete, "/admin/keys/:id", requireScope(order.ScopeKeysManage, h.deleteKeyByID))
+}
+
+func (h *APIKeyHttpHandler) getKeys(w http.ResponseWriter, r *http.Request) {
+	res, err := h.apiKeyUsecase.FetchAll()
+	if res == nil && err == nil {
+		res = make([]models.APIKey, 0)
+	}
+	respondWithResult(w, r, http.StatusOK, res, err)
+}
+
+func (h *APIKeyHttpHandler) postKey(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	var keyReq models.APIKeyRequest
+	if err := json.NewDecoder(r.Body).Decode(&keyReq); err != nil {
+		logging.FromContext(r.Context()).Debug("Unable to decode the request body", "error", err)
+		respondWithProblem(w, r, http.StatusBadRequest, "invalid_payload", "Invalid request payload")
+		return
+	}
+	res, err := h.apiKeyUsecase.Issue(&keyReq)
+	respondWithResult(w, r, http.StatusCreated, res, err)
+}
+
+func (h *APIKeyHttpHandler) deleteKeyByID(w http.ResponseWriter, r *http.Request) {
+	id := Param(r, "id")
+	if err := h.apiKeyUsecase.RevokeByID(id); err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
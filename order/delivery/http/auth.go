@@ -0,0 +1,112 @@
+package http
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/karanbhomiagit/order-service/models"
+	"github.com/karanbhomiagit/order-service/order"
+	"github.com/karanbhomiagit/order-service/order/logging"
+)
+
+const (
+	APIKeyHeader = "X-API-Key"
+	//AuthChallenge is sent along with 401 responses
+	AuthChallenge = `ApiKey header="` + APIKeyHeader + `", Bearer`
+)
+
+var errTokensDisabled = order.NewUnauthenticated("invalid_token", "Bearer tokens are not accepted")
+
+//Authenticate returns a middleware attaching the identity of the caller to the request: the client owning
+//the API key, or the end user carrying a bearer token. Requests without credentials go through anonymously,
+//so that public routes stay reachable; requests with invalid credentials are rejected. The token verifier
+//may be nil when bearer tokens are not accepted.
+func Authenticate(aku order.APIKeyUsecase, tv order.TokenVerifier) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			identity, err := authenticate(r, aku, tv)
+			if err != nil {
+				respondWithError(w, r, err)
+				return
+			}
+			if identity != nil {
+				r = r.WithContext(order.NewContext(r.Context(), identity))
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+//LimitFailedAuth returns a middleware limiting the failed authentications of each IP address with token buckets
+//kept in the store, to be used before Authenticate. Every request carrying credentials which answers 401 takes a
+//token; once the bucket of the address is empty its requests carrying credentials answer 429 without being
+//authenticated, so that keys and tokens cannot be guessed. Requests are let through when the store fails.
+func LimitFailedAuth(store order.RateLimitStore, limit models.RateLimit) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if limit.Requests <= 0 || limit.Period <= 0 || !hasCredentials(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			key := "ip:" + clientIP(r) + " auth"
+			status, err := store.Check(key, limit, time.Now())
+			if err != nil {
+				logging.FromContext(r.Context()).Error("Unable to check the failed authentications, letting the request through", "error", err)
+				next.ServeHTTP(w, r)
+				return
+			}
+			if !status.Allowed {
+				retryAfter := seconds(status.RetryAfter)
+				w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+				respondWithProblem(w, r, http.StatusTooManyRequests, "rate_limited", fmt.Sprintf("Too many failed authentications, retry in %d seconds", retryAfter))
+				return
+			}
+			sw := &statusWriter{ResponseWriter: w}
+			next.ServeHTTP(sw, r)
+			if sw.Status() != http.StatusUnauthorized {
+				return
+			}
+			if _, err := store.Take(key, limit, time.Now()); err != nil {
+				logging.FromContext(r.Context()).Error("Unable to record the failed authentication", "error", err)
+			}
+		})
+	}
+}
+
+//hasCredentials reports whether the request carries an API key or a bearer token
+func hasCredentials(r *http.Request) bool {
+	if r.Header.Get(APIKeyHeader) != "" {
+		return true
+	}
+	scheme, _, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	return ok && strings.EqualFold(scheme, "Bearer")
+}
+
+//authenticate returns the identity of the credentials of the request, or nil when there are none
+func authenticate(r *http.Request, aku order.APIKeyUsecase, tv order.TokenVerifier) (*order.Identity, error) {
+	if key := r.Header.Get(APIKeyHeader); key != "" {
+		return aku.Authenticate(key)
+	}
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return nil, nil
+	}
+	if tv == nil {
+		return nil, errTokensDisabled
+	}
+	return tv.Verify(strings.TrimSpace(token))
+}
+
+//requireScope wraps the handler so that it only serves callers granted the scope
+func requireScope(scope string, handler http.HandlerFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := order.Authorize(r.Context(), scope); err != nil {
+			respondWithError(w, r, err)
+			return
+		}
+		handler(w, r)
+	})
+}
@@ -0,0 +1,320 @@
+package http
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/karanbhomiagit/order-service/models"
+	"github.com/karanbhomiagit/order-service/order"
+	"github.com/karanbhomiagit/order-service/order/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"gopkg.in/mgo.v2/bson"
+)
+
+type MockedAPIKeyUsecase struct {
+	mock.Mock
+}
+
+func (ku *MockedAPIKeyUsecase) Authenticate(key string) (*order.Identity, error) {
+	args := ku.Called(key)
+	return args.Get(0).(*order.Identity), args.Error(1)
+}
+
+func (ku *MockedAPIKeyUsecase) FetchAll() ([]models.APIKey, error) {
+	args := ku.Called()
+	return args.Get(0).([]models.APIKey), args.Error(1)
+}
+
+func (ku *MockedAPIKeyUsecase) Issue(keyReq *models.APIKeyRequest) (*models.APIKey, error) {
+	args := ku.Called(keyReq)
+	return args.Get(0).(*models.APIKey), args.Error(1)
+}
+
+func (ku *MockedAPIKeyUsecase) RevokeByID(id string) error {
+	args := ku.Called(id)
+	return args.Error(0)
+}
+
+type MockedTokenVerifier struct {
+	mock.Mock
+}
+
+func (tv *MockedTokenVerifier) Verify(token string) (*order.Identity, error) {
+	args := tv.Called(token)
+	return args.Get(0).(*order.Identity), args.Error(1)
+}
+
+const testAPIKey = "osk_test"
+
+/*
+	Actual test functions
+*/
+
+func TestAuthenticate(t *testing.T) {
+
+	//whoami responds with the subject of the identity of the request
+	whoami := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		identity, ok := order.IdentityFrom(r.Context())
+		if !ok {
+			w.Write([]byte("anonymous"))
+			return
+		}
+		w.Write([]byte(identity.Subject))
+	})
+
+	t.Run("Should let requests without an API key through anonymously", func(t *testing.T) {
+		assert := assert.New(t)
+		testObj := new(MockedAPIKeyUsecase)
+		rec := httptest.NewRecorder()
+		Authenticate(testObj, nil)(whoami).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/orders", nil))
+		assert.Equal(http.StatusOK, rec.Code)
+		assert.Equal("anonymous", rec.Body.String())
+		testObj.AssertExpectations(t)
+	})
+
+	t.Run("Should attach the identity owning the API key to the request", func(t *testing.T) {
+		assert := assert.New(t)
+		testObj := new(MockedAPIKeyUsecase)
+		testObj.On("Authenticate", testAPIKey).Return(&order.Identity{Subject: "merchant", Scopes: []string{order.ScopeOrdersRead}}, nil)
+		req := httptest.NewRequest(http.MethodGet, "/orders", nil)
+		req.Header.Set(APIKeyHeader, testAPIKey)
+		rec := httptest.NewRecorder()
+		Authenticate(testObj, nil)(whoami).ServeHTTP(rec, req)
+		assert.Equal(http.StatusOK, rec.Code)
+		assert.Equal("merchant", rec.Body.String())
+		testObj.AssertExpectations(t)
+	})
+
+	t.Run("Should respond with 401 and a challenge for an invalid API key", func(t *testing.T) {
+		assert := assert.New(t)
+		testObj := new(MockedAPIKeyUsecase)
+		testObj.On("Authenticate", testAPIKey).Return((*order.Identity)(nil), order.NewUnauthenticated("invalid_api_key", "Invalid API key"))
+		req := httptest.NewRequest(http.MethodGet, "/orders", nil)
+		req.Header.Set(APIKeyHeader, testAPIKey)
+		rec := httptest.NewRecorder()
+		Authenticate(testObj, nil)(whoami).ServeHTTP(rec, req)
+		assert.Equal(http.StatusUnauthorized, rec.Code)
+		assert.Equal(AuthChallenge, rec.Header().Get("WWW-Authenticate"))
+		assert.Contains(rec.Body.String(), `"code":"invalid_api_key"`)
+		testObj.AssertExpectations(t)
+	})
+
+	t.Run("Should attach the identity of the end user carrying a bearer token to the request", func(t *testing.T) {
+		assert := assert.New(t)
+		testObj := new(MockedTokenVerifier)
+		testObj.On("Verify", "eyJ.token").Return(&order.Identity{Subject: "courier-1", Roles: []string{order.RoleCourier}}, nil)
+		req := httptest.NewRequest(http.MethodPatch, "/orders/1234", nil)
+		req.Header.Set("Authorization", "Bearer eyJ.token")
+		rec := httptest.NewRecorder()
+		Authenticate(new(MockedAPIKeyUsecase), testObj)(whoami).ServeHTTP(rec, req)
+		assert.Equal(http.StatusOK, rec.Code)
+		assert.Equal("courier-1", rec.Body.String())
+		testObj.AssertExpectations(t)
+	})
+
+	t.Run("Should respond with 401 for an invalid bearer token", func(t *testing.T) {
+		assert := assert.New(t)
+		testObj := new(MockedTokenVerifier)
+		testObj.On("Verify", "eyJ.token").Return((*order.Identity)(nil), order.NewUnauthenticated("invalid_token", "Invalid bearer token"))
+		req := httptest.NewRequest(http.MethodPatch, "/orders/1234", nil)
+		req.Header.Set("Authorization", "Bearer eyJ.token")
+		rec := httptest.NewRecorder()
+		Authenticate(new(MockedAPIKeyUsecase), testObj)(whoami).ServeHTTP(rec, req)
+		assert.Equal(http.StatusUnauthorized, rec.Code)
+		assert.Contains(rec.Body.String(), `"code":"invalid_token"`)
+		testObj.AssertExpectations(t)
+	})
+
+	t.Run("Should respond with 401 for a bearer token when tokens are not accepted", func(t *testing.T) {
+		assert := assert.New(t)
+		req := httptest.NewRequest(http.MethodPatch, "/orders/1234", nil)
+		req.Header.Set("Authorization", "Bearer eyJ.token")
+		rec := httptest.NewRecorder()
+		Authenticate(new(MockedAPIKeyUsecase), nil)(whoami).ServeHTTP(rec, req)
+		assert.Equal(http.StatusUnauthorized, rec.Code)
+		assert.Contains(rec.Body.String(), `"code":"invalid_token"`)
+	})
+}
+
+func TestLimitFailedAuth(t *testing.T) {
+
+	whoami := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		identity, ok := order.IdentityFrom(r.Context())
+		if !ok {
+			w.Write([]byte("anonymous"))
+			return
+		}
+		w.Write([]byte(identity.Subject))
+	})
+	requestWithKey := func(key string, remoteAddr string) *http.Request {
+		req := requestFrom(http.MethodGet, "/orders", remoteAddr)
+		if key != "" {
+			req.Header.Set(APIKeyHeader, key)
+		}
+		return req
+	}
+
+	t.Run("Should respond with 429 to repeated bad keys, without checking the keys", func(t *testing.T) {
+		assert := assert.New(t)
+		testObj := new(MockedAPIKeyUsecase)
+		testObj.On("Authenticate", "osk_guess").Return((*order.Identity)(nil), order.NewUnauthenticated("invalid_api_key", "Invalid API key")).Times(2)
+		testObj.On("Authenticate", testAPIKey).Return(&order.Identity{Subject: "merchant-1"}, nil).Once()
+		handler := LimitFailedAuth(repository.NewMemoryRateLimitStore(), models.RateLimit{Requests: 2, Period: time.Minute})(Authenticate(testObj, nil)(whoami))
+
+		for i := 0; i < 2; i++ {
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, requestWithKey("osk_guess", "10.0.0.1:1234"))
+			assert.Equal(http.StatusUnauthorized, rec.Code)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, requestWithKey("osk_guess", "10.0.0.1:1234"))
+		assert.Equal(http.StatusTooManyRequests, rec.Code)
+		assert.Equal("30", rec.Header().Get("Retry-After"))
+		assert.Contains(rec.Body.String(), `"code":"rate_limited"`)
+
+		//Even the right key is not checked from the address
+		rec = httptest.NewRecorder()
+		handler.ServeHTTP(rec, requestWithKey(testAPIKey, "10.0.0.1:5678"))
+		assert.Equal(http.StatusTooManyRequests, rec.Code)
+
+		//Other addresses and anonymous requests are not limited
+		rec = httptest.NewRecorder()
+		handler.ServeHTTP(rec, requestWithKey(testAPIKey, "10.0.0.2:1234"))
+		assert.Equal(http.StatusOK, rec.Code)
+		assert.Equal("merchant-1", rec.Body.String())
+		rec = httptest.NewRecorder()
+		handler.ServeHTTP(rec, requestWithKey("", "10.0.0.1:1234"))
+		assert.Equal(http.StatusOK, rec.Code)
+		assert.Equal("anonymous", rec.Body.String())
+		testObj.AssertExpectations(t)
+	})
+
+	t.Run("Should not count successful authentications", func(t *testing.T) {
+		assert := assert.New(t)
+		testObj := new(MockedAPIKeyUsecase)
+		testObj.On("Authenticate", testAPIKey).Return(&order.Identity{Subject: "merchant-1"}, nil)
+		handler := LimitFailedAuth(repository.NewMemoryRateLimitStore(), models.RateLimit{Requests: 2, Period: time.Minute})(Authenticate(testObj, nil)(whoami))
+
+		for i := 0; i < 5; i++ {
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, requestWithKey(testAPIKey, "10.0.0.1:1234"))
+			assert.Equal(http.StatusOK, rec.Code)
+		}
+		testObj.AssertExpectations(t)
+	})
+
+	t.Run("Should let requests through when the store fails", func(t *testing.T) {
+		assert := assert.New(t)
+		testObj := new(MockedAPIKeyUsecase)
+		testObj.On("Authenticate", testAPIKey).Return(&order.Identity{Subject: "merchant-1"}, nil)
+		store := new(MockedRateLimitStore)
+		store.On("Check", "ip:10.0.0.1 auth", mock.Anything).Return((*models.RateLimitStatus)(nil), errors.New("connection lost"))
+		handler := LimitFailedAuth(store, models.RateLimit{Requests: 2, Period: time.Minute})(Authenticate(testObj, nil)(whoami))
+
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, requestWithKey(testAPIKey, "10.0.0.1:1234"))
+		assert.Equal(http.StatusOK, rec.Code)
+		store.AssertExpectations(t)
+	})
+}
+
+func TestRequireScope(t *testing.T) {
+	ok := func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}
+
+	t.Run("Should respond with 401 for anonymous requests", func(t *testing.T) {
+		assert := assert.New(t)
+		rec := httptest.NewRecorder()
+		requireScope(order.ScopeOrdersRead, ok).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/orders", nil))
+		assert.Equal(http.StatusUnauthorized, rec.Code)
+		assert.Equal(AuthChallenge, rec.Header().Get("WWW-Authenticate"))
+		assert.Contains(rec.Body.String(), `"code":"unauthenticated"`)
+	})
+
+	t.Run("Should respond with 403 when the scope was not granted", func(t *testing.T) {
+		assert := assert.New(t)
+		identity := &order.Identity{Subject: "merchant", Scopes: []string{order.ScopeOrdersRead}}
+		req := httptest.NewRequest(http.MethodPost, "/orders", nil)
+		req = req.WithContext(order.NewContext(req.Context(), identity))
+		rec := httptest.NewRecorder()
+		requireScope(order.ScopeOrdersCreate, ok).ServeHTTP(rec, req)
+		assert.Equal(http.StatusForbidden, rec.Code)
+		assert.Contains(rec.Body.String(), `"code":"forbidden"`)
+	})
+
+	t.Run("Should serve the request when the scope was granted", func(t *testing.T) {
+		assert := assert.New(t)
+		identity := &order.Identity{Subject: "merchant", Scopes: []string{order.ScopeOrdersRead}}
+		req := httptest.NewRequest(http.MethodGet, "/orders", nil)
+		req = req.WithContext(order.NewContext(req.Context(), identity))
+		rec := httptest.NewRecorder()
+		requireScope(order.ScopeOrdersRead, ok).ServeHTTP(rec, req)
+		assert.Equal(http.StatusNoContent, rec.Code)
+	})
+}
+
+func TestAPIKeysHandler(t *testing.T) {
+
+	t.Run("Should respond with 201 and the key for POST /admin/keys", func(t *testing.T) {
+		assert := assert.New(t)
+		testObj := new(MockedAPIKeyUsecase)
+		keyReq := models.APIKeyRequest{Name: "merchant", Scopes: []string{order.ScopeOrdersRead}}
+		testObj.On("Issue", &keyReq).Return(&models.APIKey{
+			ID:     bson.ObjectId("12345"),
+			Name:   "merchant",
+			Key:    "osk_0123",
+			Scopes: []string{order.ScopeOrdersRead},
+		}, nil)
+		handler := &APIKeyHttpHandler{apiKeyUsecase: testObj}
+		body := []byte(`{"name":"merchant","scopes":["orders:read"]}`)
+		req := httptest.NewRequest(http.MethodPost, "/admin/keys", bytes.NewReader(body))
+		rec := httptest.NewRecorder()
+		routed(handler).ServeHTTP(rec, req)
+		assert.Equal(http.StatusCreated, rec.Code)
+		res, _ := ioutil.ReadAll(rec.Body)
+		assert.Contains(string(res), `"key":"osk_0123"`)
+		assert.NotContains(string(res), "hash")
+		testObj.AssertExpectations(t)
+	})
+
+	t.Run("Should respond with an empty list for GET /admin/keys when there are no keys", func(t *testing.T) {
+		assert := assert.New(t)
+		testObj := new(MockedAPIKeyUsecase)
+		testObj.On("FetchAll").Return([]models.APIKey(nil), nil)
+		handler := &APIKeyHttpHandler{apiKeyUsecase: testObj}
+		rec := httptest.NewRecorder()
+		routed(handler).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/admin/keys", nil))
+		assert.Equal(http.StatusOK, rec.Code)
+		assert.Equal("[]", rec.Body.String())
+		testObj.AssertExpectations(t)
+	})
+
+	t.Run("Should respond with 204 for DELETE /admin/keys/:id when revoked", func(t *testing.T) {
+		assert := assert.New(t)
+		testObj := new(MockedAPIKeyUsecase)
+		testObj.On("RevokeByID", "12345").Return(nil)
+		handler := &APIKeyHttpHandler{apiKeyUsecase: testObj}
+		rec := httptest.NewRecorder()
+		routed(handler).ServeHTTP(rec, httptest.NewRequest(http.MethodDelete, "/admin/keys/12345", nil))
+		assert.Equal(http.StatusNoContent, rec.Code)
+		testObj.AssertExpectations(t)
+	})
+
+	t.Run("Should respond with 404 for DELETE /admin/keys/:id when the key is missing", func(t *testing.T) {
+		assert := assert.New(t)
+		testObj := new(MockedAPIKeyUsecase)
+		testObj.On("RevokeByID", "12345").Return(order.NewNotFound("api_key_not_found", "Invalid Id"))
+		handler := &APIKeyHttpHandler{apiKeyUsecase: testObj}
+		rec := httptest.NewRecorder()
+		routed(handler).ServeHTTP(rec, httptest.NewRequest(http.MethodDelete, "/admin/keys/12345", nil))
+		assert.Equal(http.StatusNotFound, rec.Code)
+		testObj.AssertExpectations(t)
+	})
+}
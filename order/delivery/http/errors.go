@@ -0,0 +1,73 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/karanbhomiagit/order-service/order"
+	"github.com/karanbhomiagit/order-service/order/logging"
+)
+
+const (
+	ProblemContentType = "application/problem+json"
+)
+
+//Problem is an RFC 7807 problem details response, extended with a stable machine-readable code
+type Problem struct {
+	Type   string `json:"type"`
+	Title  string `json:"title"`
+	Status int    `json:"status"`
+	Detail string `json:"detail,omitempty"`
+	Code   string `json:"code"`
+}
+
+//statusCodes maps each kind of domain error to its http response code
+var statusCodes = map[order.Kind]int{
+	order.KindNotFound:         http.StatusNotFound,
+	order.KindInvalidArgument:  http.StatusBadRequest,
+	order.KindConflict:         http.StatusConflict,
+	order.KindUnavailable:      http.StatusServiceUnavailable,
+	order.KindUnauthenticated:  http.StatusUnauthorized,
+	order.KindPermissionDenied: http.StatusForbidden,
+}
+
+//respondWithError is the central mapping of errors returned by the usecase layer to problem responses
+func respondWithError(w http.ResponseWriter, r *http.Request, err error) {
+	statusCode, ok := statusCodes[order.KindOf(err)]
+	if !ok {
+		//Unexpected errors may carry internal details which clients should not see
+		logging.FromContext(r.Context()).Error("Internal error", "error", err)
+		respondWithProblem(w, r, http.StatusInternalServerError, "internal", "Internal error")
+		return
+	}
+	//The cause, such as the error of MongoDB or the Google APIs, is only logged
+	if cause := errors.Unwrap(err); cause != nil {
+		logging.FromContext(r.Context()).Warn("Request failed", "code", order.CodeOf(err), "error", cause)
+	}
+	if statusCode == http.StatusUnauthorized {
+		w.Header().Set("WWW-Authenticate", AuthChallenge)
+	}
+	respondWithProblem(w, r, statusCode, order.CodeOf(err), err.Error())
+}
+
+//respondWithProblem writes a problem response for errors raised in the delivery layer itself
+func respondWithProblem(w http.ResponseWriter, r *http.Request, statusCode int, code string, detail string) {
+	level := slog.LevelInfo
+	if statusCode >= http.StatusInternalServerError {
+		level = slog.LevelError
+	}
+	logging.FromContext(r.Context()).Log(r.Context(), level, "Problem response", "status", statusCode, "code", code, "detail", detail)
+	problem := Problem{
+		Type:   "about:blank",
+		Title:  http.StatusText(statusCode),
+		Status: statusCode,
+		Detail: detail,
+		Code:   code,
+	}
+	b, _ := json.Marshal(problem)
+	w.Header().Set("Content-Type", ProblemContentType)
+	w.WriteHeader(statusCode)
+	w.Write(b)
+}
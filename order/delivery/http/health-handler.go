@@ -0,0 +1,44 @@
+package http
+
+import (
+	"net/http"
+
+	"github.com/karanbhomiagit/order-service/models"
+	"github.com/karanbhomiagit/order-service/order"
+	"github.com/karanbhomiagit/order-service/order/logging"
+)
+
+type HealthHttpHandler struct {
+	healthUsecase order.HealthUsecase
+}
+
+//NewHealthHttpHandler registers the probes of the orchestrator, outside of the versioned API
+func NewHealthHttpHandler(router *Router, hu order.HealthUsecase) {
+	handler := &HealthHttpHandler{
+		healthUsecase: hu,
+	}
+	handler.routes(router)
+}
+
+//routes registers the entrypoints for the "/healthz" and "/readyz" paths
+func (h *HealthHttpHandler) routes(router *Router) {
+	router.HandleFunc(http.MethodGet, "/healthz", h.getHealthz)
+	router.HandleFunc(http.MethodGet, "/readyz", h.getReadyz)
+}
+
+//getHealthz responds as long as the process is able to serve requests, whatever the state of its dependencies
+func (h *HealthHttpHandler) getHealthz(w http.ResponseWriter, r *http.Request) {
+	respondWithResult(w, r, http.StatusOK, models.Health{Status: models.HealthOK}, nil)
+}
+
+//getReadyz responds with the outcome of the check of each dependency, and 503 unless the service is ready
+func (h *HealthHttpHandler) getReadyz(w http.ResponseWriter, r *http.Request) {
+	health := h.healthUsecase.Ready(r.Context())
+	statusCode := http.StatusOK
+	if health.Status != models.HealthOK {
+		statusCode = http.StatusServiceUnavailable
+		logging.FromContext(r.Context()).Warn("Not ready", "status", health.Status, "checks", health.Checks)
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	respondWithResult(w, r, statusCode, health, nil)
+}
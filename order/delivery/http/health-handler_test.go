@@ -0,0 +1,106 @@
+package http
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/karanbhomiagit/order-service/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockedHealthUsecase struct {
+	mock.Mock
+}
+
+func (hu *MockedHealthUsecase) Ready(ctx context.Context) models.Health {
+	args := hu.Called()
+	return args.Get(0).(models.Health)
+}
+
+func (hu *MockedHealthUsecase) Shutdown() {
+	hu.Called()
+}
+
+/*
+	Actual test functions
+*/
+
+func TestHealthHandler(t *testing.T) {
+
+	t.Run("Should respond with 200 for GET /healthz without checking the dependencies", func(t *testing.T) {
+		testObj := new(MockedHealthUsecase)
+		handler := &HealthHttpHandler{healthUsecase: testObj}
+
+		req, err := http.NewRequest(http.MethodGet, "/healthz", nil)
+		assert.NoError(t, err)
+		rec := httptest.NewRecorder()
+
+		routed(handler).ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		body, _ := ioutil.ReadAll(rec.Body)
+		assert.Equal(t, `{"status":"ok"}`, string(body))
+		testObj.AssertExpectations(t)
+	})
+
+	t.Run("Should respond with 200 and the checks for GET /readyz when ready", func(t *testing.T) {
+		testObj := new(MockedHealthUsecase)
+		testObj.On("Ready").Return(models.Health{Status: models.HealthOK, Checks: map[string]models.HealthCheck{
+			"mongo": {Status: models.HealthOK, LatencyMs: 1.5},
+		}})
+		handler := &HealthHttpHandler{healthUsecase: testObj}
+
+		req, err := http.NewRequest(http.MethodGet, "/readyz", nil)
+		assert.NoError(t, err)
+		rec := httptest.NewRecorder()
+
+		routed(handler).ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		body, _ := ioutil.ReadAll(rec.Body)
+		assert.Equal(t, `{"status":"ok","checks":{"mongo":{"status":"ok","latencyMs":1.5}}}`, string(body))
+		assert.Equal(t, "no-store", rec.Header().Get("Cache-Control"))
+		testObj.AssertExpectations(t)
+	})
+
+	t.Run("Should respond with 503 and the failing checks for GET /readyz when a dependency is unavailable", func(t *testing.T) {
+		testObj := new(MockedHealthUsecase)
+		testObj.On("Ready").Return(models.Health{Status: models.HealthFailing, Checks: map[string]models.HealthCheck{
+			"mongo":    {Status: models.HealthFailing, Error: "no reachable servers", LatencyMs: 2},
+			"distance": {Status: models.HealthOK, LatencyMs: 30},
+		}})
+		handler := &HealthHttpHandler{healthUsecase: testObj}
+
+		req, err := http.NewRequest(http.MethodGet, "/readyz", nil)
+		assert.NoError(t, err)
+		rec := httptest.NewRecorder()
+
+		routed(handler).ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+		body, _ := ioutil.ReadAll(rec.Body)
+		assert.Equal(t, `{"status":"failing","checks":{"distance":{"status":"ok","latencyMs":30},"mongo":{"status":"failing","error":"no reachable servers","latencyMs":2}}}`, string(body))
+		testObj.AssertExpectations(t)
+	})
+
+	t.Run("Should respond with 503 for GET /readyz while shutting down", func(t *testing.T) {
+		testObj := new(MockedHealthUsecase)
+		testObj.On("Ready").Return(models.Health{Status: models.HealthShuttingDown})
+		handler := &HealthHttpHandler{healthUsecase: testObj}
+
+		req, err := http.NewRequest(http.MethodGet, "/readyz", nil)
+		assert.NoError(t, err)
+		rec := httptest.NewRecorder()
+
+		routed(handler).ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+		body, _ := ioutil.ReadAll(rec.Body)
+		assert.Equal(t, `{"status":"shutting_down"}`, string(body))
+		testObj.AssertExpectations(t)
+	})
+}
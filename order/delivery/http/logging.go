@@ -0,0 +1,57 @@
+package http
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/karanbhomiagit/order-service/order/logging"
+)
+
+const (
+	RequestIDHeader = "X-Request-ID"
+	//maxRequestIDLength bounds the ids taken from clients, which end up in every log line
+	maxRequestIDLength = 128
+)
+
+//Logging returns a middleware giving every request an id, taken from the X-Request-ID header when the
+//client or a proxy set one and echoed in the response, and writing a line when the request is served.
+//The logger and the request are carried by the context of the request, see logging.FromContext.
+func Logging(router *Router, logger *slog.Logger) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			id := r.Header.Get(RequestIDHeader)
+			if !validRequestID(id) {
+				id = logging.NewRequestID()
+			}
+			w.Header().Set(RequestIDHeader, id)
+			req := &logging.Request{ID: id, Route: router.Route(r), Start: time.Now()}
+			r = r.WithContext(logging.NewContext(r.Context(), logger, req))
+
+			sw := &statusWriter{ResponseWriter: w}
+			next.ServeHTTP(sw, r)
+			level := slog.LevelInfo
+			if sw.Status() >= http.StatusInternalServerError {
+				level = slog.LevelError
+			}
+			logging.FromContext(r.Context()).Log(r.Context(), level, "Request served",
+				"method", r.Method,
+				"path", r.URL.Path,
+				"status", sw.Status(),
+			)
+		})
+	}
+}
+
+//validRequestID tells whether the id is short and made of printable ASCII characters only
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
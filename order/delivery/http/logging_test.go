@@ -0,0 +1,90 @@
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/karanbhomiagit/order-service/order/logging"
+	"github.com/stretchr/testify/assert"
+)
+
+//logged returns a router logging to buf, with a route failing with 503 for the order of its id
+func logged(buf *bytes.Buffer) *Router {
+	router := NewRouter()
+	router.Use(Logging(router, logging.New(buf, slog.LevelInfo)))
+	router.HandleFunc(http.MethodPatch, "/v1/orders/:id", func(w http.ResponseWriter, r *http.Request) {
+		logging.SetOrderID(r.Context(), Param(r, "id"))
+		respondWithProblem(w, r, http.StatusServiceUnavailable, "database_unavailable", "Unable to reach the database")
+	})
+	return router
+}
+
+//logLines decodes the lines of JSON written to buf
+func logLines(t *testing.T, buf *bytes.Buffer) []map[string]interface{} {
+	var lines []map[string]interface{}
+	dec := json.NewDecoder(buf)
+	for dec.More() {
+		var line map[string]interface{}
+		if err := dec.Decode(&line); err != nil {
+			t.Fatal(err)
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+/*
+	Actual test functions
+*/
+
+func TestLogging(t *testing.T) {
+
+	t.Run("Should log every line of the request with its id, route, order and latency", func(t *testing.T) {
+		assert := assert.New(t)
+		var buf bytes.Buffer
+		req := httptest.NewRequest(http.MethodPatch, "/v1/orders/5c2b2aaf4530558539f91859", nil)
+		req.Header.Set(RequestIDHeader, "req-1234")
+		rec := httptest.NewRecorder()
+		logged(&buf).ServeHTTP(rec, req)
+
+		assert.Equal("req-1234", rec.Header().Get(RequestIDHeader))
+		lines := logLines(t, &buf)
+		if assert.Len(lines, 2) {
+			assert.Equal("Problem response", lines[0]["msg"])
+			assert.Equal("database_unavailable", lines[0]["code"])
+			assert.Equal("Request served", lines[1]["msg"])
+			assert.Equal("ERROR", lines[1]["level"])
+			assert.Equal(float64(http.StatusServiceUnavailable), lines[1]["status"])
+			for _, line := range lines {
+				assert.Equal("req-1234", line["request_id"])
+				assert.Equal("/v1/orders/:id", line["route"])
+				assert.Equal("5c2b2aaf4530558539f91859", line["order_id"])
+				assert.Contains(line, "latency_ms")
+			}
+		}
+	})
+
+	t.Run("Should give an id to requests without a valid one", func(t *testing.T) {
+		assert := assert.New(t)
+		var buf bytes.Buffer
+		req := httptest.NewRequest(http.MethodGet, "/v1/unknown", nil)
+		req.Header.Set(RequestIDHeader, "line\nbreak")
+		rec := httptest.NewRecorder()
+		logged(&buf).ServeHTTP(rec, req)
+
+		id := rec.Header().Get(RequestIDHeader)
+		assert.Len(id, 32)
+		lines := logLines(t, &buf)
+		if assert.NotEmpty(lines) {
+			last := lines[len(lines)-1]
+			assert.Equal(id, last["request_id"])
+			assert.Equal("INFO", last["level"])
+			assert.NotContains(last, "route")
+			assert.NotContains(last, "order_id")
+		}
+	})
+}
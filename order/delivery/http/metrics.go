@@ -0,0 +1,72 @@
+package http
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/karanbhomiagit/order-service/order/metrics"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+//Instrument returns a middleware recording the count and latency of the requests by route pattern and
+//status. It should be the outermost middleware of the router, so rejected requests are recorded too.
+func Instrument(router *Router) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			route := router.Route(r)
+			if route == "" {
+				//Unknown paths are not recorded one by one, to keep the number of series bounded
+				route = "unmatched"
+			}
+			sw := &statusWriter{ResponseWriter: w}
+			next.ServeHTTP(sw, r)
+			metrics.ObserveHTTPRequest(r.Method, route, sw.Status(), time.Since(start))
+		})
+	}
+}
+
+//NewMetricsHandler registers the entrypoint exposing the metrics in the Prometheus format
+func NewMetricsHandler(router *Router) {
+	router.Handle(http.MethodGet, "/metrics", promhttp.HandlerFor(metrics.Registry, promhttp.HandlerOpts{}))
+}
+
+//statusWriter remembers the status code of the response
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *statusWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+//Flush lets streamed responses, such as the order stream, through
+func (w *statusWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+//Unwrap lets http.ResponseController reach the writer of the server, e.g. to lift its write deadline
+func (w *statusWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+//Status returns the status code of the response, 200 when the handler wrote nothing
+func (w *statusWriter) Status() int {
+	if w.status == 0 {
+		return http.StatusOK
+	}
+	return w.status
+}
@@ -0,0 +1,62 @@
+package http
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/karanbhomiagit/order-service/order/metrics"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+/*
+	Actual test functions
+*/
+
+func TestInstrument(t *testing.T) {
+	router := NewRouter()
+	router.Use(Instrument(router))
+	router.HandleFunc(http.MethodGet, "/v1/orders/:id", func(w http.ResponseWriter, r *http.Request) {
+		respondWithProblem(w, r, http.StatusNotFound, "order_not_found", "not found")
+	})
+	router.HandleFunc(http.MethodGet, "/v1/orders/stream", func(w http.ResponseWriter, r *http.Request) {
+		_, ok := w.(http.Flusher)
+		assert.True(t, ok)
+		w.Write([]byte(": heartbeat\n\n"))
+	})
+	NewMetricsHandler(router)
+
+	t.Run("Should count requests by route pattern and status", func(t *testing.T) {
+		assert := assert.New(t)
+		counter := metrics.HTTPRequests.WithLabelValues(http.MethodGet, "/v1/orders/:id", "404")
+		before := testutil.ToFloat64(counter)
+		serve(router, http.MethodGet, "/v1/orders/1234")
+		serve(router, http.MethodGet, "/v1/orders/5678")
+		assert.Equal(before+2, testutil.ToFloat64(counter))
+	})
+
+	t.Run("Should keep streamed responses flushable and count them as 200", func(t *testing.T) {
+		assert := assert.New(t)
+		counter := metrics.HTTPRequests.WithLabelValues(http.MethodGet, "/v1/orders/stream", "200")
+		before := testutil.ToFloat64(counter)
+		rec := serve(router, http.MethodGet, "/v1/orders/stream")
+		assert.Equal(http.StatusOK, rec.Code)
+		assert.Equal(before+1, testutil.ToFloat64(counter))
+	})
+
+	t.Run("Should count unknown paths under a single route", func(t *testing.T) {
+		assert := assert.New(t)
+		counter := metrics.HTTPRequests.WithLabelValues(http.MethodGet, "unmatched", "404")
+		before := testutil.ToFloat64(counter)
+		serve(router, http.MethodGet, "/v1/unknown/1234")
+		assert.Equal(before+1, testutil.ToFloat64(counter))
+	})
+
+	t.Run("Should expose the metrics at /metrics", func(t *testing.T) {
+		assert := assert.New(t)
+		rec := serve(router, http.MethodGet, "/metrics")
+		assert.Equal(http.StatusOK, rec.Code)
+		assert.Contains(rec.Body.String(), `order_service_http_requests_total{method="GET",route="/v1/orders/:id",status="404"}`)
+		assert.Contains(rec.Body.String(), "order_service_http_request_duration_seconds_bucket")
+	})
+}
@@ -0,0 +1,20 @@
+package http
+
+import (
+	_ "embed"
+	"net/http"
+)
+
+//OpenAPI is the OpenAPI 3 document describing every endpoint of the service
+//
+//go:embed openapi.json
+var OpenAPI []byte
+
+//NewOpenAPIHandler serves the OpenAPI document at /openapi.json
+func NewOpenAPIHandler(router *Router) {
+	router.HandleFunc(http.MethodGet, "/openapi.json", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Content-Type", "application/json; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		w.Write(OpenAPI)
+	})
+}
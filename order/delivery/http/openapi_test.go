@@ -0,0 +1,209 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers/gorillamux"
+	"github.com/karanbhomiagit/order-service/models"
+	"github.com/karanbhomiagit/order-service/order"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/mgo.v2/bson"
+)
+
+func loadOpenAPI(t *testing.T) *openapi3.T {
+	doc, err := openapi3.NewLoader().LoadFromData(OpenAPI)
+	require.NoError(t, err)
+	require.NoError(t, doc.Validate(context.Background()))
+	return doc
+}
+
+//specRouter registers every route of the service as main does, with the usecases mocked
+func specRouter(ou *MockedOrderUsecase, wu *MockedWebhookUsecase, ku *MockedAPIKeyUsecase) *Router {
+	router := NewRouter()
+	router.Use(Authenticate(ku, nil))
+	Mount(router, ou, nil, wu, ku, nil, http.NotFoundHandler(), time.Now().Add(time.Hour))
+	return router
+}
+
+func TestOpenAPI(t *testing.T) {
+	doc := loadOpenAPI(t)
+
+	t.Run("Should document every route and nothing else", func(t *testing.T) {
+		assert := assert.New(t)
+		var documented []string
+		for path, item := range doc.Paths.Map() {
+			for method := range item.Operations() {
+				documented = append(documented, method+" "+path)
+			}
+		}
+		var routes []string
+		for _, r := range specRouter(nil, nil, nil).routes {
+			segments := make([]string, len(r.segments))
+			for i, segment := range r.segments {
+				if strings.HasPrefix(segment, ":") {
+					segment = "{" + segment[1:] + "}"
+				}
+				segments[i] = segment
+			}
+			for _, method := range r.methods() {
+				route := method + " /" + strings.Join(segments, "/")
+				//The unversioned aliases of the /v1 routes are documented as deprecated in the description, the
+				//other unversioned routes have to be documented one by one
+				if r.segments[0] != "v1" && slices.Contains(documented, method+" /v1/"+strings.Join(segments, "/")) {
+					continue
+				}
+				routes = append(routes, route)
+			}
+		}
+		sort.Strings(routes)
+		sort.Strings(documented)
+		assert.Equal(documented, routes)
+	})
+
+	t.Run("Should serve the document at /openapi.json", func(t *testing.T) {
+		assert := assert.New(t)
+		rec := serve(specRouter(nil, nil, nil), http.MethodGet, "/openapi.json")
+		assert.Equal(http.StatusOK, rec.Code)
+		assert.Equal("application/json; charset=utf-8", rec.Header().Get("Content-Type"))
+		body, _ := ioutil.ReadAll(rec.Body)
+		assert.JSONEq(string(OpenAPI), string(body))
+	})
+}
+
+func TestOpenAPIResponses(t *testing.T) {
+	doc := loadOpenAPI(t)
+	oaRouter, err := gorillamux.NewRouter(doc)
+	require.NoError(t, err)
+
+	id := "5c2b2aaf4530558539f91859"
+	orderRes := models.Order{ID: bson.ObjectIdHex(id), Distance: 100, Status: "UNASSIGNED"}
+	webhookRes := models.Webhook{ID: bson.ObjectIdHex(id), URL: "https://merchant.example.com/hooks", Events: []string{models.EventOrderAssigned}, CreatedAt: time.Now()}
+	deliveryRes := models.WebhookDelivery{ID: bson.NewObjectId(), WebhookID: bson.ObjectIdHex(id), EventID: "1", EventType: models.EventWebhookTest, Attempt: 1, StatusCode: 200, Success: true, DeliveredAt: time.Now()}
+	webhookReq := &models.WebhookRequest{URL: "https://merchant.example.com/hooks"}
+	keyRes := models.APIKey{ID: bson.ObjectIdHex(id), Name: "merchant", Scopes: []string{order.ScopeOrdersRead}, CreatedAt: time.Now()}
+
+	cases := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+		setup  func(ou *MockedOrderUsecase, wu *MockedWebhookUsecase, ku *MockedAPIKeyUsecase)
+		status int
+	}{
+		{"list orders", http.MethodGet, "/v1/orders", "", func(ou *MockedOrderUsecase, wu *MockedWebhookUsecase, ku *MockedAPIKeyUsecase) {
+			ou.On("FetchByRange", 1, 10).Return([]models.Order{orderRes}, nil)
+		}, http.StatusOK},
+		{"list orders with an invalid page", http.MethodGet, "/v1/orders?page=first", "", nil, http.StatusBadRequest},
+		{"list orders while the database is unavailable", http.MethodGet, "/v1/orders", "", func(ou *MockedOrderUsecase, wu *MockedWebhookUsecase, ku *MockedAPIKeyUsecase) {
+			ou.On("FetchByRange", 1, 10).Return([]models.Order(nil), order.NewUnavailable("database_unavailable", "Database is unavailable", nil))
+		}, http.StatusServiceUnavailable},
+		{"create an order", http.MethodPost, "/v1/orders", `{"origin":["22.3193","114.1694"],"destination":["22.2783","114.1747"]}`, func(ou *MockedOrderUsecase, wu *MockedWebhookUsecase, ku *MockedAPIKeyUsecase) {
+			ou.On("Store", mock.Anything).Return(&orderRes, nil)
+		}, http.StatusOK},
+		{"create an order with an invalid payload", http.MethodPost, "/v1/orders", `{"origin":`, nil, http.StatusBadRequest},
+		{"assign an order", http.MethodPatch, "/v1/orders/" + id, `{"status":"TAKEN"}`, func(ou *MockedOrderUsecase, wu *MockedWebhookUsecase, ku *MockedAPIKeyUsecase) {
+			ou.On("AssignByID", id, "TAKEN").Return(&map[string]string{"status": "SUCCESS"}, nil)
+		}, http.StatusOK},
+		{"assign a missing order", http.MethodPatch, "/v1/orders/1234", `{"status":"TAKEN"}`, func(ou *MockedOrderUsecase, wu *MockedWebhookUsecase, ku *MockedAPIKeyUsecase) {
+			ou.On("AssignByID", "1234", "TAKEN").Return((*map[string]string)(nil), order.NewNotFound("order_not_found", "Invalid Id"))
+		}, http.StatusNotFound},
+		{"assign an assigned order", http.MethodPatch, "/v1/orders/" + id, `{"status":"TAKEN"}`, func(ou *MockedOrderUsecase, wu *MockedWebhookUsecase, ku *MockedAPIKeyUsecase) {
+			ou.On("AssignByID", id, "TAKEN").Return((*map[string]string)(nil), order.NewConflict("order_already_assigned", "Order is already assigned"))
+		}, http.StatusConflict},
+		{"list webhooks", http.MethodGet, "/v1/webhooks", "", func(ou *MockedOrderUsecase, wu *MockedWebhookUsecase, ku *MockedAPIKeyUsecase) {
+			wu.On("FetchAll").Return([]models.Webhook(nil), nil)
+		}, http.StatusOK},
+		{"create a webhook", http.MethodPost, "/v1/webhooks", `{"url":"https://merchant.example.com/hooks"}`, func(ou *MockedOrderUsecase, wu *MockedWebhookUsecase, ku *MockedAPIKeyUsecase) {
+			created := webhookRes
+			created.Secret = "secret"
+			wu.On("Store", webhookReq).Return(&created, nil)
+		}, http.StatusCreated},
+		{"get a missing webhook", http.MethodGet, "/v1/webhooks/1234", "", func(ou *MockedOrderUsecase, wu *MockedWebhookUsecase, ku *MockedAPIKeyUsecase) {
+			wu.On("FetchByID", "1234").Return((*models.Webhook)(nil), order.NewNotFound("webhook_not_found", "Invalid Id"))
+		}, http.StatusNotFound},
+		{"update a webhook", http.MethodPut, "/v1/webhooks/" + id, `{"url":"https://merchant.example.com/hooks"}`, func(ou *MockedOrderUsecase, wu *MockedWebhookUsecase, ku *MockedAPIKeyUsecase) {
+			wu.On("UpdateByID", id, webhookReq).Return(&webhookRes, nil)
+		}, http.StatusOK},
+		{"delete a webhook", http.MethodDelete, "/v1/webhooks/" + id, "", func(ou *MockedOrderUsecase, wu *MockedWebhookUsecase, ku *MockedAPIKeyUsecase) {
+			wu.On("RemoveByID", id).Return(nil)
+		}, http.StatusNoContent},
+		{"list webhook deliveries", http.MethodGet, "/v1/webhooks/" + id + "/deliveries", "", func(ou *MockedOrderUsecase, wu *MockedWebhookUsecase, ku *MockedAPIKeyUsecase) {
+			wu.On("FetchDeliveries", id).Return([]models.WebhookDelivery{deliveryRes}, nil)
+		}, http.StatusOK},
+		{"test a webhook", http.MethodPost, "/v1/webhooks/" + id + "/test", "", func(ou *MockedOrderUsecase, wu *MockedWebhookUsecase, ku *MockedAPIKeyUsecase) {
+			wu.On("Test", id).Return(&deliveryRes, nil)
+		}, http.StatusOK},
+		{"list orders with an invalid API key", http.MethodGet, "/v1/orders", "", func(ou *MockedOrderUsecase, wu *MockedWebhookUsecase, ku *MockedAPIKeyUsecase) {
+			ku.On("Authenticate", testAPIKey).Return((*order.Identity)(nil), order.NewUnauthenticated("invalid_api_key", "Invalid API key"))
+		}, http.StatusUnauthorized},
+		{"create an order without the scope", http.MethodPost, "/v1/orders", `{"origin":["22.3193","114.1694"],"destination":["22.2783","114.1747"]}`, func(ou *MockedOrderUsecase, wu *MockedWebhookUsecase, ku *MockedAPIKeyUsecase) {
+			ku.On("Authenticate", testAPIKey).Return(&order.Identity{Subject: id, Scopes: []string{order.ScopeOrdersRead}}, nil)
+		}, http.StatusForbidden},
+		{"list API keys", http.MethodGet, "/v1/admin/keys", "", func(ou *MockedOrderUsecase, wu *MockedWebhookUsecase, ku *MockedAPIKeyUsecase) {
+			ku.On("FetchAll").Return([]models.APIKey{keyRes}, nil)
+		}, http.StatusOK},
+		{"issue an API key", http.MethodPost, "/v1/admin/keys", `{"name":"merchant","scopes":["orders:read"]}`, func(ou *MockedOrderUsecase, wu *MockedWebhookUsecase, ku *MockedAPIKeyUsecase) {
+			issued := keyRes
+			issued.Key = "osk_0123"
+			ku.On("Issue", &models.APIKeyRequest{Name: "merchant", Scopes: []string{order.ScopeOrdersRead}}).Return(&issued, nil)
+		}, http.StatusCreated},
+		{"issue an API key with an unknown scope", http.MethodPost, "/v1/admin/keys", `{"name":"merchant","scopes":["everything"]}`, func(ou *MockedOrderUsecase, wu *MockedWebhookUsecase, ku *MockedAPIKeyUsecase) {
+			ku.On("Issue", mock.Anything).Return((*models.APIKey)(nil), order.NewInvalidArgument("invalid_scope", "Unknown scope everything"))
+		}, http.StatusBadRequest},
+		{"revoke an API key", http.MethodDelete, "/v1/admin/keys/" + id, "", func(ou *MockedOrderUsecase, wu *MockedWebhookUsecase, ku *MockedAPIKeyUsecase) {
+			ku.On("RevokeByID", id).Return(nil)
+		}, http.StatusNoContent},
+	}
+
+	for _, c := range cases {
+		t.Run("Should match the spec when asked to "+c.name, func(t *testing.T) {
+			assert := assert.New(t)
+			ou := new(MockedOrderUsecase)
+			wu := new(MockedWebhookUsecase)
+			ku := new(MockedAPIKeyUsecase)
+			if c.setup != nil {
+				c.setup(ou, wu, ku)
+			}
+			ku.On("Authenticate", testAPIKey).Return(&order.Identity{Subject: "admin", Scopes: order.Scopes}, nil).Maybe()
+			req := httptest.NewRequest(c.method, c.path, strings.NewReader(c.body))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set(APIKeyHeader, testAPIKey)
+			rec := httptest.NewRecorder()
+
+			specRouter(ou, wu, ku).ServeHTTP(rec, req)
+			assert.Equal(c.status, rec.Code)
+
+			route, params, err := oaRouter.FindRoute(req)
+			require.NoError(t, err)
+			err = openapi3filter.ValidateResponse(context.Background(), &openapi3filter.ResponseValidationInput{
+				RequestValidationInput: &openapi3filter.RequestValidationInput{
+					Request:    req,
+					PathParams: params,
+					Route:      route,
+				},
+				Status:  rec.Code,
+				Header:  rec.Header(),
+				Body:    io.NopCloser(bytes.NewReader(rec.Body.Bytes())),
+				Options: &openapi3filter.Options{IncludeResponseStatus: true},
+			})
+			assert.NoError(err)
+			ou.AssertExpectations(t)
+			wu.AssertExpectations(t)
+			ku.AssertExpectations(t)
+		})
+	}
+}
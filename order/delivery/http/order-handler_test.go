@@ -2,6 +2,7 @@ package http
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"io/ioutil"
 	"net/http"
@@ -10,6 +11,7 @@ import (
 	"testing"
 
 	"github.com/karanbhomiagit/order-service/models"
+	"github.com/karanbhomiagit/order-service/order"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"gopkg.in/mgo.v2/bson"
@@ -19,21 +21,73 @@ type MockedOrderUsecase struct {
 	mock.Mock
 }
 
-func (ou *MockedOrderUsecase) AssignByID(id string, status string) (*map[string]string, error) {
+func (ou *MockedOrderUsecase) AssignByID(ctx context.Context, id string, status string) (*map[string]string, error) {
 	args := ou.Called(id, status)
 	return args.Get(0).(*map[string]string), args.Error(1)
 }
 
-func (ou *MockedOrderUsecase) FetchByRange(page int, limit int) ([]models.Order, error) {
+func (ou *MockedOrderUsecase) FetchByRange(ctx context.Context, page int, limit int) ([]models.Order, error) {
 	args := ou.Called(page, limit)
 	return args.Get(0).([]models.Order), args.Error(1)
 }
 
-func (ou *MockedOrderUsecase) Store(orderReq *models.OrderRequest) (*models.Order, error) {
+func (ou *MockedOrderUsecase) CancelByID(ctx context.Context, id string) (*models.Order, error) {
+	args := ou.Called(id)
+	return args.Get(0).(*models.Order), args.Error(1)
+}
+
+func (ou *MockedOrderUsecase) FetchByID(ctx context.Context, id string) (*models.Order, error) {
+	args := ou.Called(id)
+	return args.Get(0).(*models.Order), args.Error(1)
+}
+
+func (ou *MockedOrderUsecase) FetchByFilter(ctx context.Context, filter *models.OrderFilter, page int, limit int) ([]models.Order, error) {
+	args := ou.Called(filter, page, limit)
+	return args.Get(0).([]models.Order), args.Error(1)
+}
+
+func (ou *MockedOrderUsecase) FetchByFilterAfter(ctx context.Context, filter *models.OrderFilter, after string, limit int) ([]models.Order, error) {
+	args := ou.Called(filter, after, limit)
+	return args.Get(0).([]models.Order), args.Error(1)
+}
+
+func (ou *MockedOrderUsecase) Store(ctx context.Context, orderReq *models.OrderRequest) (*models.Order, error) {
 	args := ou.Called(orderReq)
 	return args.Get(0).(*models.Order), args.Error(1)
 }
 
+func (ou *MockedOrderUsecase) TransitionByID(ctx context.Context, id string, status string) (*models.Order, error) {
+	args := ou.Called(id, status)
+	return args.Get(0).(*models.Order), args.Error(1)
+}
+
+//contextRecordingUsecase records the context orders are stored with
+type contextRecordingUsecase struct {
+	MockedOrderUsecase
+	ctx context.Context
+}
+
+func (ou *contextRecordingUsecase) Store(ctx context.Context, orderReq *models.OrderRequest) (*models.Order, error) {
+	ou.ctx = ctx
+	return ou.MockedOrderUsecase.Store(ctx, orderReq)
+}
+
+//asAdmin is a middleware authenticating every request with every scope
+func asAdmin(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		identity := &order.Identity{Subject: "admin", Scopes: order.Scopes}
+		next.ServeHTTP(w, r.WithContext(order.NewContext(r.Context(), identity)))
+	})
+}
+
+//routed registers the routes of the handler on a new router authenticating every request as admin
+func routed(handler interface{ routes(*Router) }) *Router {
+	router := NewRouter()
+	router.Use(asAdmin)
+	handler.routes(router)
+	return router
+}
+
 /*
 	Actual test functions
 */
@@ -45,17 +99,36 @@ func TestOrderHandler(t *testing.T) {
 		testObj := new(MockedOrderUsecase)
 		handler := &OrderHttpHandler{
 			orderUsecase: testObj,
+			presenter:    OrderPresenterV1{},
 		}
 
 		req, err := http.NewRequest(http.MethodGet, "/orders/1234", strings.NewReader(""))
 		assert.NoError(t, err)
 		rec := httptest.NewRecorder()
 
-		handler.OrderHandler(rec, req)
+		routed(handler).ServeHTTP(rec, req)
 		assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+		assert.Equal(t, "PATCH", rec.Header().Get("Allow"))
 		body, _ := ioutil.ReadAll(rec.Body)
-		assert.Equal(t, `{"error":"Unsupported Request Method"}`, string(body))
-		assert.Equal(t, "application/json; charset=utf-8", rec.Header().Get("Content-Type"))
+		assert.Equal(t, `{"type":"about:blank","title":"Method Not Allowed","status":405,"detail":"Unsupported Request Method","code":"method_not_allowed"}`, string(body))
+		assert.Equal(t, "application/problem+json", rec.Header().Get("Content-Type"))
+		testObj.AssertExpectations(t)
+	})
+
+	t.Run("Should respond with 404 for PATCH /orders/id/extra", func(t *testing.T) {
+		testObj := new(MockedOrderUsecase)
+		handler := &OrderHttpHandler{
+			orderUsecase: testObj,
+			presenter:    OrderPresenterV1{},
+		}
+
+		req, err := http.NewRequest(http.MethodPatch, "/orders/abc/def", strings.NewReader(`{"status":"TAKEN"}`))
+		assert.NoError(t, err)
+		rec := httptest.NewRecorder()
+
+		routed(handler).ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+		assert.Equal(t, "application/problem+json", rec.Header().Get("Content-Type"))
 		testObj.AssertExpectations(t)
 	})
 
@@ -64,6 +137,7 @@ func TestOrderHandler(t *testing.T) {
 		testObj.On("AssignByID", "1234", "TAKEN").Return(&map[string]string{"status": "SUCCESS"}, nil)
 		handler := &OrderHttpHandler{
 			orderUsecase: testObj,
+			presenter:    OrderPresenterV1{},
 		}
 
 		var jsonStr = []byte(`{"status":"TAKEN"}`)
@@ -71,7 +145,7 @@ func TestOrderHandler(t *testing.T) {
 		assert.NoError(t, err)
 		rec := httptest.NewRecorder()
 
-		handler.OrderHandler(rec, req)
+		routed(handler).ServeHTTP(rec, req)
 
 		assert.Equal(t, http.StatusOK, rec.Code)
 		body, _ := ioutil.ReadAll(rec.Body)
@@ -80,11 +154,12 @@ func TestOrderHandler(t *testing.T) {
 		testObj.AssertExpectations(t)
 	})
 
-	t.Run("Should respond with 400 error for PATCH /orders/id when usecase layer returns error", func(t *testing.T) {
+	t.Run("Should respond with 409 error for PATCH /orders/id when usecase layer returns a conflict", func(t *testing.T) {
 		testObj := new(MockedOrderUsecase)
-		testObj.On("AssignByID", "1234", "TAKEN").Return(&map[string]string{}, errors.New("Order is already assigned"))
+		testObj.On("AssignByID", "1234", "TAKEN").Return(&map[string]string{}, order.NewConflict("order_already_assigned", "Order is already assigned"))
 		handler := &OrderHttpHandler{
 			orderUsecase: testObj,
+			presenter:    OrderPresenterV1{},
 		}
 
 		var jsonStr = []byte(`{"status":"TAKEN"}`)
@@ -92,20 +167,21 @@ func TestOrderHandler(t *testing.T) {
 		assert.NoError(t, err)
 		rec := httptest.NewRecorder()
 
-		handler.OrderHandler(rec, req)
+		routed(handler).ServeHTTP(rec, req)
 
-		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Equal(t, http.StatusConflict, rec.Code)
 		body, _ := ioutil.ReadAll(rec.Body)
-		assert.Equal(t, `{"error":"Order is already assigned"}`, string(body))
-		assert.Equal(t, "application/json; charset=utf-8", rec.Header().Get("Content-Type"))
+		assert.Equal(t, `{"type":"about:blank","title":"Conflict","status":409,"detail":"Order is already assigned","code":"order_already_assigned"}`, string(body))
+		assert.Equal(t, "application/problem+json", rec.Header().Get("Content-Type"))
 		testObj.AssertExpectations(t)
 	})
 
 	t.Run("Should respond with 404 error for PATCH /orders/id when usecase layer returns not found", func(t *testing.T) {
 		testObj := new(MockedOrderUsecase)
-		testObj.On("AssignByID", "1234", "TAKEN").Return(&map[string]string{}, errors.New("not found"))
+		testObj.On("AssignByID", "1234", "TAKEN").Return(&map[string]string{}, order.NewNotFound("order_not_found", "not found"))
 		handler := &OrderHttpHandler{
 			orderUsecase: testObj,
+			presenter:    OrderPresenterV1{},
 		}
 
 		var jsonStr = []byte(`{"status":"TAKEN"}`)
@@ -113,12 +189,12 @@ func TestOrderHandler(t *testing.T) {
 		assert.NoError(t, err)
 		rec := httptest.NewRecorder()
 
-		handler.OrderHandler(rec, req)
+		routed(handler).ServeHTTP(rec, req)
 
 		assert.Equal(t, http.StatusNotFound, rec.Code)
 		body, _ := ioutil.ReadAll(rec.Body)
-		assert.Equal(t, `{"error":"not found"}`, string(body))
-		assert.Equal(t, "application/json; charset=utf-8", rec.Header().Get("Content-Type"))
+		assert.Equal(t, `{"type":"about:blank","title":"Not Found","status":404,"detail":"not found","code":"order_not_found"}`, string(body))
+		assert.Equal(t, "application/problem+json", rec.Header().Get("Content-Type"))
 		testObj.AssertExpectations(t)
 	})
 
@@ -131,17 +207,19 @@ func TestOrdersHandler(t *testing.T) {
 		testObj := new(MockedOrderUsecase)
 		handler := &OrderHttpHandler{
 			orderUsecase: testObj,
+			presenter:    OrderPresenterV1{},
 		}
 
 		req, err := http.NewRequest(http.MethodDelete, "/orders", strings.NewReader(""))
 		assert.NoError(t, err)
 		rec := httptest.NewRecorder()
 
-		handler.OrdersHandler(rec, req)
+		routed(handler).ServeHTTP(rec, req)
 		assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+		assert.Equal(t, "GET, POST", rec.Header().Get("Allow"))
 		body, _ := ioutil.ReadAll(rec.Body)
-		assert.Equal(t, `{"error":"Unsupported Request Method"}`, string(body))
-		assert.Equal(t, "application/json; charset=utf-8", rec.Header().Get("Content-Type"))
+		assert.Equal(t, `{"type":"about:blank","title":"Method Not Allowed","status":405,"detail":"Unsupported Request Method","code":"method_not_allowed"}`, string(body))
+		assert.Equal(t, "application/problem+json", rec.Header().Get("Content-Type"))
 		testObj.AssertExpectations(t)
 	})
 
@@ -149,6 +227,7 @@ func TestOrdersHandler(t *testing.T) {
 		testObj := new(MockedOrderUsecase)
 		handler := &OrderHttpHandler{
 			orderUsecase: testObj,
+			presenter:    OrderPresenterV1{},
 		}
 
 		var jsonStr = []byte(`{"origin": "a"}`)
@@ -156,12 +235,12 @@ func TestOrdersHandler(t *testing.T) {
 		assert.NoError(t, err)
 		rec := httptest.NewRecorder()
 
-		handler.OrdersHandler(rec, req)
+		routed(handler).ServeHTTP(rec, req)
 
 		assert.Equal(t, http.StatusBadRequest, rec.Code)
 		body, _ := ioutil.ReadAll(rec.Body)
-		assert.Equal(t, `{"error":"Invalid request payload"}`, string(body))
-		assert.Equal(t, "application/json; charset=utf-8", rec.Header().Get("Content-Type"))
+		assert.Equal(t, `{"type":"about:blank","title":"Bad Request","status":400,"detail":"Invalid request payload","code":"invalid_payload"}`, string(body))
+		assert.Equal(t, "application/problem+json", rec.Header().Get("Content-Type"))
 		testObj.AssertExpectations(t)
 	})
 
@@ -179,6 +258,7 @@ func TestOrdersHandler(t *testing.T) {
 		testObj.On("Store", &testOrderReq).Return(&testOrderRes, nil)
 		handler := &OrderHttpHandler{
 			orderUsecase: testObj,
+			presenter:    OrderPresenterV1{},
 		}
 
 		var jsonStr = []byte(`{"origin":["1", "2"], "destination":["3","4"]}`)
@@ -186,7 +266,7 @@ func TestOrdersHandler(t *testing.T) {
 		assert.NoError(t, err)
 		rec := httptest.NewRecorder()
 
-		handler.OrdersHandler(rec, req)
+		routed(handler).ServeHTTP(rec, req)
 
 		assert.Equal(t, http.StatusOK, rec.Code)
 		body, _ := ioutil.ReadAll(rec.Body)
@@ -195,16 +275,17 @@ func TestOrdersHandler(t *testing.T) {
 		testObj.AssertExpectations(t)
 	})
 
-	t.Run("Should respond with 400 for POST /orders if usecase layer returns error", func(t *testing.T) {
+	t.Run("Should respond with 503 for POST /orders if the distance provider is unavailable", func(t *testing.T) {
 		testObj := new(MockedOrderUsecase)
 		testOrderReq := models.OrderRequest{
 			Origin:      []string{"1", "2"},
 			Destination: []string{"3", "4"},
 		}
 
-		testObj.On("Store", &testOrderReq).Return(&models.Order{}, errors.New("Unable to fetch distance from Google APIs"))
+		testObj.On("Store", &testOrderReq).Return(&models.Order{}, order.NewUnavailable("distance_unavailable", "Unable to fetch distance from Google APIs", errors.New("connection refused")))
 		handler := &OrderHttpHandler{
 			orderUsecase: testObj,
+			presenter:    OrderPresenterV1{},
 		}
 
 		var jsonStr = []byte(`{"origin":["1", "2"], "destination":["3","4"]}`)
@@ -212,12 +293,43 @@ func TestOrdersHandler(t *testing.T) {
 		assert.NoError(t, err)
 		rec := httptest.NewRecorder()
 
-		handler.OrdersHandler(rec, req)
+		routed(handler).ServeHTTP(rec, req)
 
-		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
 		body, _ := ioutil.ReadAll(rec.Body)
-		assert.Equal(t, `{"error":"Unable to fetch distance from Google APIs"}`, string(body))
-		assert.Equal(t, "application/json; charset=utf-8", rec.Header().Get("Content-Type"))
+		assert.Equal(t, `{"type":"about:blank","title":"Service Unavailable","status":503,"detail":"Unable to fetch distance from Google APIs","code":"distance_unavailable"}`, string(body))
+		assert.Equal(t, "application/problem+json", rec.Header().Get("Content-Type"))
+		testObj.AssertExpectations(t)
+	})
+
+	t.Run("Should pass the context of the request to the usecase for POST /orders", func(t *testing.T) {
+		testObj := new(contextRecordingUsecase)
+		testOrderReq := models.OrderRequest{
+			Origin:      []string{"1", "2"},
+			Destination: []string{"3", "4"},
+		}
+
+		testObj.On("Store", &testOrderReq).Return(&models.Order{}, order.NewCanceled(context.Canceled))
+		handler := &OrderHttpHandler{
+			orderUsecase: testObj,
+			presenter:    OrderPresenterV1{},
+		}
+
+		var jsonStr = []byte(`{"origin":["1", "2"], "destination":["3","4"]}`)
+		ctx, cancel := context.WithCancel(context.Background())
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, "/orders", bytes.NewBuffer(jsonStr))
+		assert.NoError(t, err)
+		rec := httptest.NewRecorder()
+
+		routed(handler).ServeHTTP(rec, req)
+		cancel()
+
+		if assert.NotNil(t, testObj.ctx) {
+			assert.ErrorIs(t, testObj.ctx.Err(), context.Canceled)
+		}
+		assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+		body, _ := ioutil.ReadAll(rec.Body)
+		assert.Equal(t, `{"type":"about:blank","title":"Service Unavailable","status":503,"detail":"The request was cancelled","code":"request_cancelled"}`, string(body))
 		testObj.AssertExpectations(t)
 	})
 
@@ -237,13 +349,14 @@ func TestOrdersHandler(t *testing.T) {
 		testObj.On("FetchByRange", 1, 10).Return([]models.Order{testOrder1, testOrder2}, nil)
 		handler := &OrderHttpHandler{
 			orderUsecase: testObj,
+			presenter:    OrderPresenterV1{},
 		}
 
 		req, err := http.NewRequest(http.MethodGet, "/orders", strings.NewReader(""))
 		assert.NoError(t, err)
 		rec := httptest.NewRecorder()
 
-		handler.OrdersHandler(rec, req)
+		routed(handler).ServeHTTP(rec, req)
 		assert.Equal(t, http.StatusOK, rec.Code)
 		body, _ := ioutil.ReadAll(rec.Body)
 		assert.Equal(t, `[{"id":"3132333435","distance":12345,"status":"UNASSIGNED"},{"id":"3132333436","distance":52345,"status":"TAKEN"}]`, string(body))
@@ -255,17 +368,18 @@ func TestOrdersHandler(t *testing.T) {
 		testObj := new(MockedOrderUsecase)
 		handler := &OrderHttpHandler{
 			orderUsecase: testObj,
+			presenter:    OrderPresenterV1{},
 		}
 
 		req, err := http.NewRequest(http.MethodGet, "/orders?page=x", strings.NewReader(""))
 		assert.NoError(t, err)
 		rec := httptest.NewRecorder()
 
-		handler.OrdersHandler(rec, req)
+		routed(handler).ServeHTTP(rec, req)
 		assert.Equal(t, http.StatusBadRequest, rec.Code)
 		body, _ := ioutil.ReadAll(rec.Body)
-		assert.Equal(t, `{"error":"page parameter should be a number"}`, string(body))
-		assert.Equal(t, "application/json; charset=utf-8", rec.Header().Get("Content-Type"))
+		assert.Equal(t, `{"type":"about:blank","title":"Bad Request","status":400,"detail":"page parameter should be a number","code":"invalid_page"}`, string(body))
+		assert.Equal(t, "application/problem+json", rec.Header().Get("Content-Type"))
 		testObj.AssertExpectations(t)
 	})
 
@@ -273,37 +387,63 @@ func TestOrdersHandler(t *testing.T) {
 		testObj := new(MockedOrderUsecase)
 		handler := &OrderHttpHandler{
 			orderUsecase: testObj,
+			presenter:    OrderPresenterV1{},
 		}
 
 		req, err := http.NewRequest(http.MethodGet, "/orders?page=1&limit=x", strings.NewReader(""))
 		assert.NoError(t, err)
 		rec := httptest.NewRecorder()
 
-		handler.OrdersHandler(rec, req)
+		routed(handler).ServeHTTP(rec, req)
 		assert.Equal(t, http.StatusBadRequest, rec.Code)
 		body, _ := ioutil.ReadAll(rec.Body)
-		assert.Equal(t, `{"error":"limit parameter should be a number"}`, string(body))
-		assert.Equal(t, "application/json; charset=utf-8", rec.Header().Get("Content-Type"))
+		assert.Equal(t, `{"type":"about:blank","title":"Bad Request","status":400,"detail":"limit parameter should be a number","code":"invalid_limit"}`, string(body))
+		assert.Equal(t, "application/problem+json", rec.Header().Get("Content-Type"))
 		testObj.AssertExpectations(t)
 	})
 
-	t.Run("Should return error for GET /orders if usecase layer returns error", func(t *testing.T) {
+	t.Run("Should return 503 for GET /orders if the database is unavailable", func(t *testing.T) {
 		testObj := new(MockedOrderUsecase)
 
-		testObj.On("FetchByRange", 2, 8).Return([]models.Order{}, errors.New("connection lost"))
+		testObj.On("FetchByRange", 2, 8).Return([]models.Order{}, order.NewUnavailable("database_unavailable", "Database is unavailable", errors.New("connection lost")))
 		handler := &OrderHttpHandler{
 			orderUsecase: testObj,
+			presenter:    OrderPresenterV1{},
 		}
 
 		req, err := http.NewRequest(http.MethodGet, "/orders?page=2&limit=8", strings.NewReader(""))
 		assert.NoError(t, err)
 		rec := httptest.NewRecorder()
 
-		handler.OrdersHandler(rec, req)
-		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		routed(handler).ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
 		body, _ := ioutil.ReadAll(rec.Body)
-		assert.Equal(t, `{"error":"connection lost"}`, string(body))
-		assert.Equal(t, "application/json; charset=utf-8", rec.Header().Get("Content-Type"))
+		assert.Equal(t, `{"type":"about:blank","title":"Service Unavailable","status":503,"detail":"Database is unavailable","code":"database_unavailable"}`, string(body))
+		assert.Equal(t, "application/problem+json", rec.Header().Get("Content-Type"))
 		testObj.AssertExpectations(t)
 	})
 }
+
+func TestRespondWithError(t *testing.T) {
+
+	t.Run("Should hide the details of unexpected errors", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+
+		respondWithError(rec, httptest.NewRequest(http.MethodGet, "/v1/orders", nil), errors.New("socket closed at 10.0.0.12:27017"))
+
+		assert.Equal(t, http.StatusInternalServerError, rec.Code)
+		body, _ := ioutil.ReadAll(rec.Body)
+		assert.Equal(t, `{"type":"about:blank","title":"Internal Server Error","status":500,"detail":"Internal error","code":"internal"}`, string(body))
+		assert.Equal(t, "application/problem+json", rec.Header().Get("Content-Type"))
+	})
+
+	t.Run("Should map invalid arguments to 400", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+
+		respondWithError(rec, httptest.NewRequest(http.MethodGet, "/v1/orders", nil), order.NewInvalidArgument("invalid_status", "Please provide requested status as TAKEN"))
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		body, _ := ioutil.ReadAll(rec.Body)
+		assert.Equal(t, `{"type":"about:blank","title":"Bad Request","status":400,"detail":"Please provide requested status as TAKEN","code":"invalid_status"}`, string(body))
+	})
+}
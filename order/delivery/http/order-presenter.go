@@ -0,0 +1,36 @@
+package http
+
+import "github.com/karanbhomiagit/order-service/models"
+
+//OrderPresenter shapes the orders returned by one version of the API, so versions with
+//different representations can share the same usecase
+type OrderPresenter interface {
+	Order(o models.Order) interface{}
+	Orders(o []models.Order) interface{}
+}
+
+//OrderPresenterV1 presents orders as in version 1 of the API
+type OrderPresenterV1 struct{}
+
+//OrderV1 is the representation of an order in version 1 of the API
+type OrderV1 struct {
+	ID       string `json:"id"`
+	Distance int    `json:"distance"`
+	Status   string `json:"status"`
+}
+
+func (OrderPresenterV1) Order(o models.Order) interface{} {
+	return OrderV1{
+		ID:       o.ID.Hex(),
+		Distance: o.Distance,
+		Status:   o.Status,
+	}
+}
+
+func (p OrderPresenterV1) Orders(o []models.Order) interface{} {
+	res := make([]interface{}, 0, len(o))
+	for _, order := range o {
+		res = append(res, p.Order(order))
+	}
+	return res
+}
@@ -0,0 +1,88 @@
+package http
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/karanbhomiagit/order-service/models"
+	"github.com/karanbhomiagit/order-service/order/logging"
+)
+
+const (
+	HeartbeatInterval = 15 * time.Second
+)
+
+//streamOrders pushes order events to the client as Server-Sent Events until it disconnects
+func (h *OrderHttpHandler) streamOrders(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok || h.orderFeed == nil {
+		respondWithProblem(w, r, http.StatusInternalServerError, "internal", "Streaming is not supported")
+		return
+	}
+	statuses := statusFilter(r)
+	//The stream outlives the write timeout of the server, the heartbeats detect clients which went away
+	http.NewResponseController(w).SetWriteDeadline(time.Time{})
+
+	//Subscribe before writing anything so no event is missed
+	replay, events, unsubscribe := h.orderFeed.Subscribe(r.Header.Get("Last-Event-ID"))
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	//Stop proxies such as nginx from buffering the stream
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	for _, event := range replay {
+		writeEvent(w, r, event, statuses)
+	}
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(h.heartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case event, ok := <-events:
+			//The feed dropped this subscriber, the client reconnects with Last-Event-ID
+			if !ok {
+				return
+			}
+			writeEvent(w, r, event, statuses)
+		case <-heartbeat.C:
+			//Comments are ignored by clients but keep idle connections open
+			fmt.Fprint(w, ": heartbeat\n\n")
+		}
+		flusher.Flush()
+	}
+}
+
+//writeEvent writes the event in the Server-Sent Events format if its status passes the filter
+func writeEvent(w http.ResponseWriter, r *http.Request, event models.OrderEvent, statuses map[string]bool) {
+	if len(statuses) > 0 && !statuses[event.Data.Status] {
+		return
+	}
+	b, err := json.Marshal(event)
+	if err != nil {
+		logging.FromContext(r.Context()).Error("Unable to marshal the event", "event_id", event.ID, "error", err)
+		return
+	}
+	fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", event.ID, event.Type, b)
+}
+
+//statusFilter reads the statuses to stream from ?status=A,B or repeated status parameters
+func statusFilter(r *http.Request) map[string]bool {
+	statuses := make(map[string]bool)
+	for _, param := range r.URL.Query()["status"] {
+		for _, status := range strings.Split(param, ",") {
+			if status != "" {
+				statuses[strings.ToUpper(status)] = true
+			}
+		}
+	}
+	return statuses
+}
@@ -0,0 +1,139 @@
+package http
+
+import (
+	"bufio"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/karanbhomiagit/order-service/models"
+	"github.com/karanbhomiagit/order-service/order/usecase"
+	"github.com/stretchr/testify/assert"
+)
+
+func streamEvent(id string, eventType string, status string) models.OrderEvent {
+	return models.OrderEvent{
+		ID:         id,
+		Type:       eventType,
+		Version:    1,
+		OccurredAt: time.Date(2019, 1, 2, 12, 0, 0, 0, time.UTC),
+		Data:       models.OrderEventData{OrderID: "5c2b2aaf4530558539f91859", Status: status},
+	}
+}
+
+//openStream connects to the stream and returns a reader over its body
+func openStream(t *testing.T, url string, lastEventID string) (*http.Response, *bufio.Reader) {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	assert.NoError(t, err)
+	if lastEventID != "" {
+		req.Header.Set("Last-Event-ID", lastEventID)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	return resp, bufio.NewReader(resp.Body)
+}
+
+//readFrame reads the lines of the next Server-Sent Events frame
+func readFrame(t *testing.T, reader *bufio.Reader) string {
+	var frame []string
+	for {
+		line, err := reader.ReadString('\n')
+		if !assert.NoError(t, err) || line == "\n" {
+			return strings.Join(frame, "")
+		}
+		frame = append(frame, line)
+	}
+}
+
+/*
+	Actual test functions
+*/
+
+func TestStreamOrders(t *testing.T) {
+
+	t.Run("Should stream events as they are appended", func(t *testing.T) {
+		feed := usecase.NewOrderFeed(10)
+		handler := &OrderHttpHandler{orderFeed: feed, heartbeat: time.Minute}
+		server := httptest.NewServer(routed(handler))
+		defer server.Close()
+
+		resp, reader := openStream(t, server.URL+"/orders/stream", "")
+		defer resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+		feed.Append(streamEvent("e1", "order.created", "UNASSIGNED"))
+
+		assert.Equal(t, "id: e1\nevent: order.created\n"+
+			`data: {"id":"e1","type":"order.created","version":1,"occurredAt":"2019-01-02T12:00:00Z","data":{"orderId":"5c2b2aaf4530558539f91859","status":"UNASSIGNED","distance":0}}`+"\n",
+			readFrame(t, reader))
+	})
+
+	t.Run("Should keep streaming past the write timeout of the server", func(t *testing.T) {
+		feed := usecase.NewOrderFeed(10)
+		handler := &OrderHttpHandler{orderFeed: feed, heartbeat: time.Minute}
+		server := httptest.NewUnstartedServer(Instrument(NewRouter())(routed(handler)))
+		server.Config.WriteTimeout = 50 * time.Millisecond
+		server.Start()
+		defer server.Close()
+
+		resp, reader := openStream(t, server.URL+"/orders/stream", "")
+		defer resp.Body.Close()
+		time.Sleep(100 * time.Millisecond)
+		feed.Append(streamEvent("e1", "order.created", "UNASSIGNED"))
+		assert.True(t, strings.HasPrefix(readFrame(t, reader), "id: e1\n"))
+	})
+
+	t.Run("Should end the stream once the feed is closed", func(t *testing.T) {
+		feed := usecase.NewOrderFeed(10)
+		handler := &OrderHttpHandler{orderFeed: feed, heartbeat: time.Minute}
+		server := httptest.NewServer(routed(handler))
+		defer server.Close()
+
+		resp, reader := openStream(t, server.URL+"/orders/stream", "")
+		defer resp.Body.Close()
+		feed.Close()
+		_, err := reader.ReadString('\n')
+		assert.Equal(t, io.EOF, err)
+	})
+
+	t.Run("Should resume after Last-Event-ID and apply status filters", func(t *testing.T) {
+		feed := usecase.NewOrderFeed(10)
+		feed.Append(streamEvent("e1", "order.created", "UNASSIGNED"))
+		feed.Append(streamEvent("e2", "order.created", "UNASSIGNED"))
+		feed.Append(streamEvent("e3", "order.assigned", "TAKEN"))
+		handler := &OrderHttpHandler{orderFeed: feed, heartbeat: time.Minute}
+		server := httptest.NewServer(routed(handler))
+		defer server.Close()
+
+		resp, reader := openStream(t, server.URL+"/orders/stream?status=taken,EXPIRED", "e1")
+		defer resp.Body.Close()
+		assert.True(t, strings.HasPrefix(readFrame(t, reader), "id: e3\n"))
+		feed.Append(streamEvent("e4", "order.created", "UNASSIGNED"))
+		feed.Append(streamEvent("e5", "order.status_changed", "EXPIRED"))
+		assert.True(t, strings.HasPrefix(readFrame(t, reader), "id: e5\n"))
+	})
+
+	t.Run("Should send heartbeat comments", func(t *testing.T) {
+		feed := usecase.NewOrderFeed(10)
+		handler := &OrderHttpHandler{orderFeed: feed, heartbeat: 10 * time.Millisecond}
+		server := httptest.NewServer(routed(handler))
+		defer server.Close()
+
+		resp, reader := openStream(t, server.URL+"/orders/stream", "")
+		defer resp.Body.Close()
+		assert.Equal(t, ": heartbeat\n", readFrame(t, reader))
+	})
+
+	t.Run("Should respond with 405 for POST /orders/stream", func(t *testing.T) {
+		handler := &OrderHttpHandler{orderFeed: usecase.NewOrderFeed(10), heartbeat: time.Minute}
+		req, err := http.NewRequest(http.MethodPost, "/orders/stream", strings.NewReader(""))
+		assert.NoError(t, err)
+		rec := httptest.NewRecorder()
+
+		routed(handler).ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+	})
+}
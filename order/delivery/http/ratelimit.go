@@ -0,0 +1,87 @@
+package http
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/karanbhomiagit/order-service/models"
+	"github.com/karanbhomiagit/order-service/order"
+	"github.com/karanbhomiagit/order-service/order/logging"
+)
+
+//RateLimit returns a middleware limiting the requests of each client with token buckets kept in the store.
+//Clients are the authenticated callers, or else the IP addresses. Requests to the routes of a rule take
+//tokens from a bucket of their own, any other request takes them from the bucket of the fallback limit.
+//Requests are let through when the store fails, rather than taking the API down with it.
+func RateLimit(store order.RateLimitStore, fallback models.RateLimit, rules []models.RateLimitRule) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			name, limit := matchRateLimit(r, fallback, rules)
+			if limit.Requests <= 0 || limit.Period <= 0 {
+				next.ServeHTTP(w, r)
+				return
+			}
+			status, err := store.Take(clientKey(r)+" "+name, limit, time.Now())
+			if err != nil {
+				logging.FromContext(r.Context()).Error("Unable to take a token, letting the request through", "error", err)
+				next.ServeHTTP(w, r)
+				return
+			}
+			//Headers of the RateLimit header fields draft of the IETF
+			w.Header().Set("RateLimit-Policy", fmt.Sprintf("%d;w=%d", limit.Requests, seconds(limit.Period)))
+			w.Header().Set("RateLimit-Limit", strconv.Itoa(limit.Requests))
+			w.Header().Set("RateLimit-Remaining", strconv.Itoa(status.Remaining))
+			w.Header().Set("RateLimit-Reset", strconv.Itoa(seconds(status.Reset)))
+			if !status.Allowed {
+				retryAfter := seconds(status.RetryAfter)
+				w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+				respondWithProblem(w, r, http.StatusTooManyRequests, "rate_limited", fmt.Sprintf("Too many requests, retry in %d seconds", retryAfter))
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+//matchRateLimit returns the name and limit of the rule matching the request, or the fallback limit
+func matchRateLimit(r *http.Request, fallback models.RateLimit, rules []models.RateLimitRule) (string, models.RateLimit) {
+	segments := splitPath(r.URL.Path)
+	if len(segments) > 0 && "/"+segments[0] == V1 {
+		segments = segments[1:]
+	}
+	for _, rule := range rules {
+		if rule.Method != r.Method {
+			continue
+		}
+		if _, _, ok := (&route{segments: splitPath(rule.Pattern)}).match(segments); ok {
+			return rule.Method + " " + rule.Pattern, rule.Limit
+		}
+	}
+	return "*", fallback
+}
+
+//clientKey identifies the client making the request
+func clientKey(r *http.Request) string {
+	if identity, ok := order.IdentityFrom(r.Context()); ok {
+		return "subject:" + identity.Subject
+	}
+	return "ip:" + clientIP(r)
+}
+
+//clientIP returns the IP address the request comes from
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+//seconds rounds the duration up to whole seconds
+func seconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
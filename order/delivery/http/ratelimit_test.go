@@ -0,0 +1,144 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/karanbhomiagit/order-service/models"
+	"github.com/karanbhomiagit/order-service/order"
+	"github.com/karanbhomiagit/order-service/order/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockedRateLimitStore struct {
+	mock.Mock
+}
+
+func (s *MockedRateLimitStore) Take(key string, limit models.RateLimit, now time.Time) (*models.RateLimitStatus, error) {
+	args := s.Called(key, limit)
+	return args.Get(0).(*models.RateLimitStatus), args.Error(1)
+}
+
+func (s *MockedRateLimitStore) Check(key string, limit models.RateLimit, now time.Time) (*models.RateLimitStatus, error) {
+	args := s.Called(key, limit)
+	return args.Get(0).(*models.RateLimitStatus), args.Error(1)
+}
+
+//limited returns a handler responding with 204, limited to 2 requests per minute with 1 per minute for POST /orders
+func limited(store order.RateLimitStore) http.Handler {
+	rules := []models.RateLimitRule{{Method: http.MethodPost, Pattern: "/orders", Limit: models.RateLimit{Requests: 1, Period: time.Minute}}}
+	return RateLimit(store, models.RateLimit{Requests: 2, Period: time.Minute}, rules)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+}
+
+func requestFrom(method string, target string, remoteAddr string) *http.Request {
+	req := httptest.NewRequest(method, target, nil)
+	req.RemoteAddr = remoteAddr
+	return req
+}
+
+/*
+	Actual test functions
+*/
+
+func TestRateLimit(t *testing.T) {
+
+	t.Run("Should respond with 429 and Retry-After once the bucket is empty", func(t *testing.T) {
+		assert := assert.New(t)
+		handler := limited(repository.NewMemoryRateLimitStore())
+
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, requestFrom(http.MethodGet, "/v1/orders", "10.0.0.1:1234"))
+		assert.Equal(http.StatusNoContent, rec.Code)
+		assert.Equal("2", rec.Header().Get("RateLimit-Limit"))
+		assert.Equal("1", rec.Header().Get("RateLimit-Remaining"))
+		assert.Equal("30", rec.Header().Get("RateLimit-Reset"))
+		assert.Equal("2;w=60", rec.Header().Get("RateLimit-Policy"))
+
+		rec = httptest.NewRecorder()
+		handler.ServeHTTP(rec, requestFrom(http.MethodGet, "/v1/orders", "10.0.0.1:1234"))
+		assert.Equal(http.StatusNoContent, rec.Code)
+		assert.Equal("0", rec.Header().Get("RateLimit-Remaining"))
+
+		rec = httptest.NewRecorder()
+		handler.ServeHTTP(rec, requestFrom(http.MethodGet, "/v1/orders", "10.0.0.1:1234"))
+		assert.Equal(http.StatusTooManyRequests, rec.Code)
+		assert.Equal("30", rec.Header().Get("Retry-After"))
+		assert.Equal("0", rec.Header().Get("RateLimit-Remaining"))
+		assert.Equal("application/problem+json", rec.Header().Get("Content-Type"))
+		assert.Contains(rec.Body.String(), `"code":"rate_limited"`)
+	})
+
+	t.Run("Should limit the routes of a rule with a bucket of their own, shared by the versions", func(t *testing.T) {
+		assert := assert.New(t)
+		handler := limited(repository.NewMemoryRateLimitStore())
+
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, requestFrom(http.MethodPost, "/v1/orders", "10.0.0.1:1234"))
+		assert.Equal(http.StatusNoContent, rec.Code)
+		assert.Equal("1", rec.Header().Get("RateLimit-Limit"))
+
+		rec = httptest.NewRecorder()
+		handler.ServeHTTP(rec, requestFrom(http.MethodPost, "/orders", "10.0.0.1:1234"))
+		assert.Equal(http.StatusTooManyRequests, rec.Code)
+		assert.Equal("60", rec.Header().Get("Retry-After"))
+
+		rec = httptest.NewRecorder()
+		handler.ServeHTTP(rec, requestFrom(http.MethodGet, "/v1/orders", "10.0.0.1:1234"))
+		assert.Equal(http.StatusNoContent, rec.Code)
+		assert.Equal("2", rec.Header().Get("RateLimit-Limit"))
+	})
+
+	t.Run("Should keep a bucket per client IP and per authenticated caller", func(t *testing.T) {
+		assert := assert.New(t)
+		handler := limited(repository.NewMemoryRateLimitStore())
+
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, requestFrom(http.MethodPost, "/orders", "10.0.0.1:1234"))
+		assert.Equal(http.StatusNoContent, rec.Code)
+
+		rec = httptest.NewRecorder()
+		handler.ServeHTTP(rec, requestFrom(http.MethodPost, "/orders", "10.0.0.2:1234"))
+		assert.Equal(http.StatusNoContent, rec.Code)
+
+		req := requestFrom(http.MethodPost, "/orders", "10.0.0.1:1234")
+		req = req.WithContext(order.NewContext(req.Context(), &order.Identity{Subject: "merchant"}))
+		rec = httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		assert.Equal(http.StatusNoContent, rec.Code)
+	})
+
+	t.Run("Should let the requests through without taking tokens when the limit is off", func(t *testing.T) {
+		assert := assert.New(t)
+		testObj := new(MockedRateLimitStore)
+		rules := []models.RateLimitRule{{Method: http.MethodPost, Pattern: "/orders"}}
+		handler := RateLimit(testObj, models.RateLimit{}, rules)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNoContent)
+		}))
+
+		for _, method := range []string{http.MethodGet, http.MethodPost} {
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, requestFrom(method, "/v1/orders", "10.0.0.1:1234"))
+			assert.Equal(http.StatusNoContent, rec.Code)
+			assert.Empty(rec.Header().Get("RateLimit-Limit"))
+		}
+		testObj.AssertNotCalled(t, "Take", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Should let requests through when the store fails", func(t *testing.T) {
+		assert := assert.New(t)
+		testObj := new(MockedRateLimitStore)
+		testObj.On("Take", "ip:10.0.0.1 POST /orders", models.RateLimit{Requests: 1, Period: time.Minute}).Return((*models.RateLimitStatus)(nil), errors.New("connection lost"))
+
+		rec := httptest.NewRecorder()
+		limited(testObj).ServeHTTP(rec, requestFrom(http.MethodPost, "/v1/orders", "10.0.0.1:1234"))
+		assert.Equal(http.StatusNoContent, rec.Code)
+		assert.Empty(rec.Header().Get("RateLimit-Limit"))
+		testObj.AssertExpectations(t)
+	})
+}
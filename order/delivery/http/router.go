@@ -0,0 +1,176 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+)
+
+//Middleware wraps a handler with behaviour shared by every route
+type Middleware func(http.Handler) http.Handler
+
+//Router dispatches requests by method and path. Patterns are made of literal segments and
+//parameters such as "/orders/:id"; literal segments take precedence over parameters.
+type Router struct {
+	routes      []*route
+	middlewares []Middleware
+	//Set for groups, which register their routes on the parent under the prefix
+	parent *Router
+	prefix string
+}
+
+type route struct {
+	segments []string
+	handlers map[string]http.Handler
+}
+
+type paramsKey struct{}
+
+func NewRouter() *Router {
+	return &Router{}
+}
+
+//Group returns a router registering its routes on rt under the prefix. The middlewares of a group
+//only wrap its own routes, inside the middlewares of rt.
+func (rt *Router) Group(prefix string, middlewares ...Middleware) *Router {
+	return &Router{
+		middlewares: middlewares,
+		parent:      rt,
+		prefix:      prefix,
+	}
+}
+
+//Handle registers the handler for requests with the method and a path matching the pattern
+func (rt *Router) Handle(method string, pattern string, handler http.Handler) {
+	if rt.parent != nil {
+		rt.parent.Handle(method, rt.prefix+"/"+pattern, rt.wrap(handler))
+		return
+	}
+	segments := splitPath(pattern)
+	for _, r := range rt.routes {
+		if strings.Join(r.segments, "/") == strings.Join(segments, "/") {
+			r.handlers[method] = handler
+			return
+		}
+	}
+	rt.routes = append(rt.routes, &route{
+		segments: segments,
+		handlers: map[string]http.Handler{method: handler},
+	})
+}
+
+//HandleFunc registers the handler function for requests with the method and a path matching the pattern
+func (rt *Router) HandleFunc(method string, pattern string, handler func(http.ResponseWriter, *http.Request)) {
+	rt.Handle(method, pattern, http.HandlerFunc(handler))
+}
+
+//Use appends middlewares to the chain wrapping every request, the first one being the outermost
+func (rt *Router) Use(middlewares ...Middleware) {
+	rt.middlewares = append(rt.middlewares, middlewares...)
+}
+
+//ServeHTTP runs the request through the middleware chain and the handler of the matching route
+func (rt *Router) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if rt.parent != nil {
+		rt.parent.ServeHTTP(w, r)
+		return
+	}
+	rt.wrap(http.HandlerFunc(rt.dispatch)).ServeHTTP(w, r)
+}
+
+//wrap returns a handler running the middlewares of rt, as they are when the request is served
+func (rt *Router) wrap(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var h http.Handler = handler
+		for i := len(rt.middlewares) - 1; i >= 0; i-- {
+			h = rt.middlewares[i](h)
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+//Route returns the pattern of the route matching the path of the request, or "" when none does
+func (rt *Router) Route(r *http.Request) string {
+	if rt.parent != nil {
+		return rt.parent.Route(r)
+	}
+	matched, _ := rt.match(splitPath(r.URL.Path))
+	if matched == nil {
+		return ""
+	}
+	return "/" + strings.Join(matched.segments, "/")
+}
+
+//Param returns the value of a path parameter of the matched route
+func Param(r *http.Request, name string) string {
+	params, _ := r.Context().Value(paramsKey{}).(map[string]string)
+	return params[name]
+}
+
+func (rt *Router) dispatch(w http.ResponseWriter, r *http.Request) {
+	matched, params := rt.match(splitPath(r.URL.Path))
+	if matched == nil {
+		respondWithProblem(w, r, http.StatusNotFound, "not_found", "not found")
+		return
+	}
+	handler, ok := matched.handlers[r.Method]
+	if !ok {
+		w.Header().Set("Allow", strings.Join(matched.methods(), ", "))
+		respondWithProblem(w, r, http.StatusMethodNotAllowed, "method_not_allowed", "Unsupported Request Method")
+		return
+	}
+	handler.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), paramsKey{}, params)))
+}
+
+//match finds the most specific route for the path segments along with its parameters
+func (rt *Router) match(segments []string) (*route, map[string]string) {
+	var best *route
+	var bestParams map[string]string
+	bestLiterals := -1
+	for _, r := range rt.routes {
+		params, literals, ok := r.match(segments)
+		if ok && literals > bestLiterals {
+			best, bestParams, bestLiterals = r, params, literals
+		}
+	}
+	return best, bestParams
+}
+
+func (r *route) match(segments []string) (map[string]string, int, bool) {
+	if len(segments) != len(r.segments) {
+		return nil, 0, false
+	}
+	params := make(map[string]string)
+	literals := 0
+	for i, segment := range r.segments {
+		if strings.HasPrefix(segment, ":") {
+			params[segment[1:]] = segments[i]
+			continue
+		}
+		if segment != segments[i] {
+			return nil, 0, false
+		}
+		literals++
+	}
+	return params, literals, true
+}
+
+func (r *route) methods() []string {
+	var methods []string
+	for method := range r.handlers {
+		methods = append(methods, method)
+	}
+	sort.Strings(methods)
+	return methods
+}
+
+//splitPath cleans the path, so "/orders/" and "/orders//" are the same as "/orders", and splits it into segments
+func splitPath(p string) []string {
+	p = strings.Trim(path.Clean("/"+p), "/")
+	if p == "" {
+		return []string{}
+	}
+	return strings.Split(p, "/")
+}
@@ -0,0 +1,108 @@
+package http
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+//write returns a handler writing the text along with the given path parameters
+func write(text string, params ...string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(text))
+		for _, name := range params {
+			w.Write([]byte(" " + Param(r, name)))
+		}
+	}
+}
+
+func serve(router *Router, method string, path string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest(method, path, nil)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestRouter(t *testing.T) {
+
+	t.Run("Should extract path parameters", func(t *testing.T) {
+		assert := assert.New(t)
+		router := NewRouter()
+		router.HandleFunc(http.MethodGet, "/webhooks/:id/deliveries", write("deliveries", "id"))
+
+		rec := serve(router, http.MethodGet, "/webhooks/42/deliveries")
+		assert.Equal(http.StatusOK, rec.Code)
+		body, _ := ioutil.ReadAll(rec.Body)
+		assert.Equal("deliveries 42", string(body))
+	})
+
+	t.Run("Should respond with 405 and the allowed methods", func(t *testing.T) {
+		assert := assert.New(t)
+		router := NewRouter()
+		router.HandleFunc(http.MethodPost, "/orders", write("post"))
+		router.HandleFunc(http.MethodGet, "/orders", write("get"))
+
+		rec := serve(router, http.MethodDelete, "/orders")
+		assert.Equal(http.StatusMethodNotAllowed, rec.Code)
+		assert.Equal("GET, POST", rec.Header().Get("Allow"))
+		assert.Equal("application/problem+json", rec.Header().Get("Content-Type"))
+	})
+
+	t.Run("Should respond with 404 for unknown paths", func(t *testing.T) {
+		assert := assert.New(t)
+		router := NewRouter()
+		router.HandleFunc(http.MethodPatch, "/orders/:id", write("patch", "id"))
+
+		rec := serve(router, http.MethodPatch, "/orders/abc/def")
+		assert.Equal(http.StatusNotFound, rec.Code)
+		body, _ := ioutil.ReadAll(rec.Body)
+		assert.Equal(`{"type":"about:blank","title":"Not Found","status":404,"detail":"not found","code":"not_found"}`, string(body))
+	})
+
+	t.Run("Should normalize trailing and duplicate slashes", func(t *testing.T) {
+		assert := assert.New(t)
+		router := NewRouter()
+		router.HandleFunc(http.MethodGet, "/orders", write("get"))
+
+		for _, path := range []string{"/orders/", "/orders//", "/orders/./"} {
+			rec := serve(router, http.MethodGet, path)
+			assert.Equal(http.StatusOK, rec.Code, path)
+		}
+	})
+
+	t.Run("Should prefer literal segments over parameters", func(t *testing.T) {
+		assert := assert.New(t)
+		router := NewRouter()
+		router.HandleFunc(http.MethodGet, "/orders/:id", write("order", "id"))
+		router.HandleFunc(http.MethodGet, "/orders/stream", write("stream"))
+
+		body, _ := ioutil.ReadAll(serve(router, http.MethodGet, "/orders/stream").Body)
+		assert.Equal("stream", string(body))
+		body, _ = ioutil.ReadAll(serve(router, http.MethodGet, "/orders/7").Body)
+		assert.Equal("order 7", string(body))
+	})
+
+	t.Run("Should run middlewares in order around every request", func(t *testing.T) {
+		assert := assert.New(t)
+		router := NewRouter()
+		router.HandleFunc(http.MethodGet, "/orders", write("handler"))
+		tag := func(name string) Middleware {
+			return func(next http.Handler) http.Handler {
+				return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					w.Write([]byte(name + " "))
+					next.ServeHTTP(w, r)
+				})
+			}
+		}
+		router.Use(tag("first"), tag("second"))
+
+		body, _ := ioutil.ReadAll(serve(router, http.MethodGet, "/orders").Body)
+		assert.Equal("first second handler", string(body))
+		//Middlewares also wrap requests that match no route
+		body, _ = ioutil.ReadAll(serve(router, http.MethodGet, "/unknown").Body)
+		assert.Contains(string(body), "first second ")
+	})
+}
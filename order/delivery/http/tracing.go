@@ -0,0 +1,43 @@
+package http
+
+import (
+	"net/http"
+
+	"github.com/karanbhomiagit/order-service/order/tracing"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+//Tracing returns a middleware serving every request within a span named after its route pattern, which
+//continues the trace of the traceparent header when the client sent one
+func Tracing(router *Router) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+			route := router.Route(r)
+			name := r.Method
+			if route != "" {
+				name += " " + route
+			}
+			ctx, span := tracing.Start(ctx, name,
+				trace.WithSpanKind(trace.SpanKindServer),
+				trace.WithAttributes(
+					attribute.String("http.request.method", r.Method),
+					attribute.String("url.path", r.URL.Path),
+					attribute.String("http.route", route),
+				))
+			defer span.End()
+
+			sw := &statusWriter{ResponseWriter: w}
+			next.ServeHTTP(sw, r.WithContext(ctx))
+			span.SetAttributes(attribute.Int("http.response.status_code", sw.Status()))
+			//Client errors are the expected outcome of invalid requests, not failures of the service
+			if sw.Status() >= http.StatusInternalServerError {
+				span.SetStatus(codes.Error, http.StatusText(sw.Status()))
+			}
+		})
+	}
+}
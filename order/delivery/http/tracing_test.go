@@ -0,0 +1,77 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/karanbhomiagit/order-service/order/tracing"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+//recordSpans installs a tracer provider recording the spans ended during the test
+func recordSpans(t *testing.T) *tracetest.SpanRecorder {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	previous, propagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() {
+		otel.SetTracerProvider(previous)
+		otel.SetTextMapPropagator(propagator)
+	})
+	return recorder
+}
+
+/*
+	Actual test functions
+*/
+
+func TestTracing(t *testing.T) {
+	router := NewRouter()
+	router.Use(Tracing(router))
+	router.HandleFunc(http.MethodPatch, "/v1/orders/:id", func(w http.ResponseWriter, r *http.Request) {
+		_, span := tracing.Start(r.Context(), "OrderUsecase.AssignByID")
+		span.End()
+		respondWithProblem(w, r, http.StatusServiceUnavailable, "database_unavailable", "Unable to reach the database")
+	})
+
+	t.Run("Should continue the trace of the traceparent header in a span named after the route", func(t *testing.T) {
+		assert := assert.New(t)
+		recorder := recordSpans(t)
+		req := httptest.NewRequest(http.MethodPatch, "/v1/orders/5c2b2aaf4530558539f91859", nil)
+		req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+		router.ServeHTTP(httptest.NewRecorder(), req)
+
+		spans := recorder.Ended()
+		if assert.Len(spans, 2) {
+			child, server := spans[0], spans[1]
+			assert.Equal("PATCH /v1/orders/:id", server.Name())
+			assert.Equal("4bf92f3577b34da6a3ce929d0e0e4736", server.SpanContext().TraceID().String())
+			assert.Equal("00f067aa0ba902b7", server.Parent().SpanID().String())
+			assert.Contains(server.Attributes(), attribute.String("http.route", "/v1/orders/:id"))
+			assert.Contains(server.Attributes(), attribute.Int("http.response.status_code", http.StatusServiceUnavailable))
+			assert.Equal(codes.Error, server.Status().Code)
+			assert.Equal(server.SpanContext().SpanID(), child.Parent().SpanID())
+		}
+	})
+
+	t.Run("Should start a trace for requests without traceparent", func(t *testing.T) {
+		assert := assert.New(t)
+		recorder := recordSpans(t)
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/v1/unknown", nil))
+
+		spans := recorder.Ended()
+		if assert.Len(spans, 1) {
+			assert.Equal("GET", spans[0].Name())
+			assert.False(spans[0].Parent().IsValid())
+			assert.Equal(codes.Unset, spans[0].Status().Code)
+		}
+	})
+}
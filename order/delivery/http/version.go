@@ -0,0 +1,55 @@
+package http
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/karanbhomiagit/order-service/order"
+)
+
+//Prefix of the routes of version 1 of the API
+const V1 = "/v1"
+
+//UnversionedDeprecation is when the unversioned paths were deprecated in favour of V1
+var UnversionedDeprecation = time.Date(2026, time.October, 18, 0, 0, 0, 0, time.UTC)
+
+//Mount registers every route of the service: the version 1 API along with its deprecated aliases, the OpenAPI
+//document, the metrics, the probes and the GraphQL endpoint served by graphql
+func Mount(router *Router, ou order.Usecase, of order.Feed, wu order.WebhookUsecase, aku order.APIKeyUsecase, hu order.HealthUsecase, graphql http.Handler, sunset time.Time) {
+	MountV1(router, ou, of, wu, aku, sunset)
+	NewOpenAPIHandler(router)
+	NewMetricsHandler(router)
+	NewHealthHttpHandler(router, hu)
+	router.Handle(http.MethodPost, "/graphql", graphql)
+}
+
+//MountV1 registers the version 1 handlers under V1, and under the unversioned paths as deprecated
+//aliases which answer 410 Gone from the sunset
+func MountV1(router *Router, ou order.Usecase, of order.Feed, wu order.WebhookUsecase, aku order.APIKeyUsecase, sunset time.Time) {
+	mount := func(r *Router) {
+		NewOrderHttpHandler(r, ou, of, OrderPresenterV1{})
+		NewWebhookHttpHandler(r, wu)
+		NewAPIKeyHttpHandler(r, aku)
+	}
+	mount(router.Group(V1))
+	mount(router.Group("", Deprecated(UnversionedDeprecation, sunset, V1)))
+}
+
+//Deprecated returns a middleware signaling that the routes are deprecated, with the Deprecation (RFC 9745)
+//and Sunset (RFC 8594) headers and a link to the same path under the successor prefix. From the sunset
+//the routes answer 410 Gone instead.
+func Deprecated(deprecation time.Time, sunset time.Time, successor string) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Deprecation", "@"+strconv.FormatInt(deprecation.Unix(), 10))
+			w.Header().Set("Sunset", sunset.UTC().Format(http.TimeFormat))
+			w.Header().Add("Link", "<"+successor+r.URL.Path+`>; rel="successor-version"`)
+			if !time.Now().Before(sunset) {
+				respondWithProblem(w, r, http.StatusGone, "sunset", "This path is no longer served, use "+successor+r.URL.Path+" instead")
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
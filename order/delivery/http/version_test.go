@@ -0,0 +1,108 @@
+package http
+
+import (
+	"io/ioutil"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/karanbhomiagit/order-service/models"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/mgo.v2/bson"
+)
+
+func TestMountV1(t *testing.T) {
+	sunset := time.Date(2027, time.April, 18, 0, 0, 0, 0, time.UTC)
+	orders := []models.Order{{ID: bson.ObjectIdHex("5c2b2aaf4530558539f91859"), Distance: 100, Status: "UNASSIGNED"}}
+
+	t.Run("Should serve the orders under /v1 without deprecation headers", func(t *testing.T) {
+		assert := assert.New(t)
+		testObj := new(MockedOrderUsecase)
+		testObj.On("FetchByRange", 1, 10).Return(orders, nil)
+		router := NewRouter()
+		router.Use(asAdmin)
+		MountV1(router, testObj, nil, nil, nil, sunset)
+
+		rec := serve(router, http.MethodGet, "/v1/orders")
+		assert.Equal(http.StatusOK, rec.Code)
+		body, _ := ioutil.ReadAll(rec.Body)
+		assert.Equal(`[{"id":"5c2b2aaf4530558539f91859","distance":100,"status":"UNASSIGNED"}]`, string(body))
+		assert.Empty(rec.Header().Get("Deprecation"))
+		assert.Empty(rec.Header().Get("Sunset"))
+		testObj.AssertExpectations(t)
+	})
+
+	t.Run("Should serve the unversioned paths as deprecated aliases", func(t *testing.T) {
+		assert := assert.New(t)
+		testObj := new(MockedOrderUsecase)
+		testObj.On("FetchByRange", 1, 10).Return(orders, nil)
+		router := NewRouter()
+		router.Use(asAdmin)
+		MountV1(router, testObj, nil, nil, nil, sunset)
+
+		rec := serve(router, http.MethodGet, "/orders")
+		assert.Equal(http.StatusOK, rec.Code)
+		body, _ := ioutil.ReadAll(rec.Body)
+		assert.Equal(`[{"id":"5c2b2aaf4530558539f91859","distance":100,"status":"UNASSIGNED"}]`, string(body))
+		assert.Equal("@1792281600", rec.Header().Get("Deprecation"))
+		assert.Equal("Sun, 18 Apr 2027 00:00:00 GMT", rec.Header().Get("Sunset"))
+		assert.Equal(`</v1/orders>; rel="successor-version"`, rec.Header().Get("Link"))
+		testObj.AssertExpectations(t)
+	})
+
+	t.Run("Should answer 410 Gone on the unversioned paths from the sunset", func(t *testing.T) {
+		assert := assert.New(t)
+		testObj := new(MockedOrderUsecase)
+		testObj.On("FetchByRange", 1, 10).Return(orders, nil)
+		router := NewRouter()
+		router.Use(asAdmin)
+		MountV1(router, testObj, nil, nil, nil, time.Now().Add(-time.Minute))
+
+		rec := serve(router, http.MethodGet, "/orders")
+		assert.Equal(http.StatusGone, rec.Code)
+		body, _ := ioutil.ReadAll(rec.Body)
+		assert.Contains(string(body), `"code":"sunset"`)
+		assert.Equal(`</v1/orders>; rel="successor-version"`, rec.Header().Get("Link"))
+		testObj.AssertNotCalled(t, "FetchByRange", 1, 10)
+
+		rec = serve(router, http.MethodGet, "/v1/orders")
+		assert.Equal(http.StatusOK, rec.Code)
+	})
+
+	t.Run("Should not mistake versioned paths for unversioned ids", func(t *testing.T) {
+		assert := assert.New(t)
+		router := NewRouter()
+		router.Use(asAdmin)
+		MountV1(router, new(MockedOrderUsecase), nil, nil, nil, sunset)
+
+		rec := serve(router, http.MethodPatch, "/v1/orders")
+		assert.Equal(http.StatusMethodNotAllowed, rec.Code)
+		assert.Equal("GET, POST", rec.Header().Get("Allow"))
+	})
+}
+
+func TestRouterGroup(t *testing.T) {
+
+	t.Run("Should register routes under the prefix wrapped by the group middlewares", func(t *testing.T) {
+		assert := assert.New(t)
+		router := NewRouter()
+		tagged := func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("X-Group", "v2")
+				next.ServeHTTP(w, r)
+			})
+		}
+		router.Group("/v2", tagged).HandleFunc(http.MethodGet, "/orders/:id", write("order", "id"))
+		router.HandleFunc(http.MethodGet, "/orders/:id", write("legacy", "id"))
+
+		rec := serve(router, http.MethodGet, "/v2/orders/7")
+		body, _ := ioutil.ReadAll(rec.Body)
+		assert.Equal("order 7", string(body))
+		assert.Equal("v2", rec.Header().Get("X-Group"))
+
+		rec = serve(router, http.MethodGet, "/orders/7")
+		body, _ = ioutil.ReadAll(rec.Body)
+		assert.Equal("legacy 7", string(body))
+		assert.Empty(rec.Header().Get("X-Group"))
+	})
+}
@@ -0,0 +1,117 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/karanbhomiagit/order-service/models"
+	"github.com/karanbhomiagit/order-service/order"
+	"github.com/karanbhomiagit/order-service/order/logging"
+)
+
+type WebhookHttpHandler struct {
+	webhookUsecase order.WebhookUsecase
+}
+
+func NewWebhookHttpHandler(router *Router, wu order.WebhookUsecase) {
+	handler := &WebhookHttpHandler{
+		webhookUsecase: wu,
+	}
+	handler.routes(router)
+}
+
+//routes registers the entrypoints for the "/webhooks" paths
+func (h *WebhookHttpHandler) routes(router *Router) {
+	router.Handle(http.MethodGet, "/webhooks", requireScope(order.ScopeWebhooksManage, h.getWebhooks))
+	router.Handle(http.MethodPost, "/webhooks", requireScope(order.ScopeWebhooksManage, h.postWebhook))
+	router.Handle(http.MethodGet, "/webhooks/:id", requireScope(order.ScopeWebhooksManage, h.getWebhookByID))
+	router.Handle(http.MethodPut, "/webhooks/:id", requireScope(order.ScopeWebhooksManage, h.putWebhookByID))
+	router.Handle(http.MethodDelete, "/webhooks/:id", requireScope(order.ScopeWebhooksManage, h.deleteWebhookByID))
+	router.Handle(http.MethodGet, "/webhooks/:id/deliveries", requireScope(order.ScopeWebhooksManage, h.getWebhookDeliveries))
+	router.Handle(http.MethodPost, "/webhooks/:id/test", requireScope(order.ScopeWebhooksManage, h.testWebhookByID))
+}
+
+func (h *WebhookHttpHandler) getWebhooks(w http.ResponseWriter, r *http.Request) {
+	res, err := h.webhookUsecase.FetchAll(r.Context())
+	if res == nil && err == nil {
+		res = make([]models.Webhook, 0)
+	}
+	respondWithResult(w, r, http.StatusOK, res, err)
+}
+
+func (h *WebhookHttpHandler) postWebhook(w http.ResponseWriter, r *http.Request) {
+	webhookReq, ok := decodeWebhookRequest(w, r)
+	if !ok {
+		return
+	}
+	res, err := h.webhookUsecase.Store(r.Context(), webhookReq)
+	respondWithResult(w, r, http.StatusCreated, res, err)
+}
+
+func (h *WebhookHttpHandler) getWebhookByID(w http.ResponseWriter, r *http.Request) {
+	id := Param(r, "id")
+	res, err := h.webhookUsecase.FetchByID(r.Context(), id)
+	respondWithResult(w, r, http.StatusOK, res, err)
+}
+
+func (h *WebhookHttpHandler) putWebhookByID(w http.ResponseWriter, r *http.Request) {
+	id := Param(r, "id")
+	webhookReq, ok := decodeWebhookRequest(w, r)
+	if !ok {
+		return
+	}
+	res, err := h.webhookUsecase.UpdateByID(r.Context(), id, webhookReq)
+	respondWithResult(w, r, http.StatusOK, res, err)
+}
+
+func (h *WebhookHttpHandler) deleteWebhookByID(w http.ResponseWriter, r *http.Request) {
+	id := Param(r, "id")
+	if err := h.webhookUsecase.RemoveByID(r.Context(), id); err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *WebhookHttpHandler) getWebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	id := Param(r, "id")
+	res, err := h.webhookUsecase.FetchDeliveries(r.Context(), id)
+	if res == nil && err == nil {
+		res = make([]models.WebhookDelivery, 0)
+	}
+	respondWithResult(w, r, http.StatusOK, res, err)
+}
+
+func (h *WebhookHttpHandler) testWebhookByID(w http.ResponseWriter, r *http.Request) {
+	id := Param(r, "id")
+	res, err := h.webhookUsecase.Test(r.Context(), id)
+	respondWithResult(w, r, http.StatusOK, res, err)
+}
+
+func decodeWebhookRequest(w http.ResponseWriter, r *http.Request) (*models.WebhookRequest, bool) {
+	defer r.Body.Close()
+	var webhookReq models.WebhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&webhookReq); err != nil {
+		logging.FromContext(r.Context()).Debug("Unable to decode the request body", "error", err)
+		respondWithProblem(w, r, http.StatusBadRequest, "invalid_payload", "Invalid request payload")
+		return nil, false
+	}
+	return &webhookReq, true
+}
+
+//respondWithResult writes the result of a usecase call, or its error
+func respondWithResult(w http.ResponseWriter, r *http.Request, statusCode int, res interface{}, err error) {
+	if err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+	//Marshal the json
+	b, err := json.Marshal(res)
+	if err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+	w.Header().Add("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(statusCode)
+	w.Write(b)
+}
@@ -0,0 +1,245 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/karanbhomiagit/order-service/models"
+	"github.com/karanbhomiagit/order-service/order"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"gopkg.in/mgo.v2/bson"
+)
+
+type MockedWebhookUsecase struct {
+	mock.Mock
+}
+
+func (wu *MockedWebhookUsecase) FetchByID(ctx context.Context, id string) (*models.Webhook, error) {
+	args := wu.Called(id)
+	return args.Get(0).(*models.Webhook), args.Error(1)
+}
+
+func (wu *MockedWebhookUsecase) FetchAll(ctx context.Context) ([]models.Webhook, error) {
+	args := wu.Called()
+	return args.Get(0).([]models.Webhook), args.Error(1)
+}
+
+func (wu *MockedWebhookUsecase) Store(ctx context.Context, webhookReq *models.WebhookRequest) (*models.Webhook, error) {
+	args := wu.Called(webhookReq)
+	return args.Get(0).(*models.Webhook), args.Error(1)
+}
+
+func (wu *MockedWebhookUsecase) UpdateByID(ctx context.Context, id string, webhookReq *models.WebhookRequest) (*models.Webhook, error) {
+	args := wu.Called(id, webhookReq)
+	return args.Get(0).(*models.Webhook), args.Error(1)
+}
+
+func (wu *MockedWebhookUsecase) RemoveByID(ctx context.Context, id string) error {
+	args := wu.Called(id)
+	return args.Error(0)
+}
+
+func (wu *MockedWebhookUsecase) FetchDeliveries(ctx context.Context, id string) ([]models.WebhookDelivery, error) {
+	args := wu.Called(id)
+	return args.Get(0).([]models.WebhookDelivery), args.Error(1)
+}
+
+func (wu *MockedWebhookUsecase) Test(ctx context.Context, id string) (*models.WebhookDelivery, error) {
+	args := wu.Called(id)
+	return args.Get(0).(*models.WebhookDelivery), args.Error(1)
+}
+
+func (wu *MockedWebhookUsecase) Dispatch(event models.OrderEvent) error {
+	args := wu.Called(event)
+	return args.Error(0)
+}
+
+func (wu *MockedWebhookUsecase) Run(ctx context.Context) {
+	wu.Called()
+}
+
+var testWebhookCreatedAt = time.Date(2019, 1, 2, 12, 0, 0, 0, time.UTC)
+
+/*
+	Actual test functions
+*/
+
+func TestWebhooksHandler(t *testing.T) {
+
+	t.Run("Should respond with 201 for POST /webhooks when created successfully", func(t *testing.T) {
+		testObj := new(MockedWebhookUsecase)
+		webhookReq := models.WebhookRequest{URL: "https://merchant.example/hooks", Events: []string{"order.assigned"}}
+		testObj.On("Store", &webhookReq).Return(&models.Webhook{
+			ID:        bson.ObjectId("12345"),
+			URL:       "https://merchant.example/hooks",
+			Events:    []string{"order.assigned"},
+			Secret:    "s3cr3t",
+			CreatedAt: testWebhookCreatedAt,
+		}, nil)
+		handler := &WebhookHttpHandler{
+			webhookUsecase: testObj,
+		}
+
+		var jsonStr = []byte(`{"url":"https://merchant.example/hooks","events":["order.assigned"]}`)
+		req, err := http.NewRequest(http.MethodPost, "/webhooks", bytes.NewBuffer(jsonStr))
+		assert.NoError(t, err)
+		rec := httptest.NewRecorder()
+
+		routed(handler).ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusCreated, rec.Code)
+		body, _ := ioutil.ReadAll(rec.Body)
+		assert.Equal(t, `{"id":"3132333435","url":"https://merchant.example/hooks","events":["order.assigned"],"secret":"s3cr3t","createdAt":"2019-01-02T12:00:00Z"}`, string(body))
+		assert.Equal(t, "application/json; charset=utf-8", rec.Header().Get("Content-Type"))
+		testObj.AssertExpectations(t)
+	})
+
+	t.Run("Should respond with 400 for POST /webhooks when usecase layer returns error", func(t *testing.T) {
+		testObj := new(MockedWebhookUsecase)
+		webhookReq := models.WebhookRequest{URL: "/hooks"}
+		testObj.On("Store", &webhookReq).Return(&models.Webhook{}, order.NewInvalidArgument("invalid_url", "Please provide an absolute http or https url"))
+		handler := &WebhookHttpHandler{
+			webhookUsecase: testObj,
+		}
+
+		req, err := http.NewRequest(http.MethodPost, "/webhooks", strings.NewReader(`{"url":"/hooks"}`))
+		assert.NoError(t, err)
+		rec := httptest.NewRecorder()
+
+		routed(handler).ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		body, _ := ioutil.ReadAll(rec.Body)
+		assert.Equal(t, `{"type":"about:blank","title":"Bad Request","status":400,"detail":"Please provide an absolute http or https url","code":"invalid_url"}`, string(body))
+		testObj.AssertExpectations(t)
+	})
+
+	t.Run("Should respond with an empty list for GET /webhooks without webhooks", func(t *testing.T) {
+		testObj := new(MockedWebhookUsecase)
+		testObj.On("FetchAll").Return([]models.Webhook(nil), nil)
+		handler := &WebhookHttpHandler{
+			webhookUsecase: testObj,
+		}
+
+		req, err := http.NewRequest(http.MethodGet, "/webhooks", strings.NewReader(""))
+		assert.NoError(t, err)
+		rec := httptest.NewRecorder()
+
+		routed(handler).ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		body, _ := ioutil.ReadAll(rec.Body)
+		assert.Equal(t, `[]`, string(body))
+		testObj.AssertExpectations(t)
+	})
+}
+
+func TestWebhookHandler(t *testing.T) {
+
+	t.Run("Should respond with 404 for GET /webhooks/id when usecase layer returns not found", func(t *testing.T) {
+		testObj := new(MockedWebhookUsecase)
+		testObj.On("FetchByID", "1234").Return(&models.Webhook{}, order.NewNotFound("webhook_not_found", "Invalid Id"))
+		handler := &WebhookHttpHandler{
+			webhookUsecase: testObj,
+		}
+
+		req, err := http.NewRequest(http.MethodGet, "/webhooks/1234", strings.NewReader(""))
+		assert.NoError(t, err)
+		rec := httptest.NewRecorder()
+
+		routed(handler).ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+		body, _ := ioutil.ReadAll(rec.Body)
+		assert.Equal(t, `{"type":"about:blank","title":"Not Found","status":404,"detail":"Invalid Id","code":"webhook_not_found"}`, string(body))
+		testObj.AssertExpectations(t)
+	})
+
+	t.Run("Should respond with 204 for DELETE /webhooks/id when removed successfully", func(t *testing.T) {
+		testObj := new(MockedWebhookUsecase)
+		testObj.On("RemoveByID", "5c2b2aaf4530558539f91859").Return(nil)
+		handler := &WebhookHttpHandler{
+			webhookUsecase: testObj,
+		}
+
+		req, err := http.NewRequest(http.MethodDelete, "/webhooks/5c2b2aaf4530558539f91859", strings.NewReader(""))
+		assert.NoError(t, err)
+		rec := httptest.NewRecorder()
+
+		routed(handler).ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusNoContent, rec.Code)
+		testObj.AssertExpectations(t)
+	})
+
+	t.Run("Should respond with the delivery log for GET /webhooks/id/deliveries", func(t *testing.T) {
+		testObj := new(MockedWebhookUsecase)
+		testObj.On("FetchDeliveries", "5c2b2aaf4530558539f91859").Return([]models.WebhookDelivery{{
+			ID:          bson.ObjectId("12345"),
+			WebhookID:   bson.ObjectId("12346"),
+			EventID:     "e1",
+			EventType:   "order.assigned",
+			Attempt:     1,
+			StatusCode:  500,
+			Error:       "Unexpected response status 500",
+			DeliveredAt: testWebhookCreatedAt,
+		}}, nil)
+		handler := &WebhookHttpHandler{
+			webhookUsecase: testObj,
+		}
+
+		req, err := http.NewRequest(http.MethodGet, "/webhooks/5c2b2aaf4530558539f91859/deliveries", strings.NewReader(""))
+		assert.NoError(t, err)
+		rec := httptest.NewRecorder()
+
+		routed(handler).ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		body, _ := ioutil.ReadAll(rec.Body)
+		assert.Equal(t, `[{"id":"3132333435","webhookId":"3132333436","eventId":"e1","eventType":"order.assigned","attempt":1,"statusCode":500,"error":"Unexpected response status 500","success":false,"deliveredAt":"2019-01-02T12:00:00Z"}]`, string(body))
+		testObj.AssertExpectations(t)
+	})
+
+	t.Run("Should send a sample event for POST /webhooks/id/test", func(t *testing.T) {
+		testObj := new(MockedWebhookUsecase)
+		testObj.On("Test", "5c2b2aaf4530558539f91859").Return(&models.WebhookDelivery{EventType: "webhook.test", Attempt: 1, StatusCode: 200, Success: true}, nil)
+		handler := &WebhookHttpHandler{
+			webhookUsecase: testObj,
+		}
+
+		req, err := http.NewRequest(http.MethodPost, "/webhooks/5c2b2aaf4530558539f91859/test", strings.NewReader(""))
+		assert.NoError(t, err)
+		rec := httptest.NewRecorder()
+
+		routed(handler).ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		body, _ := ioutil.ReadAll(rec.Body)
+		assert.Contains(t, string(body), `"success":true`)
+		testObj.AssertExpectations(t)
+	})
+
+	t.Run("Should respond with 405 for PATCH /webhooks/id", func(t *testing.T) {
+		testObj := new(MockedWebhookUsecase)
+		handler := &WebhookHttpHandler{
+			webhookUsecase: testObj,
+		}
+
+		req, err := http.NewRequest(http.MethodPatch, "/webhooks/5c2b2aaf4530558539f91859", strings.NewReader(""))
+		assert.NoError(t, err)
+		rec := httptest.NewRecorder()
+
+		routed(handler).ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+		assert.Equal(t, "DELETE, GET, PUT", rec.Header().Get("Allow"))
+		testObj.AssertExpectations(t)
+	})
+}
@@ -0,0 +1,92 @@
+package order
+
+import (
+	"context"
+	"errors"
+)
+
+// Kind classifies domain errors so that each delivery can map them to its own responses
+type Kind int
+
+const (
+	KindInternal Kind = iota
+	KindNotFound
+	KindInvalidArgument
+	KindConflict
+	KindUnavailable
+	KindUnauthenticated
+	KindPermissionDenied
+)
+
+// Error is a domain error carrying a stable machine-readable code along with a message for humans
+type Error struct {
+	Kind    Kind
+	Code    string
+	Message string
+	Err     error
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// NewNotFound returns an error for a resource which does not exist
+func NewNotFound(code string, message string) error {
+	return &Error{Kind: KindNotFound, Code: code, Message: message}
+}
+
+// NewInvalidArgument returns an error for a request which can never succeed as it is
+func NewInvalidArgument(code string, message string) error {
+	return &Error{Kind: KindInvalidArgument, Code: code, Message: message}
+}
+
+// NewConflict returns an error for a request which conflicts with the current state of a resource
+func NewConflict(code string, message string) error {
+	return &Error{Kind: KindConflict, Code: code, Message: message}
+}
+
+// NewUnavailable returns an error for a dependency which failed, wrapping its cause
+func NewUnavailable(code string, message string, err error) error {
+	return &Error{Kind: KindUnavailable, Code: code, Message: message, Err: err}
+}
+
+// NewUnauthenticated returns an error for a caller whose credentials are missing or invalid
+func NewUnauthenticated(code string, message string) error {
+	return &Error{Kind: KindUnauthenticated, Code: code, Message: message}
+}
+
+// NewPermissionDenied returns an error for an authenticated caller which is not allowed to make the request
+func NewPermissionDenied(code string, message string) error {
+	return &Error{Kind: KindPermissionDenied, Code: code, Message: message}
+}
+
+// KindOf returns the kind of a domain error, or KindInternal for any other error
+func KindOf(err error) Kind {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Kind
+	}
+	return KindInternal
+}
+
+// CodeOf returns the code of a domain error, or "internal" for any other error
+func CodeOf(err error) string {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Code
+	}
+	return "internal"
+}
+
+// NewCanceled returns an error for a request which was cancelled or ran out of time before it completed,
+// wrapping the error of its context
+func NewCanceled(err error) error {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return &Error{Kind: KindUnavailable, Code: "deadline_exceeded", Message: "The request timed out", Err: err}
+	}
+	return &Error{Kind: KindUnavailable, Code: "request_cancelled", Message: "The request was cancelled", Err: err}
+}
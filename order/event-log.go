@@ -0,0 +1,10 @@
+package order
+
+import "github.com/karanbhomiagit/order-service/models"
+
+// EventLogRepository represents the log of the events relayed from the outbox, read by every replica, as an interface
+type EventLogRepository interface {
+	Append(models.OrderEvent) error
+	FetchAfter(int64, int) ([]models.LoggedEvent, error)
+	LastSeq() (int64, error)
+}
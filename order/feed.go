@@ -0,0 +1,9 @@
+package order
+
+import "github.com/karanbhomiagit/order-service/models"
+
+// Feed represents a live stream of order events with a bounded replay history as an interface.
+// Subscribe returns the events after the given event ID, the channel of new events and a function to unsubscribe.
+type Feed interface {
+	Subscribe(string) ([]models.OrderEvent, <-chan models.OrderEvent, func())
+}
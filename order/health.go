@@ -0,0 +1,19 @@
+package order
+
+import (
+	"context"
+
+	"github.com/karanbhomiagit/order-service/models"
+)
+
+//HealthChecker represents a dependency of the service whose availability can be checked as an interface
+type HealthChecker interface {
+	Check(context.Context) error
+}
+
+//HealthUsecase represents the readiness of the service to serve requests as an interface. Once shutting down,
+//the service is no longer ready.
+type HealthUsecase interface {
+	Ready(context.Context) models.Health
+	Shutdown()
+}
@@ -0,0 +1,94 @@
+package order
+
+import "context"
+
+//Scopes granted to API clients
+const (
+	ScopeOrdersRead     = "orders:read"
+	ScopeOrdersCreate   = "orders:create"
+	ScopeOrdersAssign   = "orders:assign"
+	ScopeOrdersCancel   = "orders:cancel"
+	ScopeWebhooksManage = "webhooks:manage"
+	ScopeKeysManage     = "keys:manage"
+)
+
+//Scopes lists every scope which can be granted
+var Scopes = []string{ScopeOrdersRead, ScopeOrdersCreate, ScopeOrdersAssign, ScopeOrdersCancel, ScopeWebhooksManage, ScopeKeysManage}
+
+//Roles of the end users authenticated by a token
+const (
+	RoleCourier  = "courier"
+	RoleMerchant = "merchant"
+)
+
+//RoleScopes lists the scopes granted to the end users holding each role
+var RoleScopes = map[string][]string{
+	RoleCourier:  {ScopeOrdersRead, ScopeOrdersAssign},
+	RoleMerchant: {ScopeOrdersRead, ScopeOrdersCreate, ScopeOrdersCancel},
+}
+
+//AdminSubject is the subject of the identity authenticated by the admin key
+const AdminSubject = "admin"
+
+//Identity is the authenticated caller of the service. Clients authenticated by an API key have no roles,
+//end users authenticated by a token have the roles of their claims.
+type Identity struct {
+	Subject string
+	Scopes  []string
+	Roles   []string
+}
+
+//HasScope reports whether the caller was granted the scope
+func (i *Identity) HasScope(scope string) bool {
+	for _, s := range i.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+//HasRole reports whether the caller holds the role
+func (i *Identity) HasRole(role string) bool {
+	for _, r := range i.Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+type identityKey struct{}
+
+//NewContext returns a copy of ctx carrying the identity of the caller
+func NewContext(ctx context.Context, identity *Identity) context.Context {
+	return context.WithValue(ctx, identityKey{}, identity)
+}
+
+//IdentityFrom returns the identity of the caller carried by ctx, if any
+func IdentityFrom(ctx context.Context) (*Identity, bool) {
+	identity, ok := ctx.Value(identityKey{}).(*Identity)
+	return identity, ok
+}
+
+//Authorize checks that the caller carried by ctx was granted the scope
+func Authorize(ctx context.Context, scope string) error {
+	identity, ok := IdentityFrom(ctx)
+	if !ok {
+		return NewUnauthenticated("unauthenticated", "Authentication is required")
+	}
+	if !identity.HasScope(scope) {
+		return NewPermissionDenied("forbidden", "Missing scope "+scope)
+	}
+	return nil
+}
+
+//AuthorizeRole checks that the end user carried by ctx holds the role. Callers without roles, i.e. API
+//clients authorized by their scopes and internal callers, are not restricted by roles.
+func AuthorizeRole(ctx context.Context, role string) error {
+	identity, ok := IdentityFrom(ctx)
+	if !ok || len(identity.Roles) == 0 || identity.HasRole(role) {
+		return nil
+	}
+	return NewPermissionDenied("forbidden_role", "Only a "+role+" can do this")
+}
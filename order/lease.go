@@ -0,0 +1,9 @@
+package order
+
+import "time"
+
+// LeaseRepository represents the storage of time-bound leases used to coordinate replicas as an interface
+type LeaseRepository interface {
+	Acquire(string, string, time.Duration) (bool, error)
+	Release(string, string) error
+}
@@ -0,0 +1,127 @@
+package logging
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+//Request holds the fields added to the log lines written while serving a request
+type Request struct {
+	ID    string
+	Route string
+	Start time.Time
+
+	mutex   sync.Mutex
+	orderID string
+}
+
+type requestKey struct{}
+type loggerKey struct{}
+
+//New returns a logger writing lines of JSON to w from the level up. Lines written with the context of
+//a request carry its request_id, route, order_id once known and the latency_ms since the request began,
+//and lines written within a span carry its trace_id and span_id.
+func New(w io.Writer, level slog.Leveler) *slog.Logger {
+	return slog.New(&contextHandler{Handler: slog.NewJSONHandler(w, &slog.HandlerOptions{Level: level})})
+}
+
+//ParseLevel parses a level written as debug, info, warn or error
+func ParseLevel(s string) (slog.Level, error) {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(strings.TrimSpace(s))); err != nil {
+		return level, fmt.Errorf("invalid log level %q, expected debug, info, warn or error", s)
+	}
+	return level, nil
+}
+
+//NewRequestID returns a random id for a request which came without one
+func NewRequestID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+//NewContext returns a copy of ctx carrying the request and the logger serving it
+func NewContext(ctx context.Context, logger *slog.Logger, req *Request) context.Context {
+	ctx = context.WithValue(ctx, requestKey{}, req)
+	return context.WithValue(ctx, loggerKey{}, logger)
+}
+
+//RequestFrom returns the request carried by ctx, if any
+func RequestFrom(ctx context.Context) (*Request, bool) {
+	req, ok := ctx.Value(requestKey{}).(*Request)
+	return req, ok
+}
+
+//FromContext returns the logger carried by ctx, or the default logger. The lines it writes carry the
+//fields of the request of ctx even when written without a context.
+func FromContext(ctx context.Context) *slog.Logger {
+	logger, ok := ctx.Value(loggerKey{}).(*slog.Logger)
+	if !ok {
+		logger = slog.Default()
+	}
+	if h, ok := logger.Handler().(*contextHandler); ok {
+		return slog.New(&contextHandler{Handler: h.Handler, ctx: ctx})
+	}
+	return logger
+}
+
+//SetOrderID records the order the request is about, so the lines written from then on carry it
+func SetOrderID(ctx context.Context, id string) {
+	if req, ok := RequestFrom(ctx); ok {
+		req.mutex.Lock()
+		req.orderID = id
+		req.mutex.Unlock()
+	}
+}
+
+//OrderID returns the order the request is about, or "" when not known
+func (r *Request) OrderID() string {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.orderID
+}
+
+//contextHandler adds the fields of the request of the context to the lines, using ctx when it is bound
+//to the context of a request
+type contextHandler struct {
+	slog.Handler
+	ctx context.Context
+}
+
+func (h *contextHandler) Handle(ctx context.Context, record slog.Record) error {
+	if h.ctx != nil {
+		ctx = h.ctx
+	}
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		record.AddAttrs(slog.String("trace_id", sc.TraceID().String()), slog.String("span_id", sc.SpanID().String()))
+	}
+	if req, ok := RequestFrom(ctx); ok {
+		record.AddAttrs(slog.String("request_id", req.ID))
+		if req.Route != "" {
+			record.AddAttrs(slog.String("route", req.Route))
+		}
+		if orderID := req.OrderID(); orderID != "" {
+			record.AddAttrs(slog.String("order_id", orderID))
+		}
+		record.AddAttrs(slog.Float64("latency_ms", float64(time.Since(req.Start).Microseconds())/1000))
+	}
+	return h.Handler.Handle(ctx, record)
+}
+
+func (h *contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &contextHandler{Handler: h.Handler.WithAttrs(attrs), ctx: h.ctx}
+}
+
+func (h *contextHandler) WithGroup(name string) slog.Handler {
+	return &contextHandler{Handler: h.Handler.WithGroup(name), ctx: h.ctx}
+}
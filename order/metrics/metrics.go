@@ -0,0 +1,146 @@
+package metrics
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/karanbhomiagit/order-service/order"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+)
+
+const namespace = "order_service"
+
+//Operations on orders counted by ObserveOrderOperation
+const (
+	OperationCreate = "create"
+	OperationAssign = "assign"
+)
+
+//Registry holds the metrics of the service, along with the Go runtime and process metrics
+var Registry = prometheus.NewRegistry()
+
+var (
+	HTTPRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "HTTP requests served, by method, route pattern and status code.",
+	}, []string{"method", "route", "status"})
+
+	HTTPRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "Latency of the HTTP requests, by method, route pattern and status code.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	GRPCCalls = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "grpc_calls_total",
+		Help:      "gRPC calls served, by method and status code.",
+	}, []string{"method", "code"})
+
+	GRPCCallDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "grpc_call_duration_seconds",
+		Help:      "Latency of the gRPC calls, by method and status code.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "code"})
+
+	OrdersCreated = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "orders_created_total",
+		Help:      "Orders created.",
+	})
+
+	OrdersAssigned = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "orders_assigned_total",
+		Help:      "Orders assigned to a courier.",
+	})
+
+	OrderFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "order_failures_total",
+		Help:      "Orders which could not be created or assigned, by operation and error code.",
+	}, []string{"operation", "code"})
+
+	DistanceRequestDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "distance_request_duration_seconds",
+		Help:      "Latency of the Google Distance Matrix requests.",
+		Buckets:   prometheus.DefBuckets,
+	})
+
+	DistanceRequestErrors = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "distance_request_errors_total",
+		Help:      "Google Distance Matrix requests which failed.",
+	})
+
+	MongoOperationDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "mongo_operation_duration_seconds",
+		Help:      "Latency of the MongoDB operations, by collection, operation and outcome.",
+		Buckets:   []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+	}, []string{"collection", "operation", "outcome"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		HTTPRequests,
+		HTTPRequestDuration,
+		GRPCCalls,
+		GRPCCallDuration,
+		OrdersCreated,
+		OrdersAssigned,
+		OrderFailures,
+		DistanceRequestDuration,
+		DistanceRequestErrors,
+		MongoOperationDuration,
+	)
+}
+
+//ObserveHTTPRequest records a request served with the status after the duration
+func ObserveHTTPRequest(method string, route string, status int, duration time.Duration) {
+	code := strconv.Itoa(status)
+	HTTPRequests.WithLabelValues(method, route, code).Inc()
+	HTTPRequestDuration.WithLabelValues(method, route, code).Observe(duration.Seconds())
+}
+
+//ObserveGRPCCall records a call of the method served with the status code after the duration
+func ObserveGRPCCall(method string, code string, duration time.Duration) {
+	GRPCCalls.WithLabelValues(method, code).Inc()
+	GRPCCallDuration.WithLabelValues(method, code).Observe(duration.Seconds())
+}
+
+//ObserveOrderOperation counts an order created or assigned, or a failure of the operation by error code
+func ObserveOrderOperation(operation string, err error) {
+	if err != nil {
+		OrderFailures.WithLabelValues(operation, order.CodeOf(err)).Inc()
+		return
+	}
+	switch operation {
+	case OperationCreate:
+		OrdersCreated.Inc()
+	case OperationAssign:
+		OrdersAssigned.Inc()
+	}
+}
+
+//ObserveDistanceRequest records a Distance Matrix request which took the duration, failing with err if not nil
+func ObserveDistanceRequest(duration time.Duration, err error) {
+	DistanceRequestDuration.Observe(duration.Seconds())
+	if err != nil {
+		DistanceRequestErrors.Inc()
+	}
+}
+
+//ObserveMongoOperation records an operation on the collection which took the duration, with its outcome:
+//ok, not_found, duplicate, aborted (transactions) or error
+func ObserveMongoOperation(collection string, operation string, duration time.Duration, outcome string) {
+	MongoOperationDuration.WithLabelValues(collection, operation, outcome).Observe(duration.Seconds())
+}
@@ -0,0 +1,16 @@
+package order
+
+import (
+	"time"
+
+	"github.com/karanbhomiagit/order-service/models"
+)
+
+// OutboxRepository represents the storage of events waiting to be relayed as an interface
+type OutboxRepository interface {
+	FetchPending(time.Time, int) ([]models.OutboxEntry, error)
+	UpdateByID(*models.OutboxEntry) error
+	RemoveByID(string) error
+	ResumeTransactions() error
+	PruneTransactions(time.Time, int) (int, error)
+}
@@ -0,0 +1,8 @@
+package order
+
+import "github.com/karanbhomiagit/order-service/models"
+
+// EventPublisher represents the publishing of order events as an interface
+type EventPublisher interface {
+	Publish(models.OrderEvent) error
+}
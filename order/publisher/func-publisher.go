@@ -0,0 +1,16 @@
+package publisher
+
+import (
+	"github.com/karanbhomiagit/order-service/models"
+	"github.com/karanbhomiagit/order-service/order"
+)
+
+//PublisherFunc publishes the events by calling the function, e.g. to hand them to a usecase
+type PublisherFunc func(models.OrderEvent) error
+
+var _ order.EventPublisher = PublisherFunc(nil)
+
+//Publish calls f with the event
+func (f PublisherFunc) Publish(event models.OrderEvent) error {
+	return f(event)
+}
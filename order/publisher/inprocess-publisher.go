@@ -0,0 +1,37 @@
+package publisher
+
+import (
+	"sync"
+
+	"github.com/karanbhomiagit/order-service/models"
+	"github.com/karanbhomiagit/order-service/order"
+)
+
+//InProcessPublisher hands every event to the subscribers registered within the same process
+type InProcessPublisher struct {
+	mu          sync.RWMutex
+	subscribers []func(models.OrderEvent)
+}
+
+func NewInProcessPublisher() *InProcessPublisher {
+	return &InProcessPublisher{}
+}
+
+var _ order.EventPublisher = (*InProcessPublisher)(nil)
+
+//Subscribe registers a handler which is called synchronously for every published event
+func (p *InProcessPublisher) Subscribe(handler func(models.OrderEvent)) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.subscribers = append(p.subscribers, handler)
+}
+
+//Publish calls every subscriber with the event, in the order they subscribed
+func (p *InProcessPublisher) Publish(event models.OrderEvent) error {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	for _, handler := range p.subscribers {
+		handler(event)
+	}
+	return nil
+}
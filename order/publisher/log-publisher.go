@@ -0,0 +1,30 @@
+package publisher
+
+import (
+	"encoding/json"
+	"log/slog"
+
+	"github.com/karanbhomiagit/order-service/models"
+	"github.com/karanbhomiagit/order-service/order"
+)
+
+type logPublisher struct {
+	logger *slog.Logger
+}
+
+//NewLogPublisher returns a publisher which logs every event, as JSON under the "event" key
+func NewLogPublisher(logger *slog.Logger) order.EventPublisher {
+	return &logPublisher{
+		logger: logger,
+	}
+}
+
+//Publish marshals the event and writes it to the log
+func (p *logPublisher) Publish(event models.OrderEvent) error {
+	b, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	p.logger.Info("Order event", "event", json.RawMessage(b))
+	return nil
+}
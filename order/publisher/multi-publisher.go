@@ -0,0 +1,25 @@
+package publisher
+
+import (
+	"github.com/karanbhomiagit/order-service/models"
+	"github.com/karanbhomiagit/order-service/order"
+)
+
+type multiPublisher struct {
+	publishers []order.EventPublisher
+}
+
+//NewMultiPublisher returns a publisher which hands every event to each of the publishers in turn
+func NewMultiPublisher(publishers ...order.EventPublisher) order.EventPublisher {
+	return &multiPublisher{publishers}
+}
+
+//Publish stops at the first publisher which fails so the event is retried; publishers must tolerate duplicates
+func (p *multiPublisher) Publish(event models.OrderEvent) error {
+	for _, publisher := range p.publishers {
+		if err := publisher.Publish(event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+package publisher
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/karanbhomiagit/order-service/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func testEvent() models.OrderEvent {
+	return models.OrderEvent{
+		ID:         "5c2b2aaf4530558539f91860",
+		Type:       models.EventOrderAssigned,
+		Version:    models.EventSchemaVersion,
+		OccurredAt: time.Date(2019, 1, 2, 12, 0, 0, 0, time.UTC),
+		Data: models.OrderEventData{
+			OrderID:        "5c2b2aaf4530558539f91859",
+			Status:         "TAKEN",
+			PreviousStatus: "UNASSIGNED",
+			Distance:       12345,
+		},
+	}
+}
+
+/*
+	Actual test functions
+*/
+
+func TestInProcessPublisher(t *testing.T) {
+
+	t.Run("Successfully deliver events to every subscriber in order", func(t *testing.T) {
+		p := NewInProcessPublisher()
+		var received []string
+		p.Subscribe(func(e models.OrderEvent) { received = append(received, "first "+e.ID) })
+		p.Subscribe(func(e models.OrderEvent) { received = append(received, "second "+e.ID) })
+
+		err := p.Publish(testEvent())
+		assert := assert.New(t)
+		assert.Nil(err)
+		assert.Equal([]string{"first 5c2b2aaf4530558539f91860", "second 5c2b2aaf4530558539f91860"}, received)
+	})
+
+	t.Run("Successfully publish without subscribers", func(t *testing.T) {
+		p := NewInProcessPublisher()
+		assert.Nil(t, p.Publish(testEvent()))
+	})
+}
+
+func TestLogPublisher(t *testing.T) {
+
+	t.Run("Successfully write the event as JSON", func(t *testing.T) {
+		var buf bytes.Buffer
+		p := NewLogPublisher(slog.New(slog.NewJSONHandler(&buf, nil)))
+
+		err := p.Publish(testEvent())
+		assert := assert.New(t)
+		assert.Nil(err)
+		assert.True(strings.HasSuffix(buf.String(), `"msg":"Order event","event":{"id":"5c2b2aaf4530558539f91860","type":"order.assigned","version":1,"occurredAt":"2019-01-02T12:00:00Z","data":{"orderId":"5c2b2aaf4530558539f91859","status":"TAKEN","previousStatus":"UNASSIGNED","distance":12345}}}`+"\n"))
+	})
+}
+
+func TestMultiPublisher(t *testing.T) {
+
+	t.Run("Successfully publish to every publisher", func(t *testing.T) {
+		first := NewInProcessPublisher()
+		second := NewInProcessPublisher()
+		received := 0
+		first.Subscribe(func(e models.OrderEvent) { received++ })
+		second.Subscribe(func(e models.OrderEvent) { received++ })
+
+		err := NewMultiPublisher(first, second).Publish(testEvent())
+		assert := assert.New(t)
+		assert.Nil(err)
+		assert.Equal(2, received)
+	})
+}
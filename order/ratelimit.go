@@ -0,0 +1,15 @@
+package order
+
+import (
+	"time"
+
+	"github.com/karanbhomiagit/order-service/models"
+)
+
+//RateLimitStore represents the token buckets of the clients as an interface. A store shared by the replicas,
+//such as the database, enforces the limits across the whole service. Check tells whether a token could be
+//taken, without taking it.
+type RateLimitStore interface {
+	Take(string, models.RateLimit, time.Time) (*models.RateLimitStatus, error)
+	Check(string, models.RateLimit, time.Time) (*models.RateLimitStatus, error)
+}
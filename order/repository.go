@@ -1,11 +1,21 @@
 package order
 
-import "github.com/karanbhomiagit/order-service/models"
+import (
+	"context"
+	"time"
 
-// Repository represents the order's storage/retrieval as an interface
+	"github.com/karanbhomiagit/order-service/models"
+)
+
+// Repository represents the order's storage/retrieval as an interface.
+// Every change is recorded together with the event describing it. The context carries the trace of the
+// operation which needs the orders.
 type Repository interface {
-	FetchByID(string) (*models.Order, error)
-	FetchByRange(int, int) ([]models.Order, error)
-	Store(*models.Order) (*models.Order, error)
-	UpdateByID(*models.Order) error
+	FetchByID(context.Context, string) (*models.Order, error)
+	FetchByRange(context.Context, int, int) ([]models.Order, error)
+	FetchByFilter(context.Context, *models.OrderFilter, int, int) ([]models.Order, error)
+	FetchByFilterAfter(context.Context, *models.OrderFilter, string, int) ([]models.Order, error)
+	FetchByStatusBefore(context.Context, string, time.Time, int) ([]models.Order, error)
+	Store(context.Context, *models.Order, models.OrderEvent) (*models.Order, error)
+	UpdateStatusByID(context.Context, string, string, string, models.OrderEvent) (time.Time, error)
 }
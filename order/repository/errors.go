@@ -0,0 +1,121 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/karanbhomiagit/order-service/order"
+	"github.com/karanbhomiagit/order-service/order/metrics"
+	"github.com/karanbhomiagit/order-service/order/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	mgo "gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/txn"
+)
+
+//traced runs a read on the collection like timed until ctx is done, within a span of the trace of ctx.
+//Operations made outside of a trace, such as those of the background workers, do not start one of their own.
+func traced(ctx context.Context, s *MongoSession, collection string, operation string, op func(c *mgo.Collection) error) error {
+	return spanned(ctx, collection, operation, func() error {
+		return cancellable(ctx, func() error { return s.run(collection, op) })
+	})
+}
+
+//tracedWrite runs a write on the collection like traced, except that ctx is only checked before it starts.
+//A write abandoned in flight may still be applied, and applied again when its caller retries.
+func tracedWrite(ctx context.Context, s *MongoSession, collection string, operation string, op func(c *mgo.Collection) error) error {
+	return spanned(ctx, collection, operation, func() error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		return s.run(collection, op)
+	})
+}
+
+//spanned runs the operation on the collection, recording it as one and within a span of the trace of ctx, if any
+func spanned(ctx context.Context, collection string, operation string, op func() error) error {
+	if !trace.SpanContextFromContext(ctx).IsValid() {
+		return measured(collection, operation, op)
+	}
+	_, span := tracing.Start(ctx, "mongo "+collection+"."+operation,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("db.system", "mongodb"),
+			attribute.String("db.collection.name", collection),
+			attribute.String("db.operation.name", operation),
+		))
+	err := measured(collection, operation, op)
+	//A missing document is an answer, not a failure
+	if err == mgo.ErrNotFound || err == txn.ErrAborted {
+		tracing.End(span, nil)
+	} else {
+		tracing.End(span, err)
+	}
+	return err
+}
+
+//cancellable runs the operation until ctx is done, returning the error of ctx once it is. mgo cannot
+//interrupt an operation in flight, so one still running is left to complete and its result is discarded.
+func cancellable(ctx context.Context, op func() error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if ctx.Done() == nil {
+		return op()
+	}
+	done := make(chan error, 1)
+	go func() { done <- op() }()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+//timed runs an operation on the collection with a copy of the session, recording its latency and outcome
+func timed(s *MongoSession, collection string, operation string, op func(c *mgo.Collection) error) error {
+	return measured(collection, operation, func() error { return s.run(collection, op) })
+}
+
+//measured runs an operation, recording its latency and outcome as one on the collection
+func measured(collection string, operation string, op func() error) error {
+	start := time.Now()
+	err := op()
+	outcome := "ok"
+	switch {
+	case err == nil:
+	case err == mgo.ErrNotFound:
+		outcome = "not_found"
+	case err == txn.ErrAborted:
+		outcome = "aborted"
+	case mgo.IsDup(err):
+		outcome = "duplicate"
+	case isContextError(err):
+		outcome = "cancelled"
+	default:
+		outcome = "error"
+	}
+	metrics.ObserveMongoOperation(collection, operation, time.Since(start), outcome)
+	return err
+}
+
+//mongoError translates errors from mgo into domain errors, using notFoundCode for missing documents
+func mongoError(err error, notFoundCode string) error {
+	switch {
+	case err == nil:
+		return nil
+	case err == mgo.ErrNotFound:
+		return order.NewNotFound(notFoundCode, "not found")
+	case isContextError(err):
+		return order.NewCanceled(err)
+	default:
+		return order.NewUnavailable("database_unavailable", "Database is unavailable", err)
+	}
+}
+
+//isContextError reports whether the operation was abandoned because its context was done
+func isContextError(err error) bool {
+	return errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
+}
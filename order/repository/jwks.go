@@ -0,0 +1,148 @@
+package repository
+
+import (
+	"crypto/rsa"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/karanbhomiagit/order-service/config"
+	"github.com/karanbhomiagit/order-service/models"
+	"github.com/karanbhomiagit/order-service/order"
+)
+
+const (
+	//jwksMinRefresh limits how often tokens signed with unknown keys make the key set be fetched again
+	jwksMinRefresh = time.Minute
+	//maxJWKSSize bounds the key set read from the identity provider, far above the size of a few keys
+	maxJWKSSize = 1 << 20
+)
+
+type staticKeySet map[string]*rsa.PublicKey
+
+//NewStaticKeySet returns the key set made of the keys, e.g. the ones of the JWKS file loaded with the configuration
+func NewStaticKeySet(keys map[string]*rsa.PublicKey) order.KeySet {
+	return staticKeySet(keys)
+}
+
+//Key returns the public key with the given id
+func (ks staticKeySet) Key(kid string) (interface{}, error) {
+	key, ok := ks[kid]
+	if !ok {
+		return nil, errUnknownKey(kid)
+	}
+	return key, nil
+}
+
+type urlKeySet struct {
+	url       string
+	ttl       time.Duration
+	client    *http.Client
+	mutex     sync.Mutex
+	keys      map[string]*rsa.PublicKey
+	fetchedAt time.Time
+	failedAt  time.Time
+	err       error
+	//fetching is closed once the key set being fetched is stored, nil when none is
+	fetching chan struct{}
+	logger   *slog.Logger
+}
+
+//NewURLKeySet returns the JSON Web Key Set served at the JWKS url, cached for the cache ttl. The set is fetched again
+//before the ttl when a token is signed with an unknown key, e.g. after the identity provider rotated its keys.
+func NewURLKeySet(cfg config.JWT, logger *slog.Logger) order.KeySet {
+	return &urlKeySet{
+		url:    cfg.JWKSURL,
+		ttl:    cfg.JWKSCacheTTL,
+		client: &http.Client{Timeout: 10 * time.Second},
+		logger: logger,
+	}
+}
+
+//Key returns the public key with the given id, fetching the key set when the cached one is stale. The set is
+//fetched without holding the lock, by one caller at a time while the others wait for it.
+func (ks *urlKeySet) Key(kid string) (interface{}, error) {
+	for {
+		ks.mutex.Lock()
+		key, ok := ks.keys[kid]
+		age := time.Since(ks.fetchedAt)
+		if (ok && age < ks.ttl) || (!ok && ks.keys != nil && age < jwksMinRefresh) {
+			ks.mutex.Unlock()
+			return lookupKey(key, ok, kid)
+		}
+		//Keep using the cached keys while the identity provider is unreachable, without hammering it
+		if time.Since(ks.failedAt) < jwksMinRefresh {
+			err := ks.err
+			ks.mutex.Unlock()
+			return ks.cachedKey(key, ok, err)
+		}
+		//Another caller is fetching the key set, look again once it is done
+		if ks.fetching != nil {
+			fetching := ks.fetching
+			ks.mutex.Unlock()
+			<-fetching
+			continue
+		}
+		fetching := make(chan struct{})
+		ks.fetching = fetching
+		ks.mutex.Unlock()
+
+		keys, err := ks.fetch()
+
+		ks.mutex.Lock()
+		if err != nil {
+			ks.logger.Error("Unable to fetch the JSON Web Key Set", "url", ks.url, "error", err)
+			ks.failedAt = time.Now()
+			ks.err = err
+		} else {
+			ks.keys = keys
+			ks.fetchedAt = time.Now()
+		}
+		key, ok = ks.keys[kid]
+		ks.fetching = nil
+		ks.mutex.Unlock()
+		close(fetching)
+		if err != nil {
+			return ks.cachedKey(key, ok, err)
+		}
+		return lookupKey(key, ok, kid)
+	}
+}
+
+//cachedKey returns the cached key when the key set cannot be fetched
+func (ks *urlKeySet) cachedKey(key *rsa.PublicKey, ok bool, err error) (interface{}, error) {
+	if ok {
+		return key, nil
+	}
+	return nil, order.NewUnavailable("jwks_unavailable", "Unable to fetch the keys of the identity provider", err)
+}
+
+func (ks *urlKeySet) fetch() (map[string]*rsa.PublicKey, error) {
+	res, err := ks.client.Get(ks.url)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %d fetching %s", res.StatusCode, ks.url)
+	}
+	b, err := io.ReadAll(io.LimitReader(res.Body, maxJWKSSize))
+	if err != nil {
+		return nil, err
+	}
+	return models.ParseJWKS(b)
+}
+
+func lookupKey(key *rsa.PublicKey, ok bool, kid string) (interface{}, error) {
+	if !ok {
+		return nil, errUnknownKey(kid)
+	}
+	return key, nil
+}
+
+func errUnknownKey(kid string) error {
+	return order.NewNotFound("unknown_key", "Unknown signing key "+kid)
+}
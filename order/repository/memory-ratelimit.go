@@ -0,0 +1,72 @@
+package repository
+
+import (
+	"sync"
+	"time"
+
+	"github.com/karanbhomiagit/order-service/models"
+	"github.com/karanbhomiagit/order-service/order"
+)
+
+//memorySweepInterval is how often the buckets which are full again are dropped
+const memorySweepInterval = time.Minute
+
+type memoryBucket struct {
+	tokens    float64
+	updatedAt time.Time
+	fullAt    time.Time
+}
+
+type memoryRateLimitStore struct {
+	mutex   sync.Mutex
+	buckets map[string]*memoryBucket
+	sweptAt time.Time
+}
+
+//NewMemoryRateLimitStore returns a store keeping the token buckets in memory, so each replica enforces
+//the limits on its own
+func NewMemoryRateLimitStore() order.RateLimitStore {
+	return &memoryRateLimitStore{
+		buckets: make(map[string]*memoryBucket),
+	}
+}
+
+//Take takes a token from the bucket of the key, which starts full
+func (s *memoryRateLimitStore) Take(key string, limit models.RateLimit, now time.Time) (*models.RateLimitStatus, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if now.Sub(s.sweptAt) >= memorySweepInterval {
+		s.sweep(now)
+	}
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &memoryBucket{tokens: float64(limit.Requests), updatedAt: now}
+		s.buckets[key] = b
+	}
+	tokens, status := takeToken(b.tokens, b.updatedAt, limit, now)
+	b.tokens = tokens
+	b.updatedAt = now
+	b.fullAt = now.Add(status.Reset)
+	return status, nil
+}
+
+//Check tells whether a token could be taken from the bucket of the key, without taking it
+func (s *memoryRateLimitStore) Check(key string, limit models.RateLimit, now time.Time) (*models.RateLimitStatus, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	b, ok := s.buckets[key]
+	if !ok {
+		return checkToken(float64(limit.Requests), now, limit, now), nil
+	}
+	return checkToken(b.tokens, b.updatedAt, limit, now), nil
+}
+
+//sweep drops the buckets which are full again, as they are the same as new ones
+func (s *memoryRateLimitStore) sweep(now time.Time) {
+	for key, b := range s.buckets {
+		if !b.fullAt.After(now) {
+			delete(s.buckets, key)
+		}
+	}
+	s.sweptAt = now
+}
@@ -0,0 +1,61 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/karanbhomiagit/order-service/models"
+	"github.com/karanbhomiagit/order-service/order"
+	mgo "gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+type mongoAPIKeyRepository struct {
+	Conn *MongoSession
+}
+
+const (
+	API_KEY_COLLECTION = "api_keys"
+)
+
+func NewMongoAPIKeyRepository(Conn *MongoSession) order.APIKeyRepository {
+	return &mongoAPIKeyRepository{Conn}
+}
+
+//FetchByHash finds the API key with the given hash in the database
+func (kr *mongoAPIKeyRepository) FetchByHash(hash string) (*models.APIKey, error) {
+	var key models.APIKey
+	err := timed(kr.Conn, API_KEY_COLLECTION, "find", func(c *mgo.Collection) error {
+		return c.Find(bson.M{"hash": hash}).One(&key)
+	})
+	return &key, mongoError(err, "api_key_not_found")
+}
+
+//FetchAll finds every API key in the database
+func (kr *mongoAPIKeyRepository) FetchAll() ([]models.APIKey, error) {
+	var keys []models.APIKey
+	err := timed(kr.Conn, API_KEY_COLLECTION, "find", func(c *mgo.Collection) error {
+		return c.Find(bson.M{}).Sort("_id").All(&keys)
+	})
+	return keys, mongoError(err, "api_key_not_found")
+}
+
+//Store generates a new object id and inserts the API key into the database
+func (kr *mongoAPIKeyRepository) Store(key *models.APIKey) (*models.APIKey, error) {
+	(*key).ID = bson.NewObjectId()
+	err := timed(kr.Conn, API_KEY_COLLECTION, "insert", func(c *mgo.Collection) error {
+		return c.Insert(key)
+	})
+	return key, mongoError(err, "api_key_not_found")
+}
+
+//RevokeByID marks the API key as revoked at the given time
+func (kr *mongoAPIKeyRepository) RevokeByID(id string, at time.Time) error {
+	//If the ID passed is not a valid Object ID, return error
+	if !bson.IsObjectIdHex(id) {
+		return order.NewNotFound("api_key_not_found", "Invalid Id")
+	}
+	err := timed(kr.Conn, API_KEY_COLLECTION, "update", func(c *mgo.Collection) error {
+		return c.UpdateId(bson.ObjectIdHex(id), bson.M{"$set": bson.M{"revokedAt": at}})
+	})
+	return mongoError(err, "api_key_not_found")
+}
@@ -0,0 +1,96 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/karanbhomiagit/order-service/models"
+	"github.com/karanbhomiagit/order-service/order"
+	mgo "gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+type mongoEventLogRepository struct {
+	Conn *MongoSession
+}
+
+const (
+	EVENT_LOG_COLLECTION = "event_log"
+
+	//EventLogRetained is how many of the latest events are kept, far more than replicas fall behind between polls
+	EventLogRetained = 10000
+	//eventLogAppendAttempts bounds the retries when another relay took the next number, as happens when the
+	//outbox relay lease changes hands
+	eventLogAppendAttempts = 5
+)
+
+//NewMongoEventLogRepository returns the repository for the event log, appended to by the outbox relay
+func NewMongoEventLogRepository(Conn *MongoSession) order.EventLogRepository {
+	return &mongoEventLogRepository{Conn}
+}
+
+//Append numbers the event after the last one logged and inserts it, unless it is logged already. Old events are
+//trimmed every so often.
+func (elr *mongoEventLogRepository) Append(event models.OrderEvent) error {
+	for attempt := 0; attempt < eventLogAppendAttempts; attempt++ {
+		seq, err := elr.LastSeq()
+		if err != nil {
+			return err
+		}
+		logged := models.LoggedEvent{Seq: seq + 1, Event: event, LoggedAt: time.Now()}
+		err = timed(elr.Conn, EVENT_LOG_COLLECTION, "insert", func(c *mgo.Collection) error {
+			return c.Insert(logged)
+		})
+		if err == nil {
+			return elr.trim(logged.Seq)
+		}
+		if !mgo.IsDup(err) {
+			return mongoError(err, "event_not_found")
+		}
+		//The number is the _id, so either another relay took it and the next one is tried, or the event is
+		//logged already, as the outbox relays events at least once
+		var count int
+		err = timed(elr.Conn, EVENT_LOG_COLLECTION, "count", func(c *mgo.Collection) error {
+			var err error
+			count, err = c.Find(bson.M{"event.id": event.ID}).Count()
+			return err
+		})
+		if err != nil || count > 0 {
+			return mongoError(err, "event_not_found")
+		}
+	}
+	return order.NewConflict("event_not_logged", "Another relay is appending to the event log")
+}
+
+//FetchAfter finds up to limit events logged after seq, in the order they were logged
+func (elr *mongoEventLogRepository) FetchAfter(seq int64, limit int) ([]models.LoggedEvent, error) {
+	var events []models.LoggedEvent
+	err := timed(elr.Conn, EVENT_LOG_COLLECTION, "find", func(c *mgo.Collection) error {
+		return c.Find(bson.M{"_id": bson.M{"$gt": seq}}).Sort("_id").Limit(limit).All(&events)
+	})
+	return events, mongoError(err, "event_not_found")
+}
+
+//LastSeq returns the number of the last event logged, 0 if none was
+func (elr *mongoEventLogRepository) LastSeq() (int64, error) {
+	var last models.LoggedEvent
+	err := timed(elr.Conn, EVENT_LOG_COLLECTION, "find", func(c *mgo.Collection) error {
+		return c.Find(nil).Sort("-_id").One(&last)
+	})
+	if err == mgo.ErrNotFound {
+		return 0, nil
+	}
+	return last.Seq, mongoError(err, "event_not_found")
+}
+
+//trim removes the events which are no longer retained, once every hundred events. The latest event is always
+//kept, so that numbering carries on from it.
+func (elr *mongoEventLogRepository) trim(seq int64) error {
+	if seq%100 != 0 || seq <= EventLogRetained {
+		return nil
+	}
+	err := timed(elr.Conn, EVENT_LOG_COLLECTION, "remove", func(c *mgo.Collection) error {
+		_, err := c.RemoveAll(bson.M{"_id": bson.M{"$lte": seq - EventLogRetained}})
+		return err
+	})
+	return mongoError(err, "event_not_found")
+}
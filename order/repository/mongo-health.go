@@ -0,0 +1,22 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/karanbhomiagit/order-service/order"
+)
+
+type mongoHealthChecker struct {
+	session *MongoSession
+}
+
+//NewMongoHealthChecker returns the checker of the database, which pings it
+func NewMongoHealthChecker(session *MongoSession) order.HealthChecker {
+	return &mongoHealthChecker{session}
+}
+
+//Check pings the database from a copy of the session, which takes a socket of the pool rather than reusing
+//one which may have broken since
+func (mc *mongoHealthChecker) Check(ctx context.Context) error {
+	return mongoError(cancellable(ctx, mc.session.ping), "not_found")
+}
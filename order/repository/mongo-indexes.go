@@ -0,0 +1,221 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"time"
+
+	mgo "gopkg.in/mgo.v2"
+)
+
+//MongoIndex is an index a repository relies on
+type MongoIndex struct {
+	Collection string
+	mgo.Index
+}
+
+//MongoIndexes are the indexes the repositories rely on, named so they can be told apart from the others.
+//Orders are created in the order of their _id, which doubles as their creation time, so createdAt needs no index
+//of its own. Orders record neither a courier nor coordinates, so there is no courierId or geo index until they do.
+var MongoIndexes = []MongoIndex{
+	//FetchByFilter, FetchByFilterAfter and FetchByStatusBefore, the latter two walking each status in the order of creation
+	{COLLECTION, mgo.Index{Name: "status_id", Key: []string{"status", "_id"}}},
+	//FetchByFilter within a range of distances
+	{COLLECTION, mgo.Index{Name: "distance", Key: []string{"distance"}}},
+	//FetchPending
+	{OUTBOX_COLLECTION, mgo.Index{Name: "status_nextAttemptAt", Key: []string{"status", "nextAttemptAt"}}},
+	//PruneTransactions, and resuming the transactions at startup
+	{TXN_COLLECTION, mgo.Index{Name: "s_id", Key: []string{"s", "_id"}}},
+	//FetchByHash, a hash identifying a single key
+	{API_KEY_COLLECTION, mgo.Index{Name: "hash", Key: []string{"hash"}, Unique: true}},
+	//FetchSubscribed
+	{WEBHOOK_COLLECTION, mgo.Index{Name: "owner_events", Key: []string{"owner", "events"}}},
+	//FetchDeliveries, newest first
+	{WEBHOOK_DELIVERY_COLLECTION, mgo.Index{Name: "webhookId_id", Key: []string{"webhookId", "-_id"}}},
+	//FetchPending of the webhook deliveries
+	{PENDING_DELIVERY_COLLECTION, mgo.Index{Name: "nextAttemptAt", Key: []string{"nextAttemptAt"}}},
+	//Append telling an event logged already from another relay taking the next number
+	{EVENT_LOG_COLLECTION, mgo.Index{Name: "event.id", Key: []string{"event.id"}, Unique: true}},
+	//Buckets are removed once full again
+	{RATE_LIMIT_COLLECTION, mgo.Index{Name: "expiresAt", Key: []string{"expiresAt"}, ExpireAfter: time.Second}},
+}
+
+//Statuses of the indexes
+const (
+	//IndexPresent is in the database as declared
+	IndexPresent = "present"
+	//IndexMissing is declared but not in the database
+	IndexMissing = "missing"
+	//IndexChanged is in the database under the same name but with another key or options, and needs to be
+	//dropped by hand before it is created again
+	IndexChanged = "changed"
+	//IndexUnmanaged is in the database but not declared
+	IndexUnmanaged = "unmanaged"
+	//IndexCreated was missing and has been built
+	IndexCreated = "created"
+	//IndexFailed was missing and could not be built
+	IndexFailed = "failed"
+)
+
+//IndexStatus compares an index of the database with its declaration
+type IndexStatus struct {
+	Collection string
+	Name       string
+	Key        []string
+	Status     string
+	Error      error
+}
+
+//DiffIndexes compares the declared indexes with those of the database, without changing anything
+func DiffIndexes(s *MongoSession) ([]IndexStatus, error) {
+	existing := map[string][]mgo.Index{}
+	for _, declared := range MongoIndexes {
+		if _, ok := existing[declared.Collection]; ok {
+			continue
+		}
+		var indexes []mgo.Index
+		err := timed(s, declared.Collection, "indexes", func(c *mgo.Collection) error {
+			var err error
+			indexes, err = c.Indexes()
+			return err
+		})
+		if err != nil && !isNamespaceNotFound(err) {
+			return nil, mongoError(err, "index_not_found")
+		}
+		existing[declared.Collection] = indexes
+	}
+	return diffIndexes(MongoIndexes, existing), nil
+}
+
+//diffIndexes compares the declared indexes with the existing ones of their collections
+func diffIndexes(declared []MongoIndex, existing map[string][]mgo.Index) []IndexStatus {
+	var statuses []IndexStatus
+	for _, d := range declared {
+		status := IndexStatus{Collection: d.Collection, Name: d.Name, Key: d.Key, Status: IndexMissing}
+		for _, index := range existing[d.Collection] {
+			if index.Name == d.Name {
+				status.Status = IndexChanged
+				if sameIndex(index, d.Index) {
+					status.Status = IndexPresent
+				}
+			}
+		}
+		statuses = append(statuses, status)
+	}
+	//Report the indexes nobody declared, other than the one of _id, in case they are left over
+	reported := map[string]bool{}
+	for _, d := range declared {
+		if reported[d.Collection] {
+			continue
+		}
+		reported[d.Collection] = true
+		for _, index := range existing[d.Collection] {
+			if index.Name != "_id_" && declaredIndex(declared, d.Collection, index.Name).Name == "" {
+				statuses = append(statuses, IndexStatus{Collection: d.Collection, Name: index.Name, Key: index.Key, Status: IndexUnmanaged})
+			}
+		}
+	}
+	return statuses
+}
+
+//requiredIndexes are the indexes the repositories are not correct without, rather than only slower: Append
+//relies on the unique event ids to log every event once
+var requiredIndexes = map[string][]string{
+	EVENT_LOG_COLLECTION: {"event.id"},
+}
+
+//EnsureIndexes builds the declared indexes missing from the database in the background, so the
+//collections stay available meanwhile, and logs the status of each index. Changed indexes are left alone.
+func EnsureIndexes(s *MongoSession, logger *slog.Logger) ([]IndexStatus, error) {
+	statuses, err := DiffIndexes(s)
+	if err != nil {
+		return nil, err
+	}
+	return ensureIndexes(statuses, func(status IndexStatus) IndexStatus {
+		return buildIndex(s, status, logger)
+	}, logger), nil
+}
+
+//ensureIndexes builds the missing indexes of the statuses with build, which returns their new status, and logs
+//the changed and unmanaged ones
+func ensureIndexes(statuses []IndexStatus, build func(IndexStatus) IndexStatus, logger *slog.Logger) []IndexStatus {
+	for i, status := range statuses {
+		switch status.Status {
+		case IndexMissing:
+			statuses[i] = build(status)
+		case IndexChanged:
+			logger.Warn("Index differs from its declaration, drop it to build it again", "collection", status.Collection, "index", status.Name)
+		case IndexUnmanaged:
+			logger.Info("Index is not declared", "collection", status.Collection, "index", status.Name)
+		}
+	}
+	return statuses
+}
+
+//EnsureRequiredIndexes builds the required indexes missing from the database, and returns an error unless they
+//are all present as declared. It is called before the workers relying on them start.
+func EnsureRequiredIndexes(s *MongoSession, logger *slog.Logger) error {
+	statuses, err := DiffIndexes(s)
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for _, status := range statuses {
+		if !slices.Contains(requiredIndexes[status.Collection], status.Name) {
+			continue
+		}
+		switch status.Status {
+		case IndexMissing:
+			if built := buildIndex(s, status, logger); built.Status == IndexFailed {
+				errs = append(errs, fmt.Errorf("index %s of %s: %w", status.Name, status.Collection, built.Error))
+			}
+		case IndexChanged:
+			errs = append(errs, fmt.Errorf("index %s of %s differs from its declaration, drop it to build it again", status.Name, status.Collection))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+//buildIndex builds the missing index in the background and returns its new status
+func buildIndex(s *MongoSession, status IndexStatus, logger *slog.Logger) IndexStatus {
+	index := declaredIndex(MongoIndexes, status.Collection, status.Name)
+	index.Background = true
+	start := time.Now()
+	err := timed(s, status.Collection, "create_index", func(c *mgo.Collection) error {
+		return c.EnsureIndex(index)
+	})
+	if err != nil {
+		status.Status, status.Error = IndexFailed, mongoError(err, "index_not_found")
+		logger.Error("Unable to build index", "collection", status.Collection, "index", status.Name, "error", err)
+		return status
+	}
+	status.Status = IndexCreated
+	logger.Info("Built index", "collection", status.Collection, "index", status.Name, "duration_ms", time.Since(start).Milliseconds())
+	return status
+}
+
+//sameIndex reports whether the index of the database has the key and options of the declared one
+func sameIndex(index mgo.Index, declared mgo.Index) bool {
+	return slices.Equal(index.Key, declared.Key) &&
+		index.Unique == declared.Unique &&
+		index.Sparse == declared.Sparse &&
+		index.ExpireAfter == declared.ExpireAfter
+}
+
+//declaredIndex returns the declaration of the named index of the collection, the zero index when not declared
+func declaredIndex(declared []MongoIndex, collection string, name string) mgo.Index {
+	for _, d := range declared {
+		if d.Collection == collection && d.Name == name {
+			return d.Index
+		}
+	}
+	return mgo.Index{}
+}
+
+//isNamespaceNotFound reports whether the collection does not exist yet, and so has no index
+func isNamespaceNotFound(err error) bool {
+	qerr, ok := err.(*mgo.QueryError)
+	return ok && qerr.Code == 26
+}
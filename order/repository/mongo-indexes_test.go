@@ -0,0 +1,94 @@
+package repository
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	mgo "gopkg.in/mgo.v2"
+)
+
+var declared = []MongoIndex{
+	{COLLECTION, mgo.Index{Name: "status_id", Key: []string{"status", "_id"}}},
+	{COLLECTION, mgo.Index{Name: "distance", Key: []string{"distance"}}},
+	{RATE_LIMIT_COLLECTION, mgo.Index{Name: "expiresAt", Key: []string{"expiresAt"}, ExpireAfter: time.Second}},
+}
+
+/*
+	Actual test functions
+*/
+
+func TestDiffIndexes(t *testing.T) {
+
+	t.Run("Should report every declared index missing from an empty database", func(t *testing.T) {
+		assert := assert.New(t)
+		statuses := diffIndexes(declared, map[string][]mgo.Index{})
+		assert.Equal([]IndexStatus{
+			{Collection: COLLECTION, Name: "status_id", Key: []string{"status", "_id"}, Status: IndexMissing},
+			{Collection: COLLECTION, Name: "distance", Key: []string{"distance"}, Status: IndexMissing},
+			{Collection: RATE_LIMIT_COLLECTION, Name: "expiresAt", Key: []string{"expiresAt"}, Status: IndexMissing},
+		}, statuses)
+	})
+
+	t.Run("Should tell present, changed and unmanaged indexes apart", func(t *testing.T) {
+		assert := assert.New(t)
+		statuses := diffIndexes(declared, map[string][]mgo.Index{
+			COLLECTION: {
+				{Name: "_id_", Key: []string{"_id"}},
+				{Name: "status_id", Key: []string{"status", "_id"}},
+				{Name: "distance", Key: []string{"-distance"}},
+				{Name: "owner", Key: []string{"owner"}},
+			},
+			RATE_LIMIT_COLLECTION: {
+				{Name: "expiresAt", Key: []string{"expiresAt"}, ExpireAfter: time.Hour},
+			},
+		})
+		assert.Equal([]IndexStatus{
+			{Collection: COLLECTION, Name: "status_id", Key: []string{"status", "_id"}, Status: IndexPresent},
+			{Collection: COLLECTION, Name: "distance", Key: []string{"distance"}, Status: IndexChanged},
+			{Collection: RATE_LIMIT_COLLECTION, Name: "expiresAt", Key: []string{"expiresAt"}, Status: IndexChanged},
+			{Collection: COLLECTION, Name: "owner", Key: []string{"owner"}, Status: IndexUnmanaged},
+		}, statuses)
+	})
+}
+
+func TestEnsureIndexes(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	t.Run("Should build only the missing indexes", func(t *testing.T) {
+		assert := assert.New(t)
+		var built []string
+		statuses := ensureIndexes([]IndexStatus{
+			{Collection: COLLECTION, Name: "status_id", Status: IndexPresent},
+			{Collection: COLLECTION, Name: "distance", Status: IndexMissing},
+			{Collection: RATE_LIMIT_COLLECTION, Name: "expiresAt", Status: IndexChanged},
+			{Collection: COLLECTION, Name: "owner", Status: IndexUnmanaged},
+		}, func(status IndexStatus) IndexStatus {
+			built = append(built, status.Name)
+			status.Status = IndexCreated
+			return status
+		}, logger)
+		assert.Equal([]string{"distance"}, built)
+		assert.Equal([]IndexStatus{
+			{Collection: COLLECTION, Name: "status_id", Status: IndexPresent},
+			{Collection: COLLECTION, Name: "distance", Status: IndexCreated},
+			{Collection: RATE_LIMIT_COLLECTION, Name: "expiresAt", Status: IndexChanged},
+			{Collection: COLLECTION, Name: "owner", Status: IndexUnmanaged},
+		}, statuses)
+	})
+
+	t.Run("Should report the indexes which could not be built", func(t *testing.T) {
+		assert := assert.New(t)
+		err := errors.New("connection lost")
+		statuses := ensureIndexes([]IndexStatus{
+			{Collection: COLLECTION, Name: "distance", Status: IndexMissing},
+		}, func(status IndexStatus) IndexStatus {
+			status.Status, status.Error = IndexFailed, err
+			return status
+		}, logger)
+		assert.Equal([]IndexStatus{{Collection: COLLECTION, Name: "distance", Status: IndexFailed, Error: err}}, statuses)
+	})
+}
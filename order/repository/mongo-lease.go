@@ -0,0 +1,58 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/karanbhomiagit/order-service/order"
+	mgo "gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+type mongoLeaseRepository struct {
+	Conn *MongoSession
+}
+
+const (
+	LEASE_COLLECTION = "leases"
+)
+
+func NewMongoLeaseRepository(Conn *MongoSession) order.LeaseRepository {
+	return &mongoLeaseRepository{Conn}
+}
+
+//Acquire takes or renews the named lease for the owner, returning false if another owner currently holds it
+func (lr *mongoLeaseRepository) Acquire(name string, owner string, ttl time.Duration) (bool, error) {
+	now := time.Now()
+	//Only match the lease if it has expired or is already held by this owner
+	selector := bson.M{
+		"_id": name,
+		"$or": []bson.M{
+			{"expiresAt": bson.M{"$lt": now}},
+			{"owner": owner},
+		},
+	}
+	update := bson.M{"$set": bson.M{"owner": owner, "expiresAt": now.Add(ttl)}}
+	err := timed(lr.Conn, LEASE_COLLECTION, "upsert", func(c *mgo.Collection) error {
+		_, err := c.Upsert(selector, update)
+		return err
+	})
+	//A duplicate key means the upsert tried to create a lease which is held by someone else
+	if mgo.IsDup(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, mongoError(err, "lease_not_found")
+	}
+	return true, nil
+}
+
+//Release gives up the named lease if it is held by the owner
+func (lr *mongoLeaseRepository) Release(name string, owner string) error {
+	err := timed(lr.Conn, LEASE_COLLECTION, "remove", func(c *mgo.Collection) error {
+		return c.Remove(bson.M{"_id": name, "owner": owner})
+	})
+	if err == mgo.ErrNotFound {
+		return nil
+	}
+	return mongoError(err, "lease_not_found")
+}
@@ -0,0 +1,77 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/karanbhomiagit/order-service/order"
+	mgo "gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+	"gopkg.in/mgo.v2/txn"
+)
+
+//MongoMigrations are the migrations of the database, by increasing version. Databases record the versions
+//they applied, so a released migration is never changed nor its version reused.
+var MongoMigrations = []Migration{
+	{
+		Version:     1,
+		Description: "Record when orders were created and last updated",
+		Up:          backfillOrderTimestamps,
+		Down:        removeOrderTimestamps,
+	},
+	{
+		Version:     2,
+		Description: "Give the webhooks created before their owner was recorded to the admin",
+		Up:          ownWebhooks,
+		//Older versions of the service ignore the owners
+		Down: func(context.Context, *mgo.Database) error { return nil },
+	},
+}
+
+//timestampsBackfilled marks the orders whose timestamps were set by backfillOrderTimestamps
+const timestampsBackfilled = "timestampsBackfilled"
+
+//backfillOrderTimestamps sets the creation time of the orders without one from their _id, which is
+//also taken as the time they were last updated, and marks them as backfilled
+func backfillOrderTimestamps(ctx context.Context, db *mgo.Database) error {
+	return updateOrders(ctx, db, bson.M{"createdAt": bson.M{"$exists": false}}, func(id bson.ObjectId) bson.M {
+		createdAt := id.Time().UTC()
+		return bson.M{"$set": bson.M{"createdAt": createdAt, "updatedAt": createdAt, timestampsBackfilled: true}}
+	})
+}
+
+//removeOrderTimestamps removes the timestamps backfilled by backfillOrderTimestamps, keeping those the service
+//recorded when creating the orders
+func removeOrderTimestamps(ctx context.Context, db *mgo.Database) error {
+	return updateOrders(ctx, db, bson.M{timestampsBackfilled: true}, func(bson.ObjectId) bson.M {
+		return bson.M{"$unset": bson.M{"createdAt": "", "updatedAt": "", timestampsBackfilled: ""}}
+	})
+}
+
+//ownWebhooks gives the webhooks without an owner to the admin, so they keep receiving the events of every order
+func ownWebhooks(ctx context.Context, db *mgo.Database) error {
+	_, err := db.C(WEBHOOK_COLLECTION).UpdateAll(bson.M{"owner": bson.M{"$exists": false}}, bson.M{"$set": bson.M{"owner": order.AdminSubject}})
+	return err
+}
+
+//updateOrders updates each order matching the selector, one transaction at a time as orders are only ever
+//changed through the transaction runner. Orders no longer matching the selector once their turn comes are
+//left alone. It stops once ctx is done.
+func updateOrders(ctx context.Context, db *mgo.Database, selector bson.M, update func(id bson.ObjectId) bson.M) error {
+	runner := txn.NewRunner(db.C(TXN_COLLECTION))
+	var o struct {
+		ID bson.ObjectId `bson:"_id"`
+	}
+	iter := db.C(COLLECTION).Find(selector).Select(bson.M{"_id": 1}).Iter()
+	for iter.Next(&o) {
+		if err := ctx.Err(); err != nil {
+			iter.Close()
+			return err
+		}
+		ops := []txn.Op{{C: COLLECTION, Id: o.ID, Assert: selector, Update: update(o.ID)}}
+		if err := runner.Run(ops, "", nil); err != nil && err != txn.ErrAborted {
+			iter.Close()
+			return err
+		}
+	}
+	return iter.Close()
+}
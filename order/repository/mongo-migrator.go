@@ -0,0 +1,301 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/karanbhomiagit/order-service/config"
+	"github.com/karanbhomiagit/order-service/order"
+	mgo "gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+//Migration changes the documents of the database from one version of the schema to the next, and back.
+//Steps are not atomic, so they only change the documents not changed yet and can be run again once failed.
+//They stop once ctx is done, which happens when the lease of the migrations is lost.
+type Migration struct {
+	Version     int
+	Description string
+	Up          func(ctx context.Context, db *mgo.Database) error
+	//Down is nil when the migration cannot be rolled back
+	Down func(ctx context.Context, db *mgo.Database) error
+}
+
+//MongoMigrator applies the migrations to the database and rolls them back, recording the versions applied
+//in a ledger. The replicas take turns through a lease, so only one migrates the database at a time.
+type MongoMigrator struct {
+	store           migrationStore
+	leaseRepository order.LeaseRepository
+	migrations      []Migration
+	config          config.Migrations
+	owner           string
+	logger          *slog.Logger
+}
+
+//Statuses of the migrations
+const (
+	MigrationApplied = "applied"
+	MigrationPending = "pending"
+	//MigrationUnknown was applied by a newer version of the service
+	MigrationUnknown = "unknown"
+)
+
+//MigrationStatus tells whether a migration was applied to the database
+type MigrationStatus struct {
+	Version     int
+	Description string
+	Status      string
+	//AppliedAt is zero unless applied
+	AppliedAt time.Time
+}
+
+type appliedMigration struct {
+	Version     int       `bson:"_id"`
+	Description string    `bson:"description"`
+	AppliedAt   time.Time `bson:"appliedAt"`
+}
+
+//migrationStore keeps the ledger of the migrations applied to the database, and runs their steps on it
+type migrationStore interface {
+	Applied() ([]appliedMigration, error)
+	Record(appliedMigration) error
+	Remove(version int) error
+	Run(ctx context.Context, direction string, fn func(context.Context, *mgo.Database) error) error
+}
+
+//mongoMigrationStore keeps the ledger in the migrations collection
+type mongoMigrationStore struct {
+	Conn *MongoSession
+}
+
+func (ms *mongoMigrationStore) Applied() ([]appliedMigration, error) {
+	var ledger []appliedMigration
+	err := timed(ms.Conn, MIGRATION_COLLECTION, "find", func(c *mgo.Collection) error {
+		return c.Find(nil).Sort("_id").All(&ledger)
+	})
+	if err != nil {
+		return nil, mongoError(err, "migration_not_found")
+	}
+	return ledger, nil
+}
+
+func (ms *mongoMigrationStore) Record(a appliedMigration) error {
+	err := timed(ms.Conn, MIGRATION_COLLECTION, "insert", func(c *mgo.Collection) error {
+		return c.Insert(a)
+	})
+	return mongoError(err, "migration_not_found")
+}
+
+func (ms *mongoMigrationStore) Remove(version int) error {
+	err := timed(ms.Conn, MIGRATION_COLLECTION, "remove", func(c *mgo.Collection) error {
+		return c.RemoveId(version)
+	})
+	return mongoError(err, "migration_not_found")
+}
+
+func (ms *mongoMigrationStore) Run(ctx context.Context, direction string, fn func(context.Context, *mgo.Database) error) error {
+	return timed(ms.Conn, MIGRATION_COLLECTION, direction, func(c *mgo.Collection) error {
+		return fn(ctx, c.Database)
+	})
+}
+
+const (
+	MIGRATION_COLLECTION = "migrations"
+	MigrationLeaseName   = "migrations"
+	//migrationLockPoll is how often a replica checks whether the one migrating is done
+	migrationLockPoll = time.Second
+)
+
+//NewMongoMigrator returns the migrator of the database applying the migrations, sorted by increasing version
+func NewMongoMigrator(Conn *MongoSession, lr order.LeaseRepository, migrations []Migration, cfg config.Migrations, logger *slog.Logger) *MongoMigrator {
+	hostname, _ := os.Hostname()
+	return &MongoMigrator{
+		store:           &mongoMigrationStore{Conn},
+		leaseRepository: lr,
+		migrations:      migrations,
+		config:          cfg,
+		owner:           hostname + "-" + bson.NewObjectId().Hex(),
+		logger:          logger,
+	}
+}
+
+//Status returns the status of every migration, along with those applied by a newer version of the service
+func (m *MongoMigrator) Status() ([]MigrationStatus, error) {
+	applied, err := m.applied()
+	if err != nil {
+		return nil, err
+	}
+	var statuses []MigrationStatus
+	for _, migration := range m.migrations {
+		status := MigrationStatus{Version: migration.Version, Description: migration.Description, Status: MigrationPending}
+		if a, ok := applied[migration.Version]; ok {
+			status.Status, status.AppliedAt = MigrationApplied, a.AppliedAt
+			delete(applied, migration.Version)
+		}
+		statuses = append(statuses, status)
+	}
+	for _, a := range applied {
+		statuses = append(statuses, MigrationStatus{Version: a.Version, Description: a.Description, Status: MigrationUnknown, AppliedAt: a.AppliedAt})
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Version < statuses[j].Version })
+	return statuses, nil
+}
+
+//Up applies the pending migrations up to the target version, every one of them when target is 0. It waits
+//for the replica migrating the database, if any, to be done.
+func (m *MongoMigrator) Up(ctx context.Context, target int) error {
+	if err := m.lock(ctx); err != nil {
+		return err
+	}
+	defer m.leaseRepository.Release(MigrationLeaseName, m.owner)
+	applied, err := m.applied()
+	if err != nil {
+		return err
+	}
+	for _, migration := range m.migrations {
+		if _, ok := applied[migration.Version]; ok || target > 0 && migration.Version > target {
+			continue
+		}
+		if err := m.step(ctx, migration, "up", migration.Up); err != nil {
+			return err
+		}
+		if err := m.store.Record(appliedMigration{migration.Version, migration.Description, mongoNow()}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//Down rolls back the applied migrations above the target version, newest first
+func (m *MongoMigrator) Down(ctx context.Context, target int) error {
+	if err := m.lock(ctx); err != nil {
+		return err
+	}
+	defer m.leaseRepository.Release(MigrationLeaseName, m.owner)
+	applied, err := m.applied()
+	if err != nil {
+		return err
+	}
+	//Migrations applied by a newer version of the service can only be rolled back by it
+	known := make(map[int]bool, len(m.migrations))
+	for _, migration := range m.migrations {
+		known[migration.Version] = true
+	}
+	for version, a := range applied {
+		if !known[version] && version > target {
+			return fmt.Errorf("migration %d (%s) was applied by a newer version of the service, which has to roll it back", version, a.Description)
+		}
+	}
+	for i := len(m.migrations) - 1; i >= 0; i-- {
+		migration := m.migrations[i]
+		if _, ok := applied[migration.Version]; !ok || migration.Version <= target {
+			continue
+		}
+		if migration.Down == nil {
+			return fmt.Errorf("migration %d (%s) cannot be rolled back", migration.Version, migration.Description)
+		}
+		if err := m.step(ctx, migration, "down", migration.Down); err != nil {
+			return err
+		}
+		if err := m.store.Remove(migration.Version); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//step runs a step of the migration, once sure the lease is still held. The lease is renewed while the step
+//runs, which is cancelled as soon as another replica holds it.
+func (m *MongoMigrator) step(ctx context.Context, migration Migration, direction string, fn func(context.Context, *mgo.Database) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	held, err := m.leaseRepository.Acquire(MigrationLeaseName, m.owner, m.config.MigrationLockTTL)
+	if err != nil {
+		return err
+	}
+	if !held {
+		return fmt.Errorf("lost the lease of the migrations before migration %d, another replica is migrating", migration.Version)
+	}
+	stepCtx, cancel := context.WithCancelCause(ctx)
+	renewed := make(chan struct{})
+	go func() {
+		defer close(renewed)
+		m.renew(stepCtx, cancel, migration.Version)
+	}()
+	start := time.Now()
+	err = m.store.Run(stepCtx, direction, fn)
+	//The lease is not renewed once released
+	cancel(nil)
+	<-renewed
+	if err != nil {
+		if cause := context.Cause(stepCtx); ctx.Err() == nil && cause != context.Canceled {
+			err = cause
+		}
+		return fmt.Errorf("migration %d (%s) failed %s: %w", migration.Version, migration.Description, direction, err)
+	}
+	m.logger.Info("Migrated the database", "version", migration.Version, "description", migration.Description,
+		"direction", direction, "duration_ms", time.Since(start).Milliseconds())
+	return nil
+}
+
+//renew renews the lease of the migrations every third of its ttl until ctx is done, cancelling it with the
+//cause once another replica holds the lease. Failures to reach the database are retried at the next tick.
+func (m *MongoMigrator) renew(ctx context.Context, cancel context.CancelCauseFunc, version int) {
+	ticker := time.NewTicker(m.config.MigrationLockTTL / 3)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+		held, err := m.leaseRepository.Acquire(MigrationLeaseName, m.owner, m.config.MigrationLockTTL)
+		if err != nil {
+			m.logger.Error("Unable to renew the lease of the migrations", "version", version, "error", err)
+			continue
+		}
+		if !held {
+			cancel(fmt.Errorf("lost the lease of the migrations during migration %d, another replica is migrating", version))
+			return
+		}
+	}
+}
+
+//lock takes the lease of the migrations, waiting for the replica holding it to be done
+func (m *MongoMigrator) lock(ctx context.Context) error {
+	for attempt := 0; ; attempt++ {
+		acquired, err := m.leaseRepository.Acquire(MigrationLeaseName, m.owner, m.config.MigrationLockTTL)
+		if err != nil {
+			return err
+		}
+		if acquired {
+			return nil
+		}
+		if attempt == 0 {
+			m.logger.Info("Waiting for another replica to migrate the database")
+		}
+		select {
+		case <-time.After(migrationLockPoll):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+//applied returns the migrations recorded in the ledger, by version
+func (m *MongoMigrator) applied() (map[int]appliedMigration, error) {
+	ledger, err := m.store.Applied()
+	if err != nil {
+		return nil, err
+	}
+	applied := make(map[int]appliedMigration, len(ledger))
+	for _, a := range ledger {
+		applied[a.Version] = a
+	}
+	return applied, nil
+}
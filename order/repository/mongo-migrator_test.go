@@ -0,0 +1,219 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/karanbhomiagit/order-service/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	mgo "gopkg.in/mgo.v2"
+)
+
+type MockedLeaseRepository struct {
+	mock.Mock
+}
+
+func (lr *MockedLeaseRepository) Acquire(name string, owner string, ttl time.Duration) (bool, error) {
+	args := lr.Called(name, owner, ttl)
+	return args.Bool(0), args.Error(1)
+}
+
+func (lr *MockedLeaseRepository) Release(name string, owner string) error {
+	args := lr.Called(name, owner)
+	return args.Error(0)
+}
+
+//memoryMigrationStore keeps the ledger in memory, and records the steps it runs
+type memoryMigrationStore struct {
+	ledger map[int]appliedMigration
+	steps  []string
+}
+
+func (ms *memoryMigrationStore) Applied() ([]appliedMigration, error) {
+	var ledger []appliedMigration
+	for _, a := range ms.ledger {
+		ledger = append(ledger, a)
+	}
+	sort.Slice(ledger, func(i, j int) bool { return ledger[i].Version < ledger[j].Version })
+	return ledger, nil
+}
+
+func (ms *memoryMigrationStore) Record(a appliedMigration) error {
+	ms.ledger[a.Version] = a
+	return nil
+}
+
+func (ms *memoryMigrationStore) Remove(version int) error {
+	delete(ms.ledger, version)
+	return nil
+}
+
+func (ms *memoryMigrationStore) Run(ctx context.Context, direction string, fn func(context.Context, *mgo.Database) error) error {
+	return fn(ctx, nil)
+}
+
+var appliedAt = time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+//newMigrator returns a migrator of migrations 1 to 3, the second failing to go up and the third not rolling back,
+//whose lease is always held. Every step is recorded in the store.
+func newMigrator(ledger ...int) (*MongoMigrator, *memoryMigrationStore, *MockedLeaseRepository) {
+	store := &memoryMigrationStore{ledger: map[int]appliedMigration{}}
+	for _, version := range ledger {
+		store.ledger[version] = appliedMigration{version, "", appliedAt}
+	}
+	step := func(name string, err error) func(context.Context, *mgo.Database) error {
+		return func(context.Context, *mgo.Database) error {
+			store.steps = append(store.steps, name)
+			return err
+		}
+	}
+	leaseObj := new(MockedLeaseRepository)
+	leaseObj.On("Acquire", MigrationLeaseName, "owner", time.Minute).Return(true, nil)
+	leaseObj.On("Release", MigrationLeaseName, "owner").Return(nil)
+	return &MongoMigrator{
+		store:           store,
+		leaseRepository: leaseObj,
+		migrations: []Migration{
+			{Version: 1, Description: "first", Up: step("up 1", nil), Down: step("down 1", nil)},
+			{Version: 2, Description: "second", Up: step("up 2", errors.New("connection lost")), Down: step("down 2", nil)},
+			{Version: 3, Description: "third", Up: step("up 3", nil)},
+		},
+		config: config.Migrations{MigrationLockTTL: time.Minute},
+		owner:  "owner",
+		logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+	}, store, leaseObj
+}
+
+/*
+	Actual test functions
+*/
+
+func TestMigratorStatus(t *testing.T) {
+
+	t.Run("Should list the pending, applied and unknown migrations by version", func(t *testing.T) {
+		assert := assert.New(t)
+		migrator, store, _ := newMigrator(1, 4)
+		store.ledger[4] = appliedMigration{4, "newer", appliedAt}
+
+		statuses, err := migrator.Status()
+		assert.NoError(err)
+		assert.Equal([]MigrationStatus{
+			{Version: 1, Description: "first", Status: MigrationApplied, AppliedAt: appliedAt},
+			{Version: 2, Description: "second", Status: MigrationPending},
+			{Version: 3, Description: "third", Status: MigrationPending},
+			{Version: 4, Description: "newer", Status: MigrationUnknown, AppliedAt: appliedAt},
+		}, statuses)
+	})
+}
+
+func TestMigratorUp(t *testing.T) {
+
+	t.Run("Should apply the pending migrations up to the target and record them", func(t *testing.T) {
+		assert := assert.New(t)
+		migrator, store, leaseObj := newMigrator()
+
+		assert.NoError(migrator.Up(context.Background(), 1))
+		assert.Equal([]string{"up 1"}, store.steps)
+		assert.Contains(store.ledger, 1)
+		assert.Equal("first", store.ledger[1].Description)
+		leaseObj.AssertCalled(t, "Release", MigrationLeaseName, "owner")
+	})
+
+	t.Run("Should stop at the failing migration without recording it", func(t *testing.T) {
+		assert := assert.New(t)
+		migrator, store, leaseObj := newMigrator(1)
+
+		err := migrator.Up(context.Background(), 0)
+		assert.ErrorContains(err, "migration 2 (second) failed up: connection lost")
+		assert.Equal([]string{"up 2"}, store.steps)
+		assert.NotContains(store.ledger, 2)
+		assert.NotContains(store.ledger, 3)
+		leaseObj.AssertCalled(t, "Release", MigrationLeaseName, "owner")
+	})
+
+	t.Run("Should not apply anything once another replica holds the lease", func(t *testing.T) {
+		assert := assert.New(t)
+		migrator, store, leaseObj := newMigrator()
+		leaseObj.ExpectedCalls = nil
+		leaseObj.On("Acquire", MigrationLeaseName, "owner", time.Minute).Return(true, nil).Once()
+		leaseObj.On("Acquire", MigrationLeaseName, "owner", time.Minute).Return(false, nil)
+		leaseObj.On("Release", MigrationLeaseName, "owner").Return(nil)
+
+		err := migrator.Up(context.Background(), 0)
+		assert.ErrorContains(err, "lost the lease of the migrations before migration 1")
+		assert.Empty(store.steps)
+		assert.Empty(store.ledger)
+	})
+
+	t.Run("Should renew the lease while a migration runs and stop it once lost", func(t *testing.T) {
+		assert := assert.New(t)
+		migrator, store, leaseObj := newMigrator()
+		leaseObj.ExpectedCalls = nil
+		migrator.config.MigrationLockTTL = 30 * time.Millisecond
+		leaseObj.On("Acquire", MigrationLeaseName, "owner", 30*time.Millisecond).Return(true, nil).Times(3)
+		leaseObj.On("Acquire", MigrationLeaseName, "owner", 30*time.Millisecond).Return(false, nil)
+		leaseObj.On("Release", MigrationLeaseName, "owner").Return(nil)
+		migrator.migrations[0].Up = func(ctx context.Context, db *mgo.Database) error {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(time.Second):
+				return nil
+			}
+		}
+
+		err := migrator.Up(context.Background(), 1)
+		assert.ErrorContains(err, "migration 1 (first) failed up: lost the lease of the migrations during migration 1")
+		assert.Empty(store.ledger)
+		//Locked, checked before the step, then renewed once before it was lost
+		leaseObj.AssertNumberOfCalls(t, "Acquire", 4)
+	})
+}
+
+func TestMigratorDown(t *testing.T) {
+
+	t.Run("Should roll back the migrations above the target, newest first, and remove them from the ledger", func(t *testing.T) {
+		assert := assert.New(t)
+		migrator, store, _ := newMigrator(1, 2)
+
+		assert.NoError(migrator.Down(context.Background(), 0))
+		assert.Equal([]string{"down 2", "down 1"}, store.steps)
+		assert.Empty(store.ledger)
+	})
+
+	t.Run("Should keep the migrations up to the target", func(t *testing.T) {
+		assert := assert.New(t)
+		migrator, store, _ := newMigrator(1, 2)
+
+		assert.NoError(migrator.Down(context.Background(), 1))
+		assert.Equal([]string{"down 2"}, store.steps)
+		assert.Contains(store.ledger, 1)
+		assert.NotContains(store.ledger, 2)
+	})
+
+	t.Run("Should return error for a migration which cannot be rolled back", func(t *testing.T) {
+		assert := assert.New(t)
+		migrator, store, _ := newMigrator(1, 2, 3)
+
+		err := migrator.Down(context.Background(), 0)
+		assert.EqualError(err, "migration 3 (third) cannot be rolled back")
+		assert.Empty(store.steps)
+		assert.Len(store.ledger, 3)
+	})
+
+	t.Run("Should return error for a migration applied by a newer version", func(t *testing.T) {
+		assert := assert.New(t)
+		migrator, store, _ := newMigrator(1, 4)
+		store.ledger[4] = appliedMigration{4, "newer", appliedAt}
+
+		err := migrator.Down(context.Background(), 0)
+		assert.EqualError(err, "migration 4 (newer) was applied by a newer version of the service, which has to roll it back")
+		assert.Empty(store.steps)
+	})
+}
@@ -1,56 +1,194 @@
 package repository
 
 import (
-	"errors"
+	"context"
+	"time"
 
 	"github.com/karanbhomiagit/order-service/models"
 	"github.com/karanbhomiagit/order-service/order"
 	mgo "gopkg.in/mgo.v2"
 	"gopkg.in/mgo.v2/bson"
+	"gopkg.in/mgo.v2/txn"
 )
 
 type mongoOrderRepository struct {
-	Conn *mgo.Database
+	Conn *MongoSession
 }
 
 const (
-	COLLECTION = "orders"
+	COLLECTION     = "orders"
+	TXN_COLLECTION = "txns"
+	//States of the transactions which are over, as stored by the runner
+	TXN_ABORTED = 5
+	TXN_APPLIED = 6
 )
 
-func NewMongoOrderRepository(Conn *mgo.Database) order.Repository {
+var (
+	errOrderNotFound = order.NewNotFound("order_not_found", "not found")
+	errOrderExists   = order.NewConflict("order_exists", "Order already exists")
+)
+
+func NewMongoOrderRepository(Conn *MongoSession) order.Repository {
 	return &mongoOrderRepository{Conn}
 }
 
 //FetchByID validates the provided ID and finds the corresponding document in the database
-func (or *mongoOrderRepository) FetchByID(id string) (*models.Order, error) {
-	var order models.Order
+func (or *mongoOrderRepository) FetchByID(ctx context.Context, id string) (*models.Order, error) {
+	var o models.Order
 	//If the ID passed is not a valid Object ID, return error
 	isValidID := bson.IsObjectIdHex(id)
 	if isValidID != true {
-		return nil, errors.New("Invalid Id")
+		return nil, order.NewNotFound("order_not_found", "Invalid Id")
 	}
 	//Find document in DB by ID
-	err := or.Conn.C(COLLECTION).FindId(bson.ObjectIdHex(id)).One(&order)
-	return &order, err
+	err := traced(ctx, or.Conn, COLLECTION, "find", func(c *mgo.Collection) error {
+		return c.FindId(bson.ObjectIdHex(id)).One(&o)
+	})
+	//An abandoned operation may still be decoding into o
+	if err != nil {
+		return nil, mongoError(err, "order_not_found")
+	}
+	return &o, nil
 }
 
-//UpdateByID finds the corresponding document in the database and updates it
-func (or *mongoOrderRepository) UpdateByID(order *models.Order) error {
-	//Update document in DB by ID
-	return or.Conn.C(COLLECTION).UpdateId((*order).ID, order)
+//FetchByRange finds the corresponding documents in the database for a particular range
+func (or *mongoOrderRepository) FetchByRange(ctx context.Context, skip int, limit int) ([]models.Order, error) {
+	var orders []models.Order
+	//Find documents
+	err := traced(ctx, or.Conn, COLLECTION, "find", func(c *mgo.Collection) error {
+		return c.Find(bson.M{}).Skip(skip).Limit(limit).All(&orders)
+	})
+	if err != nil {
+		return nil, mongoError(err, "order_not_found")
+	}
+	return orders, nil
 }
 
-//FetchByRange finds the corresponding documents in the database for a particular range
-func (or *mongoOrderRepository) FetchByRange(skip int, limit int) ([]models.Order, error) {
+//FetchByFilter finds the corresponding documents in the database matching the filter, for a particular range
+func (or *mongoOrderRepository) FetchByFilter(ctx context.Context, filter *models.OrderFilter, skip int, limit int) ([]models.Order, error) {
 	var orders []models.Order
+	query := filterQuery(filter)
 	//Find documents
-	err := or.Conn.C(COLLECTION).Find(bson.M{}).Skip(skip).Limit(limit).All(&orders)
-	return orders, err
+	err := traced(ctx, or.Conn, COLLECTION, "find", func(c *mgo.Collection) error {
+		return c.Find(query).Skip(skip).Limit(limit).All(&orders)
+	})
+	if err != nil {
+		return nil, mongoError(err, "order_not_found")
+	}
+	return orders, nil
 }
 
-//Store generates a new object id and inserts the document into the database
-func (or *mongoOrderRepository) Store(order *models.Order) (*models.Order, error) {
+//FetchByFilterAfter finds up to limit documents matching the filter whose id comes after the given one, or from
+//the first when it is empty, in the order of their ids. Unlike skipping, walking the ids neither misses nor repeats
+//documents inserted or removed along the way.
+func (or *mongoOrderRepository) FetchByFilterAfter(ctx context.Context, filter *models.OrderFilter, after string, limit int) ([]models.Order, error) {
+	var orders []models.Order
+	query := filterQuery(filter)
+	if after != "" {
+		//If the ID passed is not a valid Object ID, return error
+		if !bson.IsObjectIdHex(after) {
+			return nil, order.NewInvalidArgument("invalid_cursor", "Invalid Id "+after)
+		}
+		query["_id"] = bson.M{"$gt": bson.ObjectIdHex(after)}
+	}
+	err := traced(ctx, or.Conn, COLLECTION, "find", func(c *mgo.Collection) error {
+		return c.Find(query).Sort("_id").Limit(limit).All(&orders)
+	})
+	if err != nil {
+		return nil, mongoError(err, "order_not_found")
+	}
+	return orders, nil
+}
+
+//filterQuery returns the query of the documents matching the filter
+func filterQuery(filter *models.OrderFilter) bson.M {
+	query := bson.M{}
+	if len(filter.Statuses) > 0 {
+		query["status"] = bson.M{"$in": filter.Statuses}
+	}
+	distance := bson.M{}
+	if filter.MinDistance > 0 {
+		distance["$gte"] = filter.MinDistance
+	}
+	if filter.MaxDistance > 0 {
+		distance["$lte"] = filter.MaxDistance
+	}
+	if len(distance) > 0 {
+		query["distance"] = distance
+	}
+	return query
+}
+
+//FetchByStatusBefore finds up to limit orders in the given status which were created before the provided time, oldest first
+func (or *mongoOrderRepository) FetchByStatusBefore(ctx context.Context, status string, before time.Time, limit int) ([]models.Order, error) {
+	var orders []models.Order
+	//Object IDs embed their creation time, so they double as an indexed creation timestamp
+	query := bson.M{
+		"_id":    bson.M{"$lt": bson.NewObjectIdWithTime(before)},
+		"status": status,
+	}
+	err := traced(ctx, or.Conn, COLLECTION, "find", func(c *mgo.Collection) error {
+		return c.Find(query).Sort("_id").Limit(limit).All(&orders)
+	})
+	if err != nil {
+		return nil, mongoError(err, "order_not_found")
+	}
+	return orders, nil
+}
+
+//UpdateStatusByID changes the status of the document only if it currently has the expected status,
+//recording the event in the outbox in the same transaction. It returns the time the document was updated at.
+func (or *mongoOrderRepository) UpdateStatusByID(ctx context.Context, id string, from string, to string, event models.OrderEvent) (time.Time, error) {
+	//If the ID passed is not a valid Object ID, return error
+	if !bson.IsObjectIdHex(id) {
+		return time.Time{}, order.NewNotFound("order_not_found", "Invalid Id")
+	}
+	updatedAt := mongoNow()
+	ops := []txn.Op{{
+		C:      COLLECTION,
+		Id:     bson.ObjectIdHex(id),
+		Assert: bson.M{"status": from},
+		Update: bson.M{"$set": bson.M{"status": to, "updatedAt": updatedAt}},
+	}, outboxInsertOp(event)}
+	//The assertion also fails if the order does not exist, which is treated the same as having moved on
+	err := runTxn(ctx, or.Conn, ops, order.NewConflict("order_status_conflict", "Order is no longer "+from))
+	if err != nil {
+		return time.Time{}, err
+	}
+	return updatedAt, nil
+}
+
+//Store generates a new object id and inserts the document and its event into the database, atomically
+func (or *mongoOrderRepository) Store(ctx context.Context, order *models.Order, event models.OrderEvent) (*models.Order, error) {
 	(*order).ID = bson.NewObjectId()
-	err := or.Conn.C(COLLECTION).Insert(order)
+	(*order).CreatedAt = mongoNow()
+	(*order).UpdatedAt = (*order).CreatedAt
+	//The event was built before the order had an ID
+	event.Data.OrderID = (*order).ID.Hex()
+	ops := []txn.Op{{
+		C:      COLLECTION,
+		Id:     (*order).ID,
+		Assert: txn.DocMissing,
+		Insert: order,
+	}, outboxInsertOp(event)}
+	err := runTxn(ctx, or.Conn, ops, errOrderExists)
 	return order, err
 }
+
+//runTxn applies the operations, returning abortErr if any of their assertions failed. The transaction is not
+//started once ctx is done, but a started one runs to completion, so callers only learn of the cancellation
+//when the operations were not applied.
+func runTxn(ctx context.Context, s *MongoSession, ops []txn.Op, abortErr error) error {
+	err := tracedWrite(ctx, s, TXN_COLLECTION, "txn", func(c *mgo.Collection) error {
+		return txn.NewRunner(c).Run(ops, "", nil)
+	})
+	if err == txn.ErrAborted {
+		return abortErr
+	}
+	return mongoError(err, "not_found")
+}
+
+//mongoNow returns the current time as stored by Mongo, in milliseconds
+func mongoNow() time.Time {
+	return time.Now().UTC().Truncate(time.Millisecond)
+}
@@ -0,0 +1,140 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/karanbhomiagit/order-service/models"
+	"github.com/karanbhomiagit/order-service/order"
+	mgo "gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+	"gopkg.in/mgo.v2/txn"
+)
+
+type mongoOutboxRepository struct {
+	Conn *MongoSession
+}
+
+const (
+	OUTBOX_COLLECTION = "outbox"
+)
+
+//NewMongoOutboxRepository returns the repository for the outbox written by the order repository.
+//Outbox documents take part in transactions, so they are only ever changed through the transaction runner.
+func NewMongoOutboxRepository(Conn *MongoSession) order.OutboxRepository {
+	return &mongoOutboxRepository{Conn}
+}
+
+//FetchPending finds up to limit pending entries which are due for an attempt, oldest first
+func (outr *mongoOutboxRepository) FetchPending(now time.Time, limit int) ([]models.OutboxEntry, error) {
+	var entries []models.OutboxEntry
+	query := bson.M{
+		"status":        models.OutboxPending,
+		"nextAttemptAt": bson.M{"$lte": now},
+	}
+	err := timed(outr.Conn, OUTBOX_COLLECTION, "find", func(c *mgo.Collection) error {
+		return c.Find(query).Sort("_id").Limit(limit).All(&entries)
+	})
+	return entries, mongoError(err, "outbox_entry_not_found")
+}
+
+//UpdateByID stores the delivery state of the entry
+func (outr *mongoOutboxRepository) UpdateByID(entry *models.OutboxEntry) error {
+	ops := []txn.Op{{
+		C:      OUTBOX_COLLECTION,
+		Id:     (*entry).ID,
+		Assert: txn.DocExists,
+		Update: bson.M{"$set": bson.M{
+			"status":        (*entry).Status,
+			"attempts":      (*entry).Attempts,
+			"nextAttemptAt": (*entry).NextAttemptAt,
+			"lastError":     (*entry).LastError,
+		}},
+	}}
+	return runTxn(context.Background(), outr.Conn, ops, order.NewNotFound("outbox_entry_not_found", "not found"))
+}
+
+//RemoveByID deletes a delivered entry
+func (outr *mongoOutboxRepository) RemoveByID(id string) error {
+	//If the ID passed is not a valid Object ID, return error
+	if !bson.IsObjectIdHex(id) {
+		return order.NewNotFound("outbox_entry_not_found", "Invalid Id")
+	}
+	ops := []txn.Op{{
+		C:      OUTBOX_COLLECTION,
+		Id:     bson.ObjectIdHex(id),
+		Remove: true,
+	}}
+	return runTxn(context.Background(), outr.Conn, ops, nil)
+}
+
+//ResumeTransactions completes every transaction a crashed process left half applied, so the entries it
+//inserted become visible. Transactions touching the same documents complete them anyway, so this is
+//only needed at startup.
+func (outr *mongoOutboxRepository) ResumeTransactions() error {
+	err := timed(outr.Conn, TXN_COLLECTION, "resume", func(c *mgo.Collection) error {
+		return txn.NewRunner(c).ResumeAll()
+	})
+	return mongoError(err, "transaction_not_found")
+}
+
+//PruneTransactions removes up to limit transactions which were applied or aborted before the given time,
+//oldest first, and returns the number removed. The documents they changed may still queue them, and the
+//runner fails on a queued transaction which is missing, so they are pulled from those queues first.
+func (outr *mongoOutboxRepository) PruneTransactions(before time.Time, limit int) (int, error) {
+	var txns []struct {
+		ID  bson.ObjectId `bson:"_id"`
+		Ops []struct {
+			C  string      `bson:"c"`
+			ID interface{} `bson:"d"`
+		} `bson:"o"`
+	}
+	query := bson.M{
+		"s":   bson.M{"$in": []int{TXN_ABORTED, TXN_APPLIED}},
+		"_id": bson.M{"$lt": bson.NewObjectIdWithTime(before)},
+	}
+	err := timed(outr.Conn, TXN_COLLECTION, "find", func(c *mgo.Collection) error {
+		return c.Find(query).Select(bson.M{"o.c": 1, "o.d": 1}).Sort("_id").Limit(limit).All(&txns)
+	})
+	if err != nil {
+		return 0, mongoError(err, "transaction_not_found")
+	}
+	pruned := 0
+	for _, t := range txns {
+		pull := bson.M{"$pull": bson.M{"txn-queue": bson.M{"$regex": "^" + t.ID.Hex() + "_"}}}
+		err := timed(outr.Conn, TXN_COLLECTION, "prune", func(c *mgo.Collection) error {
+			for _, op := range t.Ops {
+				if _, err := c.Database.C(op.C).UpdateAll(bson.M{"_id": op.ID}, pull); err != nil {
+					return err
+				}
+				//Documents inserted or removed by a transaction are queued in the stash while missing
+				stashID := bson.D{{Name: "c", Value: op.C}, {Name: "id", Value: op.ID}}
+				if _, err := c.Database.C(TXN_COLLECTION+".stash").UpdateAll(bson.M{"_id": stashID}, pull); err != nil {
+					return err
+				}
+			}
+			return c.RemoveId(t.ID)
+		})
+		if err != nil && err != mgo.ErrNotFound {
+			return pruned, mongoError(err, "transaction_not_found")
+		}
+		pruned++
+	}
+	return pruned, nil
+}
+
+//outboxInsertOp returns the operation adding a pending entry for the event to the outbox
+func outboxInsertOp(event models.OrderEvent) txn.Op {
+	id := bson.NewObjectId()
+	return txn.Op{
+		C:      OUTBOX_COLLECTION,
+		Id:     id,
+		Assert: txn.DocMissing,
+		Insert: models.OutboxEntry{
+			ID:            id,
+			Event:         event,
+			Status:        models.OutboxPending,
+			NextAttemptAt: event.OccurredAt,
+		},
+	}
+}
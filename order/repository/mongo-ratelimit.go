@@ -0,0 +1,88 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/karanbhomiagit/order-service/models"
+	"github.com/karanbhomiagit/order-service/order"
+	mgo "gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+type mongoRateLimitStore struct {
+	Conn *MongoSession
+}
+
+type mongoBucket struct {
+	Key       string    `bson:"_id"`
+	Tokens    float64   `bson:"tokens"`
+	UpdatedAt time.Time `bson:"updatedAt"`
+	//ExpiresAt is when the bucket is full again, after which it can be removed
+	ExpiresAt time.Time `bson:"expiresAt"`
+}
+
+const (
+	RATE_LIMIT_COLLECTION = "rate_limits"
+	//rateLimitAttempts is how many times taking a token is retried when another replica updated the bucket meanwhile
+	rateLimitAttempts = 3
+)
+
+//NewMongoRateLimitStore returns a store keeping the token buckets in the database, shared by the replicas
+func NewMongoRateLimitStore(Conn *MongoSession) order.RateLimitStore {
+	return &mongoRateLimitStore{Conn}
+}
+
+//Take takes a token from the bucket of the key, which starts full. The bucket is only updated if nobody
+//else updated it since it was read.
+func (s *mongoRateLimitStore) Take(key string, limit models.RateLimit, now time.Time) (*models.RateLimitStatus, error) {
+	//Mongo stores times in milliseconds
+	now = now.Truncate(time.Millisecond)
+	for attempt := 0; attempt < rateLimitAttempts; attempt++ {
+		var b mongoBucket
+		err := timed(s.Conn, RATE_LIMIT_COLLECTION, "find", func(c *mgo.Collection) error {
+			return c.FindId(key).One(&b)
+		})
+		if err != nil && err != mgo.ErrNotFound {
+			return nil, mongoError(err, "rate_limit_not_found")
+		}
+		found := err == nil
+		if !found {
+			b = mongoBucket{Key: key, Tokens: float64(limit.Requests), UpdatedAt: now}
+		}
+		tokens, status := takeToken(b.Tokens, b.UpdatedAt, limit, now)
+		next := mongoBucket{Key: key, Tokens: tokens, UpdatedAt: now, ExpiresAt: now.Add(status.Reset)}
+		if found {
+			err = timed(s.Conn, RATE_LIMIT_COLLECTION, "update", func(c *mgo.Collection) error {
+				return c.Update(bson.M{"_id": key, "updatedAt": b.UpdatedAt}, next)
+			})
+		} else {
+			err = timed(s.Conn, RATE_LIMIT_COLLECTION, "insert", func(c *mgo.Collection) error {
+				return c.Insert(next)
+			})
+		}
+		//Another replica took a token meanwhile
+		if err == mgo.ErrNotFound || mgo.IsDup(err) {
+			continue
+		}
+		if err != nil {
+			return nil, mongoError(err, "rate_limit_not_found")
+		}
+		return status, nil
+	}
+	return nil, order.NewUnavailable("rate_limit_contention", "Too many concurrent updates of the rate limit", nil)
+}
+
+//Check tells whether a token could be taken from the bucket of the key, without taking it
+func (s *mongoRateLimitStore) Check(key string, limit models.RateLimit, now time.Time) (*models.RateLimitStatus, error) {
+	var b mongoBucket
+	err := timed(s.Conn, RATE_LIMIT_COLLECTION, "find", func(c *mgo.Collection) error {
+		return c.FindId(key).One(&b)
+	})
+	if err == mgo.ErrNotFound {
+		return checkToken(float64(limit.Requests), now, limit, now), nil
+	}
+	if err != nil {
+		return nil, mongoError(err, "rate_limit_not_found")
+	}
+	return checkToken(b.Tokens, b.UpdatedAt, limit, now), nil
+}
@@ -0,0 +1,101 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/karanbhomiagit/order-service/config"
+	mgo "gopkg.in/mgo.v2"
+)
+
+//MongoSession hands every operation of the repositories a copy of the session to the database, which takes
+//a socket of the pool of its own rather than sharing a single one
+type MongoSession struct {
+	root     *mgo.Session
+	database string
+	logger   *slog.Logger
+}
+
+//maxConnectBackoff caps the wait between two attempts to connect at startup
+const maxConnectBackoff = 30 * time.Second
+
+//DialMongo connects to the database, trying again with an exponential backoff until it is reachable or
+//ctx is done. The sessions it hands out use the pool, timeouts and write concern of the config.
+func DialMongo(ctx context.Context, cfg config.Mongo, logger *slog.Logger) (*MongoSession, error) {
+	info, err := mgo.ParseURL(cfg.MongoURL)
+	if err != nil {
+		return nil, err
+	}
+	info.Timeout = cfg.MongoConnectTimeout
+	info.PoolLimit = cfg.MongoPoolLimit
+	backoff := cfg.MongoConnectBackoff
+	for attempt := 1; ; attempt++ {
+		root, err := mgo.DialWithInfo(info)
+		if err == nil {
+			root.SetSocketTimeout(cfg.MongoSocketTimeout)
+			root.SetSafe(writeConcern(cfg))
+			//Dialing reserved a socket, which every copy would otherwise share
+			root.Refresh()
+			return &MongoSession{root: root, database: cfg.DatabaseName, logger: logger}, nil
+		}
+		logger.Warn("Unable to connect to the database", "attempt", attempt, "retry_in", backoff.String(), "error", err)
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		backoff = min(2*backoff, maxConnectBackoff)
+	}
+}
+
+//writeConcern returns the write concern of the config, a number of members or a mode such as majority
+func writeConcern(cfg config.Mongo) *mgo.Safe {
+	safe := &mgo.Safe{WTimeout: int(cfg.MongoWriteTimeout / time.Millisecond)}
+	if w, err := strconv.Atoi(cfg.MongoWriteConcern); err == nil {
+		safe.W = w
+	} else {
+		safe.WMode = cfg.MongoWriteConcern
+	}
+	return safe
+}
+
+//run runs the operation on the collection with a copy of the session, closed once the operation returns.
+//Broken sockets are dropped by the pool, and the session is refreshed on network errors so the next
+//operation connects anew.
+func (s *MongoSession) run(collection string, op func(c *mgo.Collection) error) error {
+	session := s.root.Copy()
+	defer session.Close()
+	err := op(session.DB(s.database).C(collection))
+	if isNetworkError(err) {
+		s.logger.Warn("Lost the connection to the database", "collection", collection, "error", err)
+		s.root.Refresh()
+	}
+	return err
+}
+
+//ping pings the database with a copy of the session
+func (s *MongoSession) ping() error {
+	session := s.root.Copy()
+	defer session.Close()
+	return session.Ping()
+}
+
+//Close closes the session and every socket of its pool
+func (s *MongoSession) Close() {
+	s.root.Close()
+}
+
+//isNetworkError reports whether the operation failed because the database could not be reached
+func isNetworkError(err error) bool {
+	var netErr net.Error
+	if errors.As(err, &netErr) || errors.Is(err, io.EOF) {
+		return true
+	}
+	//mgo reports these without a type of their own
+	return err != nil && (err.Error() == "no reachable servers" || err.Error() == "Closed explicitly")
+}
@@ -0,0 +1,171 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/karanbhomiagit/order-service/models"
+	"github.com/karanbhomiagit/order-service/order"
+	mgo "gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+type mongoWebhookRepository struct {
+	Conn *MongoSession
+}
+
+const (
+	WEBHOOK_COLLECTION          = "webhooks"
+	WEBHOOK_DELIVERY_COLLECTION = "webhook_deliveries"
+	PENDING_DELIVERY_COLLECTION = "webhook_pending_deliveries"
+)
+
+var errInvalidWebhookID = order.NewNotFound("webhook_not_found", "Invalid Id")
+
+func NewMongoWebhookRepository(Conn *MongoSession) order.WebhookRepository {
+	return &mongoWebhookRepository{Conn}
+}
+
+//FetchByID validates the provided ID and finds the corresponding webhook in the database
+func (wr *mongoWebhookRepository) FetchByID(id string) (*models.Webhook, error) {
+	var webhook models.Webhook
+	//If the ID passed is not a valid Object ID, return error
+	if !bson.IsObjectIdHex(id) {
+		return nil, errInvalidWebhookID
+	}
+	err := timed(wr.Conn, WEBHOOK_COLLECTION, "find", func(c *mgo.Collection) error {
+		return c.FindId(bson.ObjectIdHex(id)).One(&webhook)
+	})
+	return &webhook, mongoError(err, "webhook_not_found")
+}
+
+//FetchAll finds every webhook in the database
+func (wr *mongoWebhookRepository) FetchAll() ([]models.Webhook, error) {
+	var webhooks []models.Webhook
+	err := timed(wr.Conn, WEBHOOK_COLLECTION, "find", func(c *mgo.Collection) error {
+		return c.Find(bson.M{}).Sort("_id").All(&webhooks)
+	})
+	return webhooks, mongoError(err, "webhook_not_found")
+}
+
+//FetchSubscribed finds the webhooks receiving the events of the type for the orders of the owner: those of the
+//owner and of the admin, subscribed to the type or to every event
+func (wr *mongoWebhookRepository) FetchSubscribed(eventType string, owner string) ([]models.Webhook, error) {
+	var webhooks []models.Webhook
+	query := bson.M{
+		"owner": bson.M{"$in": []string{order.AdminSubject, owner}},
+		"$or":   []bson.M{{"events": eventType}, {"events": bson.M{"$size": 0}}},
+	}
+	err := timed(wr.Conn, WEBHOOK_COLLECTION, "find", func(c *mgo.Collection) error {
+		return c.Find(query).Sort("_id").All(&webhooks)
+	})
+	return webhooks, mongoError(err, "webhook_not_found")
+}
+
+//Store generates a new object id and inserts the webhook into the database
+func (wr *mongoWebhookRepository) Store(webhook *models.Webhook) (*models.Webhook, error) {
+	(*webhook).ID = bson.NewObjectId()
+	err := timed(wr.Conn, WEBHOOK_COLLECTION, "insert", func(c *mgo.Collection) error {
+		return c.Insert(webhook)
+	})
+	return webhook, mongoError(err, "webhook_not_found")
+}
+
+//UpdateByID finds the corresponding webhook in the database and updates it
+func (wr *mongoWebhookRepository) UpdateByID(webhook *models.Webhook) error {
+	err := timed(wr.Conn, WEBHOOK_COLLECTION, "update", func(c *mgo.Collection) error {
+		return c.UpdateId((*webhook).ID, webhook)
+	})
+	return mongoError(err, "webhook_not_found")
+}
+
+//RemoveByID deletes the webhook along with its delivery log and pending deliveries
+func (wr *mongoWebhookRepository) RemoveByID(id string) error {
+	//If the ID passed is not a valid Object ID, return error
+	if !bson.IsObjectIdHex(id) {
+		return errInvalidWebhookID
+	}
+	err := timed(wr.Conn, WEBHOOK_COLLECTION, "remove", func(c *mgo.Collection) error {
+		return c.RemoveId(bson.ObjectIdHex(id))
+	})
+	if err != nil {
+		return mongoError(err, "webhook_not_found")
+	}
+	for _, collection := range []string{WEBHOOK_DELIVERY_COLLECTION, PENDING_DELIVERY_COLLECTION} {
+		err = timed(wr.Conn, collection, "remove", func(c *mgo.Collection) error {
+			_, err := c.RemoveAll(bson.M{"webhookId": bson.ObjectIdHex(id)})
+			return err
+		})
+		if err != nil {
+			return mongoError(err, "webhook_not_found")
+		}
+	}
+	return nil
+}
+
+//StoreDelivery generates a new object id and inserts the delivery attempt into the log
+func (wr *mongoWebhookRepository) StoreDelivery(delivery *models.WebhookDelivery) error {
+	(*delivery).ID = bson.NewObjectId()
+	err := timed(wr.Conn, WEBHOOK_DELIVERY_COLLECTION, "insert", func(c *mgo.Collection) error {
+		return c.Insert(delivery)
+	})
+	return mongoError(err, "webhook_delivery_not_found")
+}
+
+//FetchDeliveries finds the latest delivery attempts of the webhook, newest first
+func (wr *mongoWebhookRepository) FetchDeliveries(id string, limit int) ([]models.WebhookDelivery, error) {
+	var deliveries []models.WebhookDelivery
+	//If the ID passed is not a valid Object ID, return error
+	if !bson.IsObjectIdHex(id) {
+		return nil, errInvalidWebhookID
+	}
+	query := bson.M{"webhookId": bson.ObjectIdHex(id)}
+	err := timed(wr.Conn, WEBHOOK_DELIVERY_COLLECTION, "find", func(c *mgo.Collection) error {
+		return c.Find(query).Sort("-_id").Limit(limit).All(&deliveries)
+	})
+	return deliveries, mongoError(err, "webhook_delivery_not_found")
+}
+
+//StorePending queues the delivery, unless the event is already queued for the webhook
+func (wr *mongoWebhookRepository) StorePending(delivery *models.PendingDelivery) error {
+	err := timed(wr.Conn, PENDING_DELIVERY_COLLECTION, "insert", func(c *mgo.Collection) error {
+		return c.Insert(delivery)
+	})
+	//The outbox relays events at least once
+	if mgo.IsDup(err) {
+		return nil
+	}
+	return mongoError(err, "pending_delivery_not_found")
+}
+
+//FetchPending finds up to limit queued deliveries which are due for an attempt, longest due first
+func (wr *mongoWebhookRepository) FetchPending(now time.Time, limit int) ([]models.PendingDelivery, error) {
+	var deliveries []models.PendingDelivery
+	query := bson.M{"nextAttemptAt": bson.M{"$lte": now}}
+	err := timed(wr.Conn, PENDING_DELIVERY_COLLECTION, "find", func(c *mgo.Collection) error {
+		return c.Find(query).Sort("nextAttemptAt").Limit(limit).All(&deliveries)
+	})
+	return deliveries, mongoError(err, "pending_delivery_not_found")
+}
+
+//UpdatePending stores the attempts of the queued delivery and when it is next due
+func (wr *mongoWebhookRepository) UpdatePending(delivery *models.PendingDelivery) error {
+	err := timed(wr.Conn, PENDING_DELIVERY_COLLECTION, "update", func(c *mgo.Collection) error {
+		return c.UpdateId((*delivery).ID, bson.M{"$set": bson.M{
+			"attempts":      (*delivery).Attempts,
+			"nextAttemptAt": (*delivery).NextAttemptAt,
+		}})
+	})
+	return mongoError(err, "pending_delivery_not_found")
+}
+
+//RemovePending removes the delivery from the queue, once delivered or given up on
+func (wr *mongoWebhookRepository) RemovePending(id string) error {
+	err := timed(wr.Conn, PENDING_DELIVERY_COLLECTION, "remove", func(c *mgo.Collection) error {
+		return c.RemoveId(id)
+	})
+	//Removed along with its webhook in the meantime
+	if err == mgo.ErrNotFound {
+		return nil
+	}
+	return mongoError(err, "pending_delivery_not_found")
+}
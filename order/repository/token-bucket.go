@@ -0,0 +1,52 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/karanbhomiagit/order-service/models"
+)
+
+//takeToken refills the bucket holding tokens since updatedAt at the rate of the limit, then takes a token
+//from it if there is one. It returns the tokens left in the bucket along with the status of the request.
+func takeToken(tokens float64, updatedAt time.Time, limit models.RateLimit, now time.Time) (float64, *models.RateLimitStatus) {
+	tokens = refill(tokens, updatedAt, limit, now)
+	allowed := tokens >= 1
+	if allowed {
+		tokens--
+	}
+	return tokens, bucketStatus(tokens, allowed, limit)
+}
+
+//checkToken refills the bucket like takeToken, and returns the status of a request without taking a token
+func checkToken(tokens float64, updatedAt time.Time, limit models.RateLimit, now time.Time) *models.RateLimitStatus {
+	tokens = refill(tokens, updatedAt, limit, now)
+	return bucketStatus(tokens, tokens >= 1, limit)
+}
+
+//refill returns the tokens of the bucket holding tokens since updatedAt, refilled at the rate of the limit
+func refill(tokens float64, updatedAt time.Time, limit models.RateLimit, now time.Time) float64 {
+	burst := float64(limit.Requests)
+	if elapsed := now.Sub(updatedAt); elapsed > 0 {
+		tokens += float64(elapsed) * rate(limit)
+	}
+	if tokens > burst {
+		tokens = burst
+	}
+	return tokens
+}
+
+//bucketStatus returns the status of a request leaving tokens in the bucket
+func bucketStatus(tokens float64, allowed bool, limit models.RateLimit) *models.RateLimitStatus {
+	status := &models.RateLimitStatus{Allowed: allowed}
+	if !allowed {
+		status.RetryAfter = time.Duration((1 - tokens) / rate(limit))
+	}
+	status.Remaining = int(tokens)
+	status.Reset = time.Duration((float64(limit.Requests) - tokens) / rate(limit))
+	return status
+}
+
+//rate returns the tokens per nanosecond of the limit
+func rate(limit models.RateLimit) float64 {
+	return float64(limit.Requests) / float64(limit.Period)
+}
@@ -0,0 +1,11 @@
+package order
+
+//TokenVerifier represents the verification of the bearer tokens carried by end users as an interface
+type TokenVerifier interface {
+	Verify(string) (*Identity, error)
+}
+
+//KeySet represents the public keys signing the tokens, looked up by key id, as an interface
+type KeySet interface {
+	Key(string) (interface{}, error)
+}
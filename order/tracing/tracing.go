@@ -0,0 +1,79 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/karanbhomiagit/order-service/order"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+//Exporters of the spans
+const (
+	ExporterNone   = "none"
+	ExporterStdout = "stdout"
+	ExporterOTLP   = "otlp"
+)
+
+//instrumentationName names the tracer of the service
+const instrumentationName = "github.com/karanbhomiagit/order-service"
+
+//Setup installs the global tracer provider exporting the spans of the service with the exporter, and the
+//W3C trace context propagator. The OTLP exporter is configured with the OTEL_EXPORTER_OTLP_* variables and
+//sampling with OTEL_TRACES_SAMPLER. The returned function flushes the spans left and stops the provider.
+func Setup(ctx context.Context, exporter string, serviceName string) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	var spanExporter sdktrace.SpanExporter
+	var err error
+	switch exporter {
+	case "", ExporterNone:
+		return func(context.Context) error { return nil }, nil
+	case ExporterStdout:
+		spanExporter, err = stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+	case ExporterOTLP:
+		spanExporter, err = otlptracehttp.New(ctx)
+	default:
+		return nil, fmt.Errorf("invalid trace exporter %q, expected none, stdout or otlp", exporter)
+	}
+	if err != nil {
+		return nil, err
+	}
+	//OTEL_SERVICE_NAME and OTEL_RESOURCE_ATTRIBUTES take precedence over the name of the service
+	res, err := resource.New(ctx,
+		resource.WithAttributes(attribute.String("service.name", serviceName)),
+		resource.WithFromEnv(),
+		resource.WithTelemetrySDK(),
+	)
+	if err != nil {
+		return nil, err
+	}
+	provider := sdktrace.NewTracerProvider(sdktrace.WithBatcher(spanExporter), sdktrace.WithResource(res))
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
+
+//Start starts a span of the service, child of the span of ctx if any
+func Start(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	return otel.Tracer(instrumentationName).Start(ctx, name, opts...)
+}
+
+//End ends the span, recording the error if not nil. Only internal errors and failed dependencies mark the
+//span as failed, errors such as missing orders or invalid requests are the expected outcome of the operation.
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		if kind := order.KindOf(err); kind == order.KindInternal || kind == order.KindUnavailable {
+			span.SetStatus(codes.Error, err.Error())
+		}
+	}
+	span.End()
+}
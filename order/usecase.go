@@ -1,10 +1,19 @@
 package order
 
-import "github.com/karanbhomiagit/order-service/models"
+import (
+	"context"
+
+	"github.com/karanbhomiagit/order-service/models"
+)
 
 // Usecase represents the order's business logic as an interface
 type Usecase interface {
-	AssignByID(string, string) (*map[string]string, error)
-	FetchByRange(int, int) ([]models.Order, error)
-	Store(*models.OrderRequest) (*models.Order, error)
+	AssignByID(context.Context, string, string) (*map[string]string, error)
+	CancelByID(context.Context, string) (*models.Order, error)
+	FetchByID(context.Context, string) (*models.Order, error)
+	FetchByRange(context.Context, int, int) ([]models.Order, error)
+	FetchByFilter(context.Context, *models.OrderFilter, int, int) ([]models.Order, error)
+	FetchByFilterAfter(context.Context, *models.OrderFilter, string, int) ([]models.Order, error)
+	Store(context.Context, *models.OrderRequest) (*models.Order, error)
+	TransitionByID(context.Context, string, string) (*models.Order, error)
 }
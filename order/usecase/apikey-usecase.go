@@ -0,0 +1,120 @@
+package usecase
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"strings"
+	"time"
+
+	"github.com/karanbhomiagit/order-service/models"
+	"github.com/karanbhomiagit/order-service/order"
+)
+
+const (
+	//APIKeyPrefix makes keys easy to recognize, e.g. by secret scanners
+	APIKeyPrefix = "osk_"
+)
+
+var (
+	errInvalidAPIKey  = order.NewUnauthenticated("invalid_api_key", "Invalid API key")
+	errInvalidKeyName = order.NewInvalidArgument("invalid_name", "Please provide a name for the API key")
+	errMissingScopes  = order.NewInvalidArgument("invalid_scope", "Please provide at least one scope")
+)
+
+type APIKeyUsecase struct {
+	apiKeyRepository order.APIKeyRepository
+	adminKeyHash     string
+}
+
+//NewAPIKeyUsecase returns the usecase of the API keys stored in the repository. The admin key, when not empty,
+//is granted every scope so the first keys can be issued.
+func NewAPIKeyUsecase(kr order.APIKeyRepository, adminKey string) order.APIKeyUsecase {
+	ku := &APIKeyUsecase{
+		apiKeyRepository: kr,
+	}
+	if adminKey != "" {
+		ku.adminKeyHash = hashAPIKey(adminKey)
+	}
+	return ku
+}
+
+//Authenticate returns the identity of the client owning the key, unless the key is unknown or revoked
+func (ku *APIKeyUsecase) Authenticate(key string) (*order.Identity, error) {
+	if key == "" {
+		return nil, errInvalidAPIKey
+	}
+	hash := hashAPIKey(key)
+	if ku.adminKeyHash != "" && subtle.ConstantTimeCompare([]byte(hash), []byte(ku.adminKeyHash)) == 1 {
+		return &order.Identity{Subject: order.AdminSubject, Scopes: order.Scopes}, nil
+	}
+	apiKey, err := ku.apiKeyRepository.FetchByHash(hash)
+	if order.KindOf(err) == order.KindNotFound {
+		return nil, errInvalidAPIKey
+	}
+	if err != nil {
+		return nil, err
+	}
+	if apiKey.RevokedAt != nil {
+		return nil, errInvalidAPIKey
+	}
+	return &order.Identity{Subject: apiKey.ID.Hex(), Scopes: apiKey.Scopes}, nil
+}
+
+//FetchAll returns every API key, without the keys themselves
+func (ku *APIKeyUsecase) FetchAll() ([]models.APIKey, error) {
+	return ku.apiKeyRepository.FetchAll()
+}
+
+//Issue validates the request and stores a new API key. This is the only time the key is returned.
+func (ku *APIKeyUsecase) Issue(keyReq *models.APIKeyRequest) (*models.APIKey, error) {
+	if strings.TrimSpace(keyReq.Name) == "" {
+		return nil, errInvalidKeyName
+	}
+	if len(keyReq.Scopes) == 0 {
+		return nil, errMissingScopes
+	}
+	for _, scope := range keyReq.Scopes {
+		if !validScope(scope) {
+			return nil, order.NewInvalidArgument("invalid_scope", "Unknown scope "+scope)
+		}
+	}
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return nil, err
+	}
+	key := APIKeyPrefix + hex.EncodeToString(b)
+	apiKey := models.APIKey{
+		Name:      keyReq.Name,
+		Hash:      hashAPIKey(key),
+		Scopes:    keyReq.Scopes,
+		CreatedAt: time.Now().UTC(),
+	}
+	res, err := ku.apiKeyRepository.Store(&apiKey)
+	if err != nil {
+		return nil, err
+	}
+	(*res).Key = key
+	return res, nil
+}
+
+//RevokeByID revokes an API key, which stops authenticating right away
+func (ku *APIKeyUsecase) RevokeByID(id string) error {
+	return ku.apiKeyRepository.RevokeByID(id, time.Now().UTC())
+}
+
+//hashAPIKey returns the hex SHA-256 of the key. Keys are random, so they need no salt nor slow hashing.
+func hashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+func validScope(scope string) bool {
+	for _, s := range order.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,146 @@
+package usecase
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/karanbhomiagit/order-service/models"
+	"github.com/karanbhomiagit/order-service/order"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"gopkg.in/mgo.v2/bson"
+)
+
+type MockedAPIKeyRepository struct {
+	mock.Mock
+}
+
+func (kr *MockedAPIKeyRepository) FetchByHash(hash string) (*models.APIKey, error) {
+	args := kr.Called(hash)
+	return args.Get(0).(*models.APIKey), args.Error(1)
+}
+
+func (kr *MockedAPIKeyRepository) FetchAll() ([]models.APIKey, error) {
+	args := kr.Called()
+	return args.Get(0).([]models.APIKey), args.Error(1)
+}
+
+func (kr *MockedAPIKeyRepository) Store(key *models.APIKey) (*models.APIKey, error) {
+	args := kr.Called(key)
+	return args.Get(0).(*models.APIKey), args.Error(1)
+}
+
+func (kr *MockedAPIKeyRepository) RevokeByID(id string, at time.Time) error {
+	args := kr.Called(id, at)
+	return args.Error(0)
+}
+
+func TestAuthenticate(t *testing.T) {
+
+	t.Run("Successfully authenticate a stored key", func(t *testing.T) {
+		testObj := new(MockedAPIKeyRepository)
+		id := bson.NewObjectId()
+		testObj.On("FetchByHash", hashAPIKey("osk_key")).Return(&models.APIKey{ID: id, Scopes: []string{"orders:read"}}, nil)
+
+		keyUsecase := NewAPIKeyUsecase(testObj, "")
+		identity, err := keyUsecase.Authenticate("osk_key")
+		assert := assert.New(t)
+		assert.Nil(err)
+		assert.Equal(&order.Identity{Subject: id.Hex(), Scopes: []string{"orders:read"}}, identity)
+		testObj.AssertExpectations(t)
+	})
+
+	t.Run("Grant every scope to the admin key", func(t *testing.T) {
+		testObj := new(MockedAPIKeyRepository)
+
+		keyUsecase := NewAPIKeyUsecase(testObj, "root-key")
+		identity, err := keyUsecase.Authenticate("root-key")
+		assert := assert.New(t)
+		assert.Nil(err)
+		if assert.NotNil(identity) {
+			assert.Equal("admin", identity.Subject)
+			assert.True(identity.HasScope(order.ScopeKeysManage))
+		}
+		testObj.AssertExpectations(t)
+	})
+
+	t.Run("Return error for unknown keys", func(t *testing.T) {
+		testObj := new(MockedAPIKeyRepository)
+		testObj.On("FetchByHash", hashAPIKey("osk_unknown")).Return(&models.APIKey{}, order.NewNotFound("api_key_not_found", "not found"))
+
+		keyUsecase := NewAPIKeyUsecase(testObj, "root-key")
+		_, err := keyUsecase.Authenticate("osk_unknown")
+		assert := assert.New(t)
+		assert.Equal(order.KindUnauthenticated, order.KindOf(err))
+		assert.Equal("invalid_api_key", order.CodeOf(err))
+		testObj.AssertExpectations(t)
+	})
+
+	t.Run("Return error for revoked keys", func(t *testing.T) {
+		testObj := new(MockedAPIKeyRepository)
+		revokedAt := time.Now()
+		testObj.On("FetchByHash", hashAPIKey("osk_revoked")).Return(&models.APIKey{ID: bson.NewObjectId(), Scopes: []string{"orders:read"}, RevokedAt: &revokedAt}, nil)
+
+		keyUsecase := NewAPIKeyUsecase(testObj, "")
+		_, err := keyUsecase.Authenticate("osk_revoked")
+		assert := assert.New(t)
+		assert.Equal("invalid_api_key", order.CodeOf(err))
+		testObj.AssertExpectations(t)
+	})
+
+	t.Run("Return error for an empty key even without admin key", func(t *testing.T) {
+		testObj := new(MockedAPIKeyRepository)
+
+		keyUsecase := NewAPIKeyUsecase(testObj, "")
+		_, err := keyUsecase.Authenticate("")
+		assert := assert.New(t)
+		assert.Equal(order.KindUnauthenticated, order.KindOf(err))
+		testObj.AssertExpectations(t)
+	})
+}
+
+func TestIssue(t *testing.T) {
+
+	t.Run("Successfully issue a key storing only its hash", func(t *testing.T) {
+		testObj := new(MockedAPIKeyRepository)
+		var stored *models.APIKey
+		testObj.On("Store", mock.MatchedBy(func(k *models.APIKey) bool {
+			stored = k
+			return k.Name == "merchant" && k.Key == "" && len(k.Hash) == 64
+		})).Return(&models.APIKey{ID: bson.NewObjectId(), Name: "merchant"}, nil)
+
+		keyUsecase := NewAPIKeyUsecase(testObj, "")
+		res, err := keyUsecase.Issue(&models.APIKeyRequest{Name: "merchant", Scopes: []string{"orders:create", "orders:read"}})
+		assert := assert.New(t)
+		assert.Nil(err)
+		if assert.NotNil(res) {
+			assert.True(strings.HasPrefix(res.Key, "osk_"))
+			assert.Equal(hashAPIKey(res.Key), stored.Hash)
+		}
+		testObj.AssertExpectations(t)
+	})
+
+	t.Run("Return error for unknown scopes", func(t *testing.T) {
+		testObj := new(MockedAPIKeyRepository)
+
+		keyUsecase := NewAPIKeyUsecase(testObj, "")
+		_, err := keyUsecase.Issue(&models.APIKeyRequest{Name: "merchant", Scopes: []string{"orders:delete"}})
+		assert := assert.New(t)
+		if assert.NotNil(err) {
+			assert.Equal("Unknown scope orders:delete", err.Error())
+			assert.Equal(order.KindInvalidArgument, order.KindOf(err))
+		}
+		testObj.AssertExpectations(t)
+	})
+
+	t.Run("Return error without name", func(t *testing.T) {
+		testObj := new(MockedAPIKeyRepository)
+
+		keyUsecase := NewAPIKeyUsecase(testObj, "")
+		_, err := keyUsecase.Issue(&models.APIKeyRequest{Scopes: []string{"orders:read"}})
+		assert := assert.New(t)
+		assert.Equal("invalid_name", order.CodeOf(err))
+		testObj.AssertExpectations(t)
+	})
+}
@@ -0,0 +1,89 @@
+package usecase
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/karanbhomiagit/order-service/config"
+	"github.com/karanbhomiagit/order-service/models"
+	"github.com/karanbhomiagit/order-service/order"
+)
+
+const (
+	ExpiryLeaseName = "order-expiry"
+)
+
+//ExpiryWorker periodically moves orders which were never assigned within the TTL to EXPIRED
+type ExpiryWorker struct {
+	orderRepository order.Repository
+	leaseRepository order.LeaseRepository
+	owner           string
+	ttl             time.Duration
+	interval        time.Duration
+	batchSize       int
+	logger          *slog.Logger
+}
+
+func NewExpiryWorker(or order.Repository, lr order.LeaseRepository, cfg config.Expiry, logger *slog.Logger) *ExpiryWorker {
+	return &ExpiryWorker{
+		orderRepository: or,
+		leaseRepository: lr,
+		owner:           workerOwner(),
+		ttl:             cfg.OrderTTL,
+		interval:        cfg.ExpiryInterval,
+		batchSize:       cfg.ExpiryBatchSize,
+		logger:          logger,
+	}
+}
+
+//Run expires orders every interval until the context is cancelled
+func (w *ExpiryWorker) Run(ctx context.Context) {
+	runEvery(ctx, w.interval, func() {
+		expired, err := w.Expire(ctx, time.Now())
+		if err != nil {
+			w.logger.Error("Unable to expire orders", "error", err)
+		}
+		if expired > 0 {
+			w.logger.Info("Orders expired", "count", expired)
+		}
+	})
+	//Let another replica take over straight away
+	w.leaseRepository.Release(ExpiryLeaseName, w.owner)
+}
+
+//Expire transitions every unassigned order created before now minus the TTL to EXPIRED, in batches.
+//Only the replica holding the expiry lease does any work; it returns the number of orders expired.
+func (w *ExpiryWorker) Expire(ctx context.Context, now time.Time) (int, error) {
+	cutoff := now.Add(-w.ttl)
+	expired := 0
+	for {
+		//The lease is renewed before every batch so it does not run out while a backlog drains,
+		//and the work stops as soon as another replica holds it
+		acquired, err := w.leaseRepository.Acquire(ExpiryLeaseName, w.owner, 2*w.interval)
+		if err != nil || !acquired {
+			return expired, err
+		}
+		//Call repository function to fetch the next batch of candidates
+		orders, err := w.orderRepository.FetchByStatusBefore(ctx, StatusUnassigned, cutoff, w.batchSize)
+		if err != nil {
+			return expired, err
+		}
+		for _, o := range orders {
+			o.Status = StatusExpired
+			event := newOrderEvent(models.EventOrderStatusChanged, &o, StatusUnassigned)
+			_, err := w.orderRepository.UpdateStatusByID(ctx, o.ID.Hex(), StatusUnassigned, StatusExpired, event)
+			//The order was assigned in the meantime, leave it alone
+			if order.KindOf(err) == order.KindConflict {
+				continue
+			}
+			if err != nil {
+				return expired, err
+			}
+			expired++
+		}
+		if len(orders) == 0 || len(orders) < w.batchSize {
+			return expired, nil
+		}
+	}
+}
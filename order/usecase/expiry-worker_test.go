@@ -0,0 +1,137 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/karanbhomiagit/order-service/config"
+	"github.com/karanbhomiagit/order-service/models"
+	"github.com/karanbhomiagit/order-service/order"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"gopkg.in/mgo.v2/bson"
+)
+
+type MockedLeaseRepository struct {
+	mock.Mock
+}
+
+func (lr *MockedLeaseRepository) Acquire(name string, owner string, ttl time.Duration) (bool, error) {
+	args := lr.Called(name, owner, ttl)
+	return args.Bool(0), args.Error(1)
+}
+
+func (lr *MockedLeaseRepository) Release(name string, owner string) error {
+	args := lr.Called(name, owner)
+	return args.Error(0)
+}
+
+/*
+	Actual test functions
+*/
+
+func TestExpire(t *testing.T) {
+
+	now := time.Date(2019, 1, 2, 12, 0, 0, 0, time.UTC)
+	cutoff := now.Add(-time.Hour)
+	expiredEvent := mock.MatchedBy(func(e models.OrderEvent) bool {
+		return e.Type == "order.status_changed" && e.Data.Status == "EXPIRED" && e.Data.PreviousStatus == "UNASSIGNED"
+	})
+
+	t.Run("Successfully expire unassigned orders in batches", func(t *testing.T) {
+		testObj := new(MockedOrderRepository)
+		leaseObj := new(MockedLeaseRepository)
+		leaseObj.On("Acquire", ExpiryLeaseName, mock.Anything, 2*time.Minute).Return(true, nil)
+		batch1 := []models.Order{
+			{ID: bson.ObjectIdHex("5c2b2aaf4530558539f91859"), Distance: 12345, Status: "UNASSIGNED"},
+			{ID: bson.ObjectIdHex("5c2b2aaf4530558539f91858"), Distance: 52345, Status: "UNASSIGNED"},
+		}
+		batch2 := []models.Order{
+			{ID: bson.ObjectIdHex("5c2b2aaf4530558539f91857"), Distance: 32345, Status: "UNASSIGNED"},
+		}
+		testObj.On("FetchByStatusBefore", "UNASSIGNED", cutoff, 2).Return(batch1, nil).Once()
+		testObj.On("FetchByStatusBefore", "UNASSIGNED", cutoff, 2).Return(batch2, nil).Once()
+		testObj.On("UpdateStatusByID", "5c2b2aaf4530558539f91859", "UNASSIGNED", "EXPIRED", expiredEvent).Return(time.Now(), nil)
+		testObj.On("UpdateStatusByID", "5c2b2aaf4530558539f91858", "UNASSIGNED", "EXPIRED", expiredEvent).Return(time.Now(), nil)
+		testObj.On("UpdateStatusByID", "5c2b2aaf4530558539f91857", "UNASSIGNED", "EXPIRED", expiredEvent).Return(time.Now(), nil)
+
+		worker := NewExpiryWorker(testObj, leaseObj, config.Expiry{OrderTTL: time.Hour, ExpiryInterval: time.Minute, ExpiryBatchSize: 2}, testLogger)
+		expired, err := worker.Expire(context.Background(), now)
+		assert := assert.New(t)
+		assert.Nil(err)
+		assert.Equal(3, expired)
+		testObj.AssertExpectations(t)
+		leaseObj.AssertExpectations(t)
+	})
+
+	t.Run("Skip orders assigned while expiring", func(t *testing.T) {
+		testObj := new(MockedOrderRepository)
+		leaseObj := new(MockedLeaseRepository)
+		leaseObj.On("Acquire", ExpiryLeaseName, mock.Anything, 2*time.Minute).Return(true, nil)
+		batch := []models.Order{
+			{ID: bson.ObjectIdHex("5c2b2aaf4530558539f91859"), Distance: 12345, Status: "UNASSIGNED"},
+		}
+		testObj.On("FetchByStatusBefore", "UNASSIGNED", cutoff, 10).Return(batch, nil)
+		testObj.On("UpdateStatusByID", "5c2b2aaf4530558539f91859", "UNASSIGNED", "EXPIRED", mock.Anything).Return(time.Time{}, order.NewConflict("order_status_conflict", "Order is no longer UNASSIGNED"))
+
+		worker := NewExpiryWorker(testObj, leaseObj, config.Expiry{OrderTTL: time.Hour, ExpiryInterval: time.Minute, ExpiryBatchSize: 10}, testLogger)
+		expired, err := worker.Expire(context.Background(), now)
+		assert := assert.New(t)
+		assert.Nil(err)
+		assert.Equal(0, expired)
+		testObj.AssertExpectations(t)
+	})
+
+	t.Run("Do nothing if another replica holds the lease", func(t *testing.T) {
+		testObj := new(MockedOrderRepository)
+		leaseObj := new(MockedLeaseRepository)
+		leaseObj.On("Acquire", ExpiryLeaseName, mock.Anything, 2*time.Minute).Return(false, nil)
+
+		worker := NewExpiryWorker(testObj, leaseObj, config.Expiry{OrderTTL: time.Hour, ExpiryInterval: time.Minute, ExpiryBatchSize: 10}, testLogger)
+		expired, err := worker.Expire(context.Background(), now)
+		assert := assert.New(t)
+		assert.Nil(err)
+		assert.Equal(0, expired)
+		testObj.AssertExpectations(t)
+		leaseObj.AssertExpectations(t)
+	})
+
+	t.Run("Stop between batches once another replica holds the lease", func(t *testing.T) {
+		testObj := new(MockedOrderRepository)
+		leaseObj := new(MockedLeaseRepository)
+		leaseObj.On("Acquire", ExpiryLeaseName, mock.Anything, 2*time.Minute).Return(true, nil).Once()
+		leaseObj.On("Acquire", ExpiryLeaseName, mock.Anything, 2*time.Minute).Return(false, nil).Once()
+		batch := []models.Order{
+			{ID: bson.ObjectIdHex("5c2b2aaf4530558539f91859"), Distance: 12345, Status: "UNASSIGNED"},
+			{ID: bson.ObjectIdHex("5c2b2aaf4530558539f91858"), Distance: 52345, Status: "UNASSIGNED"},
+		}
+		testObj.On("FetchByStatusBefore", "UNASSIGNED", cutoff, 2).Return(batch, nil).Once()
+		testObj.On("UpdateStatusByID", "5c2b2aaf4530558539f91859", "UNASSIGNED", "EXPIRED", expiredEvent).Return(time.Now(), nil)
+		testObj.On("UpdateStatusByID", "5c2b2aaf4530558539f91858", "UNASSIGNED", "EXPIRED", expiredEvent).Return(time.Now(), nil)
+
+		worker := NewExpiryWorker(testObj, leaseObj, config.Expiry{OrderTTL: time.Hour, ExpiryInterval: time.Minute, ExpiryBatchSize: 2}, testLogger)
+		expired, err := worker.Expire(context.Background(), now)
+		assert := assert.New(t)
+		assert.Nil(err)
+		assert.Equal(2, expired)
+		testObj.AssertExpectations(t)
+		leaseObj.AssertExpectations(t)
+	})
+
+	t.Run("Return error if fetching candidates fails", func(t *testing.T) {
+		testObj := new(MockedOrderRepository)
+		leaseObj := new(MockedLeaseRepository)
+		leaseObj.On("Acquire", ExpiryLeaseName, mock.Anything, 2*time.Minute).Return(true, nil)
+		testObj.On("FetchByStatusBefore", "UNASSIGNED", cutoff, 10).Return([]models.Order{}, errors.New("connection lost"))
+
+		worker := NewExpiryWorker(testObj, leaseObj, config.Expiry{OrderTTL: time.Hour, ExpiryInterval: time.Minute, ExpiryBatchSize: 10}, testLogger)
+		_, err := worker.Expire(context.Background(), now)
+		assert := assert.New(t)
+		if assert.NotNil(err) {
+			assert.Equal("connection lost", err.Error())
+		}
+		testObj.AssertExpectations(t)
+	})
+}
@@ -0,0 +1,73 @@
+package usecase
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/karanbhomiagit/order-service/config"
+	"github.com/karanbhomiagit/order-service/models"
+	"github.com/karanbhomiagit/order-service/order"
+)
+
+//FeedTailer follows the event log into the feed of this replica. Events are relayed from the outbox by whichever
+//replica holds the relay lease, so every replica tails the log to stream them to its own clients.
+type FeedTailer struct {
+	eventLogRepository order.EventLogRepository
+	appendEvent        func(models.OrderEvent)
+	interval           time.Duration
+	batchSize          int
+	replaySize         int
+	last               int64
+	started            bool
+	logger             *slog.Logger
+}
+
+//NewFeedTailer returns the tailer handing the logged events to appendEvent, polling the log as often as the
+//outbox is relayed. It starts with the latest replaySize events, so that clients can resume from this replica.
+func NewFeedTailer(elr order.EventLogRepository, appendEvent func(models.OrderEvent), cfg config.Outbox, replaySize int, logger *slog.Logger) *FeedTailer {
+	return &FeedTailer{
+		eventLogRepository: elr,
+		appendEvent:        appendEvent,
+		interval:           cfg.OutboxRelayInterval,
+		batchSize:          cfg.OutboxBatchSize,
+		replaySize:         replaySize,
+		logger:             logger,
+	}
+}
+
+//Run tails the event log every interval until the context is cancelled
+func (t *FeedTailer) Run(ctx context.Context) {
+	runEvery(ctx, t.interval, func() {
+		if _, err := t.Tail(); err != nil {
+			t.logger.Error("Unable to tail the event log", "error", err)
+		}
+	})
+}
+
+//Tail hands every event logged since the last call to the feed, in batches, returning the number of events
+func (t *FeedTailer) Tail() (int, error) {
+	if !t.started {
+		last, err := t.eventLogRepository.LastSeq()
+		if err != nil {
+			return 0, err
+		}
+		t.last = max(0, last-int64(t.replaySize))
+		t.started = true
+	}
+	tailed := 0
+	for {
+		events, err := t.eventLogRepository.FetchAfter(t.last, t.batchSize)
+		if err != nil {
+			return tailed, err
+		}
+		for _, event := range events {
+			t.appendEvent(event.Event)
+			t.last = event.Seq
+			tailed++
+		}
+		if len(events) < t.batchSize {
+			return tailed, nil
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package usecase
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/karanbhomiagit/order-service/config"
+	"github.com/karanbhomiagit/order-service/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockedEventLogRepository struct {
+	mock.Mock
+}
+
+func (elr *MockedEventLogRepository) Append(event models.OrderEvent) error {
+	args := elr.Called(event)
+	return args.Error(0)
+}
+
+func (elr *MockedEventLogRepository) FetchAfter(seq int64, limit int) ([]models.LoggedEvent, error) {
+	args := elr.Called(seq, limit)
+	return args.Get(0).([]models.LoggedEvent), args.Error(1)
+}
+
+func (elr *MockedEventLogRepository) LastSeq() (int64, error) {
+	args := elr.Called()
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func loggedEvent(seq int64, id string) models.LoggedEvent {
+	return models.LoggedEvent{Seq: seq, Event: models.OrderEvent{ID: id, Type: models.EventOrderCreated}}
+}
+
+/*
+	Actual test functions
+*/
+
+func TestTail(t *testing.T) {
+
+	testOutbox := config.Outbox{OutboxRelayInterval: time.Second, OutboxBatchSize: 2}
+
+	t.Run("Successfully start with the latest events and carry on after the last one tailed", func(t *testing.T) {
+		testObj := new(MockedEventLogRepository)
+		testObj.On("LastSeq").Return(int64(12), nil).Once()
+		testObj.On("FetchAfter", int64(9), 2).Return([]models.LoggedEvent{loggedEvent(10, "e10"), loggedEvent(11, "e11")}, nil).Once()
+		testObj.On("FetchAfter", int64(11), 2).Return([]models.LoggedEvent{loggedEvent(12, "e12")}, nil).Once()
+		testObj.On("FetchAfter", int64(12), 2).Return([]models.LoggedEvent{loggedEvent(13, "e13")}, nil).Once()
+		var appended []string
+		tailer := NewFeedTailer(testObj, func(e models.OrderEvent) { appended = append(appended, e.ID) }, testOutbox, 3, testLogger)
+
+		assert := assert.New(t)
+		tailed, err := tailer.Tail()
+		assert.Nil(err)
+		assert.Equal(3, tailed)
+		tailed, err = tailer.Tail()
+		assert.Nil(err)
+		assert.Equal(1, tailed)
+		assert.Equal([]string{"e10", "e11", "e12", "e13"}, appended)
+		testObj.AssertExpectations(t)
+	})
+
+	t.Run("Successfully tail the whole log when it is shorter than the replay", func(t *testing.T) {
+		testObj := new(MockedEventLogRepository)
+		testObj.On("LastSeq").Return(int64(1), nil)
+		testObj.On("FetchAfter", int64(0), 2).Return([]models.LoggedEvent{loggedEvent(1, "e1")}, nil)
+		tailer := NewFeedTailer(testObj, func(models.OrderEvent) {}, testOutbox, 3, testLogger)
+
+		tailed, err := tailer.Tail()
+		assert := assert.New(t)
+		assert.Nil(err)
+		assert.Equal(1, tailed)
+		testObj.AssertExpectations(t)
+	})
+
+	t.Run("Resume from the last event handed to the feed after an error", func(t *testing.T) {
+		testObj := new(MockedEventLogRepository)
+		testObj.On("LastSeq").Return(int64(0), nil).Once()
+		testObj.On("FetchAfter", int64(0), 2).Return([]models.LoggedEvent{loggedEvent(1, "e1"), loggedEvent(2, "e2")}, nil).Once()
+		testObj.On("FetchAfter", int64(2), 2).Return([]models.LoggedEvent{}, errors.New("connection lost")).Once()
+		testObj.On("FetchAfter", int64(2), 2).Return([]models.LoggedEvent{}, nil).Once()
+		tailer := NewFeedTailer(testObj, func(models.OrderEvent) {}, testOutbox, 3, testLogger)
+
+		assert := assert.New(t)
+		tailed, err := tailer.Tail()
+		assert.Equal(2, tailed)
+		if assert.NotNil(err) {
+			assert.Equal("connection lost", err.Error())
+		}
+		tailed, err = tailer.Tail()
+		assert.Nil(err)
+		assert.Equal(0, tailed)
+		testObj.AssertExpectations(t)
+	})
+}
@@ -0,0 +1,93 @@
+package usecase
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/karanbhomiagit/order-service/config"
+	"github.com/karanbhomiagit/order-service/models"
+	"github.com/karanbhomiagit/order-service/order"
+)
+
+type HealthUsecase struct {
+	checkers     map[string]order.HealthChecker
+	timeout      time.Duration
+	shuttingDown atomic.Bool
+}
+
+//NewHealthUsecase returns the usecase checking the dependencies by name, each of them within the timeout
+func NewHealthUsecase(checkers map[string]order.HealthChecker, cfg config.Readiness) order.HealthUsecase {
+	return &HealthUsecase{
+		checkers: checkers,
+		timeout:  cfg.ReadinessTimeout,
+	}
+}
+
+//Ready checks every dependency concurrently. The service is ready if all of them are available and it is
+//not shutting down.
+func (hu *HealthUsecase) Ready(ctx context.Context) models.Health {
+	if hu.shuttingDown.Load() {
+		return models.Health{Status: models.HealthShuttingDown}
+	}
+	health := models.Health{Status: models.HealthOK, Checks: make(map[string]models.HealthCheck, len(hu.checkers))}
+	var mutex sync.Mutex
+	var wg sync.WaitGroup
+	for name, checker := range hu.checkers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			check := hu.check(ctx, checker)
+			mutex.Lock()
+			defer mutex.Unlock()
+			health.Checks[name] = check
+			if check.Status != models.HealthOK {
+				health.Status = models.HealthFailing
+			}
+		}()
+	}
+	wg.Wait()
+	return health
+}
+
+//Shutdown marks the service as no longer ready, so that it stops being sent requests before it stops
+func (hu *HealthUsecase) Shutdown() {
+	hu.shuttingDown.Store(true)
+}
+
+//check runs the check of a dependency within the timeout
+func (hu *HealthUsecase) check(ctx context.Context, checker order.HealthChecker) models.HealthCheck {
+	ctx, cancel := context.WithTimeout(ctx, hu.timeout)
+	defer cancel()
+	start := time.Now()
+	err := checker.Check(ctx)
+	check := models.HealthCheck{Status: models.HealthOK, LatencyMs: float64(time.Since(start).Microseconds()) / 1000}
+	if err != nil {
+		check.Status = models.HealthFailing
+		check.Error = err.Error()
+	}
+	return check
+}
+
+//distanceLocations are the origin and destination of the distance requested to check the Google APIs
+var distanceLocations = [2][]string{{"22.3193", "114.1694"}, {"22.2783", "114.1747"}}
+
+type distanceHealthChecker struct {
+	google config.Google
+}
+
+//NewDistanceHealthChecker returns the checker of the Google APIs, which requests the distance between two
+//fixed locations. Every check counts against the quota of the API key.
+func NewDistanceHealthChecker(cfg config.Google) order.HealthChecker {
+	return &distanceHealthChecker{cfg}
+}
+
+func (dc *distanceHealthChecker) Check(ctx context.Context) error {
+	_, err := getDistanceFromExternalService(ctx, dc.google, distanceLocations[0], distanceLocations[1])
+	//Locations without a route are an answer of the Google APIs all the same
+	if order.KindOf(err) == order.KindInvalidArgument {
+		return nil
+	}
+	return err
+}
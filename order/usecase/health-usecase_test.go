@@ -0,0 +1,114 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/karanbhomiagit/order-service/config"
+	"github.com/karanbhomiagit/order-service/models"
+	"github.com/karanbhomiagit/order-service/order"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockedHealthChecker struct {
+	mock.Mock
+}
+
+func (hc *MockedHealthChecker) Check(ctx context.Context) error {
+	args := hc.Called()
+	return args.Error(0)
+}
+
+//blockingChecker only answers once the check runs out of time
+type blockingChecker struct{}
+
+func (blockingChecker) Check(ctx context.Context) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+/*
+	Actual test functions
+*/
+
+func TestReady(t *testing.T) {
+
+	t.Run("Successfully report ready when every dependency is available", func(t *testing.T) {
+		mongo := new(MockedHealthChecker)
+		mongo.On("Check").Return(nil)
+		distance := new(MockedHealthChecker)
+		distance.On("Check").Return(nil)
+
+		healthUsecase := NewHealthUsecase(map[string]order.HealthChecker{"mongo": mongo, "distance": distance}, config.Readiness{ReadinessTimeout: time.Second})
+		health := healthUsecase.Ready(context.Background())
+		assert := assert.New(t)
+		assert.Equal(models.HealthOK, health.Status)
+		assert.Equal(models.HealthOK, health.Checks["mongo"].Status)
+		assert.Equal(models.HealthOK, health.Checks["distance"].Status)
+		mongo.AssertExpectations(t)
+		distance.AssertExpectations(t)
+	})
+
+	t.Run("Report failing along with the error of the dependency which is unavailable", func(t *testing.T) {
+		mongo := new(MockedHealthChecker)
+		mongo.On("Check").Return(errors.New("no reachable servers"))
+		distance := new(MockedHealthChecker)
+		distance.On("Check").Return(nil)
+
+		healthUsecase := NewHealthUsecase(map[string]order.HealthChecker{"mongo": mongo, "distance": distance}, config.Readiness{ReadinessTimeout: time.Second})
+		health := healthUsecase.Ready(context.Background())
+		assert := assert.New(t)
+		assert.Equal(models.HealthFailing, health.Status)
+		assert.Equal(models.HealthCheck{Status: models.HealthFailing, Error: "no reachable servers", LatencyMs: health.Checks["mongo"].LatencyMs}, health.Checks["mongo"])
+		assert.Equal(models.HealthOK, health.Checks["distance"].Status)
+		mongo.AssertExpectations(t)
+		distance.AssertExpectations(t)
+	})
+
+	t.Run("Report failing when a dependency does not answer in time", func(t *testing.T) {
+		healthUsecase := NewHealthUsecase(map[string]order.HealthChecker{"mongo": blockingChecker{}}, config.Readiness{ReadinessTimeout: 10 * time.Millisecond})
+		start := time.Now()
+		health := healthUsecase.Ready(context.Background())
+		assert := assert.New(t)
+		assert.Less(time.Since(start), time.Second)
+		assert.Equal(models.HealthFailing, health.Status)
+		assert.Equal(context.DeadlineExceeded.Error(), health.Checks["mongo"].Error)
+	})
+
+	t.Run("Report shutting down without checking the dependencies once shut down", func(t *testing.T) {
+		mongo := new(MockedHealthChecker)
+
+		healthUsecase := NewHealthUsecase(map[string]order.HealthChecker{"mongo": mongo}, config.Readiness{ReadinessTimeout: time.Second})
+		healthUsecase.Shutdown()
+		health := healthUsecase.Ready(context.Background())
+		assert := assert.New(t)
+		assert.Equal(models.Health{Status: models.HealthShuttingDown}, health)
+		mongo.AssertNotCalled(t, "Check")
+		mongo.AssertExpectations(t)
+	})
+}
+
+func TestDistanceHealthChecker(t *testing.T) {
+
+	t.Run("Successfully check the Google APIs when they find no route", func(t *testing.T) {
+		server := mockServer(200, `{"origin_addresses":["a"],"destination_addresses":["b"],"rows":[{"elements":[{"status":"ZERO_RESULTS"}]}],"status":"OK"}`)
+		defer server.Close()
+
+		err := NewDistanceHealthChecker(testOrders(server.URL).Google).Check(context.Background())
+		assert.Nil(t, err)
+	})
+
+	t.Run("Return error when the Google APIs fail", func(t *testing.T) {
+		server := mockServer(500, `{"status":"UNKNOWN_ERROR"}`)
+		defer server.Close()
+
+		err := NewDistanceHealthChecker(testOrders(server.URL).Google).Check(context.Background())
+		assert := assert.New(t)
+		if assert.NotNil(err) {
+			assert.Equal(order.KindUnavailable, order.KindOf(err))
+		}
+	})
+}
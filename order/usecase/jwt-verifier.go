@@ -0,0 +1,116 @@
+package usecase
+
+import (
+	"strings"
+
+	jwt "github.com/golang-jwt/jwt/v5"
+	"github.com/karanbhomiagit/order-service/config"
+	"github.com/karanbhomiagit/order-service/order"
+)
+
+var (
+	errInvalidToken = order.NewUnauthenticated("invalid_token", "Invalid bearer token")
+	errNoRole       = order.NewPermissionDenied("forbidden_role", "The token grants none of the roles of the service")
+)
+
+//invalidToken returns an error like errInvalidToken wrapping the reason the token was rejected, which is
+//logged but not shown to the client
+func invalidToken(reason error) error {
+	return &order.Error{Kind: order.KindUnauthenticated, Code: "invalid_token", Message: "Invalid bearer token", Err: reason}
+}
+
+type JWTVerifier struct {
+	secret     []byte
+	keys       order.KeySet
+	issuer     string
+	audience   string
+	rolesClaim string
+	methods    []string
+}
+
+//NewJWTVerifier returns the verifier of the JWTs of the identity provider, signed with HS256 using the secret
+//or with RS256 using the keys of the key set; either may be left out. The issuer and audience are checked
+//when not empty, and the roles of the end user are read from the roles claim.
+func NewJWTVerifier(cfg config.JWT, keys order.KeySet) order.TokenVerifier {
+	tv := &JWTVerifier{
+		secret:     []byte(cfg.Secret),
+		keys:       keys,
+		issuer:     cfg.Issuer,
+		audience:   cfg.Audience,
+		rolesClaim: cfg.RolesClaim,
+	}
+	if len(tv.secret) > 0 {
+		tv.methods = append(tv.methods, jwt.SigningMethodHS256.Alg())
+	}
+	if keys != nil {
+		tv.methods = append(tv.methods, jwt.SigningMethodRS256.Alg())
+	}
+	return tv
+}
+
+//Verify checks the signature and claims of the token and returns the identity of the end user, granted the
+//scopes of their roles
+func (tv *JWTVerifier) Verify(token string) (*order.Identity, error) {
+	options := []jwt.ParserOption{jwt.WithValidMethods(tv.methods), jwt.WithExpirationRequired()}
+	if tv.issuer != "" {
+		options = append(options, jwt.WithIssuer(tv.issuer))
+	}
+	if tv.audience != "" {
+		options = append(options, jwt.WithAudience(tv.audience))
+	}
+	claims := jwt.MapClaims{}
+	if _, err := jwt.ParseWithClaims(token, claims, tv.key, options...); err != nil {
+		//An unreachable identity provider is not the client's fault
+		if order.KindOf(err) == order.KindUnavailable {
+			return nil, err
+		}
+		return nil, invalidToken(err)
+	}
+	subject, err := claims.GetSubject()
+	if err != nil || subject == "" {
+		return nil, errInvalidToken
+	}
+	identity := &order.Identity{Subject: subject}
+	for _, role := range rolesOf(claims[tv.rolesClaim]) {
+		scopes, ok := order.RoleScopes[role]
+		if !ok {
+			continue
+		}
+		identity.Roles = append(identity.Roles, role)
+		for _, scope := range scopes {
+			if !identity.HasScope(scope) {
+				identity.Scopes = append(identity.Scopes, scope)
+			}
+		}
+	}
+	if len(identity.Roles) == 0 {
+		return nil, errNoRole
+	}
+	return identity, nil
+}
+
+//key returns the key verifying the signature of the token
+func (tv *JWTVerifier) key(token *jwt.Token) (interface{}, error) {
+	if token.Method.Alg() == jwt.SigningMethodHS256.Alg() {
+		return tv.secret, nil
+	}
+	kid, _ := token.Header["kid"].(string)
+	return tv.keys.Key(kid)
+}
+
+//rolesOf reads the roles claim, either a list of roles or a space separated string
+func rolesOf(claim interface{}) []string {
+	switch c := claim.(type) {
+	case string:
+		return strings.Fields(c)
+	case []interface{}:
+		roles := make([]string, 0, len(c))
+		for _, r := range c {
+			if role, ok := r.(string); ok {
+				roles = append(roles, role)
+			}
+		}
+		return roles
+	}
+	return nil
+}
@@ -0,0 +1,148 @@
+package usecase
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
+	"testing"
+	"time"
+
+	jwt "github.com/golang-jwt/jwt/v5"
+	"github.com/karanbhomiagit/order-service/config"
+	"github.com/karanbhomiagit/order-service/order"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockedKeySet struct {
+	mock.Mock
+}
+
+func (ks *MockedKeySet) Key(kid string) (interface{}, error) {
+	args := ks.Called(kid)
+	return args.Get(0), args.Error(1)
+}
+
+var testJWTSecret = []byte("s3cr3t")
+
+//claims returns valid claims of the end user holding the roles
+func claims(roles interface{}) jwt.MapClaims {
+	return jwt.MapClaims{
+		"sub":   "user-1",
+		"iss":   "https://id.example.com",
+		"exp":   time.Now().Add(time.Hour).Unix(),
+		"roles": roles,
+	}
+}
+
+func signHS256(t *testing.T, claims jwt.MapClaims, secret []byte) string {
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(secret)
+	assert.NoError(t, err)
+	return token
+}
+
+/*
+	Actual test functions
+*/
+
+func TestVerify(t *testing.T) {
+
+	t.Run("Grant the scopes of the roles of a HS256 token", func(t *testing.T) {
+		tv := NewJWTVerifier(config.JWT{Secret: string(testJWTSecret), Issuer: "https://id.example.com", RolesClaim: "roles"}, nil)
+		identity, err := tv.Verify(signHS256(t, claims([]string{"courier", "dispatcher"}), testJWTSecret))
+		assert := assert.New(t)
+		assert.Nil(err)
+		if assert.NotNil(identity) {
+			assert.Equal("user-1", identity.Subject)
+			assert.Equal([]string{order.RoleCourier}, identity.Roles)
+			assert.Equal([]string{order.ScopeOrdersRead, order.ScopeOrdersAssign}, identity.Scopes)
+		}
+	})
+
+	t.Run("Read the roles from a space separated claim", func(t *testing.T) {
+		tv := NewJWTVerifier(config.JWT{Secret: string(testJWTSecret), RolesClaim: "roles"}, nil)
+		identity, err := tv.Verify(signHS256(t, claims("merchant courier"), testJWTSecret))
+		assert := assert.New(t)
+		assert.Nil(err)
+		if assert.NotNil(identity) {
+			assert.Equal([]string{order.RoleMerchant, order.RoleCourier}, identity.Roles)
+			assert.ElementsMatch([]string{order.ScopeOrdersRead, order.ScopeOrdersCreate, order.ScopeOrdersCancel, order.ScopeOrdersAssign}, identity.Scopes)
+		}
+	})
+
+	t.Run("Return error for a token signed with another secret", func(t *testing.T) {
+		tv := NewJWTVerifier(config.JWT{Secret: string(testJWTSecret), RolesClaim: "roles"}, nil)
+		_, err := tv.Verify(signHS256(t, claims([]string{"courier"}), []byte("other")))
+		assert.Equal(t, "invalid_token", order.CodeOf(err))
+		assert.ErrorIs(t, err, jwt.ErrTokenSignatureInvalid)
+	})
+
+	t.Run("Return error for an expired token", func(t *testing.T) {
+		tv := NewJWTVerifier(config.JWT{Secret: string(testJWTSecret), RolesClaim: "roles"}, nil)
+		expired := claims([]string{"courier"})
+		expired["exp"] = time.Now().Add(-time.Minute).Unix()
+		_, err := tv.Verify(signHS256(t, expired, testJWTSecret))
+		assert.Equal(t, "invalid_token", order.CodeOf(err))
+	})
+
+	t.Run("Return error for a token of another issuer", func(t *testing.T) {
+		tv := NewJWTVerifier(config.JWT{Secret: string(testJWTSecret), Issuer: "https://other.example.com", RolesClaim: "roles"}, nil)
+		_, err := tv.Verify(signHS256(t, claims([]string{"courier"}), testJWTSecret))
+		assert.Equal(t, "invalid_token", order.CodeOf(err))
+	})
+
+	t.Run("Return error for a token granting none of the roles", func(t *testing.T) {
+		tv := NewJWTVerifier(config.JWT{Secret: string(testJWTSecret), RolesClaim: "roles"}, nil)
+		_, err := tv.Verify(signHS256(t, claims([]string{"dispatcher"}), testJWTSecret))
+		assert.Equal(t, errNoRole, err)
+	})
+
+	t.Run("Return error for a HS256 token when only RS256 is configured", func(t *testing.T) {
+		testObj := new(MockedKeySet)
+		tv := NewJWTVerifier(config.JWT{RolesClaim: "roles"}, testObj)
+		_, err := tv.Verify(signHS256(t, claims([]string{"courier"}), testJWTSecret))
+		assert.Equal(t, "invalid_token", order.CodeOf(err))
+		testObj.AssertExpectations(t)
+	})
+
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	signRS256 := func(kid string) string {
+		token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims([]string{"merchant"}))
+		token.Header["kid"] = kid
+		signed, err := token.SignedString(privateKey)
+		assert.NoError(t, err)
+		return signed
+	}
+
+	t.Run("Verify a RS256 token with the key of its key id", func(t *testing.T) {
+		testObj := new(MockedKeySet)
+		testObj.On("Key", "key-1").Return(&privateKey.PublicKey, nil)
+		tv := NewJWTVerifier(config.JWT{RolesClaim: "roles"}, testObj)
+		identity, err := tv.Verify(signRS256("key-1"))
+		assert := assert.New(t)
+		assert.Nil(err)
+		if assert.NotNil(identity) {
+			assert.Equal([]string{order.RoleMerchant}, identity.Roles)
+		}
+		testObj.AssertExpectations(t)
+	})
+
+	t.Run("Return error for a RS256 token signed with an unknown key", func(t *testing.T) {
+		testObj := new(MockedKeySet)
+		testObj.On("Key", "key-2").Return(nil, order.NewNotFound("unknown_key", "Unknown signing key key-2"))
+		tv := NewJWTVerifier(config.JWT{RolesClaim: "roles"}, testObj)
+		_, err := tv.Verify(signRS256("key-2"))
+		assert.Equal(t, "invalid_token", order.CodeOf(err))
+		testObj.AssertExpectations(t)
+	})
+
+	t.Run("Return unavailable when the keys cannot be fetched", func(t *testing.T) {
+		testObj := new(MockedKeySet)
+		testObj.On("Key", "key-1").Return(nil, order.NewUnavailable("jwks_unavailable", "Unable to fetch the keys of the identity provider", errors.New("connection refused")))
+		tv := NewJWTVerifier(config.JWT{RolesClaim: "roles"}, testObj)
+		_, err := tv.Verify(signRS256("key-1"))
+		assert.Equal(t, order.KindUnavailable, order.KindOf(err))
+		testObj.AssertExpectations(t)
+	})
+}
@@ -0,0 +1,94 @@
+package usecase
+
+import (
+	"sync"
+
+	"github.com/karanbhomiagit/order-service/models"
+	"github.com/karanbhomiagit/order-service/order"
+)
+
+const (
+	//FeedSubscriberBuffer is how many events a subscriber may fall behind before it is dropped
+	FeedSubscriberBuffer = 64
+)
+
+//OrderFeed fans order events out to live subscribers and remembers the latest ones for replay
+type OrderFeed struct {
+	mu          sync.Mutex
+	history     []models.OrderEvent
+	size        int
+	subscribers map[chan models.OrderEvent]struct{}
+	closed      bool
+}
+
+func NewOrderFeed(size int) *OrderFeed {
+	return &OrderFeed{
+		size:        size,
+		subscribers: make(map[chan models.OrderEvent]struct{}),
+	}
+}
+
+var _ order.Feed = (*OrderFeed)(nil)
+
+//Append records the event in the replay history and sends it to every subscriber.
+//Subscribers which are too far behind are dropped, they can resume from the history.
+func (f *OrderFeed) Append(event models.OrderEvent) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.history = append(f.history, event)
+	if len(f.history) > f.size {
+		f.history = f.history[len(f.history)-f.size:]
+	}
+	for ch := range f.subscribers {
+		select {
+		case ch <- event:
+		default:
+			delete(f.subscribers, ch)
+			close(ch)
+		}
+	}
+}
+
+//Subscribe returns the events recorded after lastEventID along with the channel of new events.
+//Every recorded event is replayed if lastEventID is no longer in the history; none if it is empty.
+func (f *OrderFeed) Subscribe(lastEventID string) ([]models.OrderEvent, <-chan models.OrderEvent, func()) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var replay []models.OrderEvent
+	if lastEventID != "" {
+		replay = append(replay, f.history...)
+		for i, event := range f.history {
+			if event.ID == lastEventID {
+				replay = append([]models.OrderEvent{}, f.history[i+1:]...)
+				break
+			}
+		}
+	}
+	ch := make(chan models.OrderEvent, FeedSubscriberBuffer)
+	if f.closed {
+		close(ch)
+		return replay, ch, func() {}
+	}
+	f.subscribers[ch] = struct{}{}
+	unsubscribe := func() {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		if _, ok := f.subscribers[ch]; ok {
+			delete(f.subscribers, ch)
+			close(ch)
+		}
+	}
+	return replay, ch, unsubscribe
+}
+
+//Close drops every subscriber, and those subscribing from then on, so that the streams end and their clients
+//resume from another replica while this one shuts down
+func (f *OrderFeed) Close() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.closed = true
+	for ch := range f.subscribers {
+		delete(f.subscribers, ch)
+		close(ch)
+	}
+}
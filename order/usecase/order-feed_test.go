@@ -0,0 +1,92 @@
+package usecase
+
+import (
+	"testing"
+
+	"github.com/karanbhomiagit/order-service/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func feedEvent(id string) models.OrderEvent {
+	return models.OrderEvent{ID: id, Type: models.EventOrderCreated}
+}
+
+func eventIDs(events []models.OrderEvent) []string {
+	ids := []string{}
+	for _, e := range events {
+		ids = append(ids, e.ID)
+	}
+	return ids
+}
+
+/*
+	Actual test functions
+*/
+
+func TestOrderFeed(t *testing.T) {
+
+	t.Run("Successfully send appended events to subscribers", func(t *testing.T) {
+		feed := NewOrderFeed(10)
+		replay, events, unsubscribe := feed.Subscribe("")
+		defer unsubscribe()
+		feed.Append(feedEvent("1"))
+
+		assert := assert.New(t)
+		assert.Empty(replay)
+		assert.Equal("1", (<-events).ID)
+	})
+
+	t.Run("Successfully replay the events after the last event id", func(t *testing.T) {
+		feed := NewOrderFeed(10)
+		feed.Append(feedEvent("1"))
+		feed.Append(feedEvent("2"))
+		feed.Append(feedEvent("3"))
+		replay, _, unsubscribe := feed.Subscribe("1")
+		defer unsubscribe()
+
+		assert.Equal(t, []string{"2", "3"}, eventIDs(replay))
+	})
+
+	t.Run("Successfully replay the bounded history if the last event id was evicted", func(t *testing.T) {
+		feed := NewOrderFeed(2)
+		feed.Append(feedEvent("1"))
+		feed.Append(feedEvent("2"))
+		feed.Append(feedEvent("3"))
+		replay, _, unsubscribe := feed.Subscribe("1")
+		defer unsubscribe()
+
+		assert.Equal(t, []string{"2", "3"}, eventIDs(replay))
+	})
+
+	t.Run("Drop subscribers which fall too far behind", func(t *testing.T) {
+		feed := NewOrderFeed(10)
+		_, events, unsubscribe := feed.Subscribe("")
+		defer unsubscribe()
+		for i := 0; i <= FeedSubscriberBuffer; i++ {
+			feed.Append(feedEvent("x"))
+		}
+
+		received := 0
+		for range events {
+			received++
+		}
+		assert.Equal(t, FeedSubscriberBuffer, received)
+	})
+
+	t.Run("Drop every subscriber once closed", func(t *testing.T) {
+		feed := NewOrderFeed(10)
+		_, events, unsubscribe := feed.Subscribe("")
+		defer unsubscribe()
+		feed.Append(feedEvent("1"))
+		feed.Close()
+		_, late, unsubscribeLate := feed.Subscribe("")
+		defer unsubscribeLate()
+
+		assert := assert.New(t)
+		assert.Equal("1", (<-events).ID)
+		_, ok := <-events
+		assert.False(ok)
+		_, ok = <-late
+		assert.False(ok)
+	})
+}
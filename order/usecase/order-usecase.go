@@ -2,59 +2,208 @@ package usecase
 
 import (
 	"context"
-	"errors"
-	"os"
-	"strconv"
+	"log/slog"
+	"slices"
+	"time"
 
+	"github.com/karanbhomiagit/order-service/config"
 	"github.com/karanbhomiagit/order-service/models"
 	"github.com/karanbhomiagit/order-service/order"
+	"github.com/karanbhomiagit/order-service/order/logging"
+	"github.com/karanbhomiagit/order-service/order/metrics"
+	"github.com/karanbhomiagit/order-service/order/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"googlemaps.github.io/maps"
+	"gopkg.in/mgo.v2/bson"
 )
 
 type OrderUsecase struct {
 	orderRepository order.Repository
+	config          config.Orders
+	logger          *slog.Logger
 }
 
-func NewOrderUsecase(or order.Repository) order.Usecase {
+func NewOrderUsecase(or order.Repository, cfg config.Orders, logger *slog.Logger) order.Usecase {
 	return &OrderUsecase{
 		orderRepository: or,
+		config:          cfg,
+		logger:          logger,
 	}
 }
 
+var (
+	errAssignOnly      = order.NewInvalidArgument("invalid_status", "This API route only supports assigning of orders. Please provide requested status as TAKEN")
+	errOrderExpired    = order.NewConflict("order_expired", "Order has expired")
+	errOrderAssigned   = order.NewConflict("order_already_assigned", "Order is already assigned")
+	errOrderCancelled  = order.NewConflict("order_cancelled", "Order has been cancelled")
+	errOrderNotFound   = order.NewNotFound("order_not_found", "not found")
+	errInvalidLocation = order.NewInvalidArgument("invalid_coordinates", "Unable to fetch distance from Google APIs. Please ensure data is in correct format")
+)
+
 const (
 	StatusUnassigned = "UNASSIGNED"
 	StatusTaken      = "TAKEN"
 	StatusSuccess    = "SUCCESS"
+	StatusExpired    = "EXPIRED"
+	StatusCancelled  = "CANCELLED"
 )
 
-//AssignByID updates the status of an already existing order
-func (ou *OrderUsecase) AssignByID(id string, status string) (*map[string]string, error) {
+//AssignByID updates the status of an already existing order. End users need to be couriers to take orders.
+func (ou *OrderUsecase) AssignByID(ctx context.Context, id string, status string) (res *map[string]string, err error) {
+	ctx, span := tracing.Start(ctx, "OrderUsecase.AssignByID", trace.WithAttributes(attribute.String("order.id", id)))
+	defer func() {
+		metrics.ObserveOrderOperation(metrics.OperationAssign, err)
+		tracing.End(span, err)
+	}()
+	if err := order.AuthorizeRole(ctx, order.RoleCourier); err != nil {
+		return nil, err
+	}
+	logging.SetOrderID(ctx, id)
 	//Check request body is correct
 	if status == "" || status != StatusTaken {
-		return nil, errors.New("This API route only supports assigning of orders. Please provide requested status as TAKEN")
+		return nil, errAssignOnly
 	}
 	//Call repository function to fetch order by ID
-	order, err := ou.orderRepository.FetchByID(id)
+	o, err := ou.orderRepository.FetchByID(ctx, id)
 	if err != nil {
 		return nil, err
 	}
-	if (*order).Status != StatusUnassigned {
-		return nil, errors.New("Order is already assigned")
+	if err := assignConflict((*o).Status); err != nil {
+		return nil, err
 	}
 	//Update status of the order
-	(*order).Status = StatusTaken
-	//Call repository function to update the order
-	err = ou.orderRepository.UpdateByID(order)
+	(*o).Status = StatusTaken
+	//Only take the order if it is still unassigned, as another courier, a cancellation or the expiry worker
+	//may have moved it on since it was read
+	_, err = ou.orderRepository.UpdateStatusByID(ctx, id, StatusUnassigned, StatusTaken, newOrderEvent(models.EventOrderAssigned, o, StatusUnassigned))
+	if order.KindOf(err) == order.KindConflict {
+		return nil, ou.assignConflictByID(ctx, id)
+	}
 	if err != nil {
 		return nil, err
 	}
+	ou.logger.InfoContext(ctx, "Order assigned")
 	return &map[string]string{"status": StatusSuccess}, nil
 }
 
+//assignConflict returns why an order in the status cannot be assigned, nil if it can
+func assignConflict(status string) error {
+	switch status {
+	case StatusUnassigned:
+		return nil
+	case StatusExpired:
+		return errOrderExpired
+	case StatusCancelled:
+		return errOrderCancelled
+	default:
+		return errOrderAssigned
+	}
+}
+
+//assignConflictByID returns why the order could not be assigned once its status changed under the courier
+func (ou *OrderUsecase) assignConflictByID(ctx context.Context, id string) error {
+	o, err := ou.orderRepository.FetchByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := assignConflict(o.Status); err != nil {
+		return err
+	}
+	//Moved on and back to unassigned in the meantime, the courier may try again
+	return errOrderAssigned
+}
+
+//CancelByID cancels an order which is either waiting for or assigned to a courier. End users can only cancel
+//their own orders, API clients any order.
+func (ou *OrderUsecase) CancelByID(ctx context.Context, id string) (res *models.Order, err error) {
+	ctx, span := tracing.Start(ctx, "OrderUsecase.CancelByID", trace.WithAttributes(attribute.String("order.id", id)))
+	defer func() { tracing.End(span, err) }()
+	logging.SetOrderID(ctx, id)
+	//Call repository function to fetch order by ID
+	o, err := ou.orderRepository.FetchByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	//Orders of other end users are not disclosed
+	if identity, ok := order.IdentityFrom(ctx); ok && len(identity.Roles) > 0 && (*o).Owner != identity.Subject {
+		return nil, errOrderNotFound
+	}
+	previousStatus := (*o).Status
+	if previousStatus == StatusExpired {
+		return nil, errOrderExpired
+	}
+	if previousStatus == StatusCancelled {
+		return nil, errOrderCancelled
+	}
+	(*o).Status = StatusCancelled
+	//Only cancel the order if nobody changed its status in the meantime
+	updatedAt, err := ou.orderRepository.UpdateStatusByID(ctx, id, previousStatus, StatusCancelled, newOrderEvent(models.EventOrderStatusChanged, o, previousStatus))
+	if err != nil {
+		return nil, err
+	}
+	(*o).UpdatedAt = updatedAt
+	return o, nil
+}
+
+//transitions lists the statuses an order may be forced to from each status. Expired orders stay expired,
+//as the expiry worker would expire them again.
+var transitions = map[string][]string{
+	StatusUnassigned: {StatusTaken, StatusExpired, StatusCancelled},
+	StatusTaken:      {StatusUnassigned, StatusCancelled},
+}
+
+//TransitionByID forces an order to another status for support staff, e.g. to release an order taken by a
+//courier who will never deliver it. End users cannot force orders, and only the transitions listed are allowed.
+func (ou *OrderUsecase) TransitionByID(ctx context.Context, id string, status string) (res *models.Order, err error) {
+	ctx, span := tracing.Start(ctx, "OrderUsecase.TransitionByID", trace.WithAttributes(attribute.String("order.id", id)))
+	defer func() { tracing.End(span, err) }()
+	if identity, ok := order.IdentityFrom(ctx); ok && len(identity.Roles) > 0 {
+		return nil, order.NewPermissionDenied("forbidden_role", "Only support staff can force the status of an order")
+	}
+	logging.SetOrderID(ctx, id)
+	switch status {
+	case StatusUnassigned, StatusTaken, StatusExpired, StatusCancelled:
+	default:
+		return nil, order.NewInvalidArgument("invalid_status", "Unknown status "+status)
+	}
+	//Call repository function to fetch order by ID
+	o, err := ou.orderRepository.FetchByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	previousStatus := (*o).Status
+	if !slices.Contains(transitions[previousStatus], status) {
+		return nil, order.NewConflict("invalid_transition", "Order cannot move from "+previousStatus+" to "+status)
+	}
+	(*o).Status = status
+	//Consumers of the events learn about forced assignments as they do about the others
+	eventType := models.EventOrderStatusChanged
+	if status == StatusTaken {
+		eventType = models.EventOrderAssigned
+	}
+	//Only change the order if nobody changed its status in the meantime
+	updatedAt, err := ou.orderRepository.UpdateStatusByID(ctx, id, previousStatus, status, newOrderEvent(eventType, o, previousStatus))
+	if err != nil {
+		return nil, err
+	}
+	(*o).UpdatedAt = updatedAt
+	ou.logger.InfoContext(ctx, "Order status forced", "previous_status", previousStatus, "status", status)
+	return o, nil
+}
+
+//FetchByID returns a single order
+func (ou *OrderUsecase) FetchByID(ctx context.Context, id string) (res *models.Order, err error) {
+	ctx, span := tracing.Start(ctx, "OrderUsecase.FetchByID", trace.WithAttributes(attribute.String("order.id", id)))
+	defer func() { tracing.End(span, err) }()
+	return ou.orderRepository.FetchByID(ctx, id)
+}
+
 //FetchByRange returns a list of orders based on paging parameters
-func (ou *OrderUsecase) FetchByRange(page int, limit int) ([]models.Order, error) {
-	pageSizeEnv := pageSize()
-	pageSize, _ := strconv.Atoi(pageSizeEnv)
+func (ou *OrderUsecase) FetchByRange(ctx context.Context, page int, limit int) (res []models.Order, err error) {
+	ctx, span := tracing.Start(ctx, "OrderUsecase.FetchByRange")
+	defer func() { tracing.End(span, err) }()
+	pageSize := ou.config.PageSize
 	//If limit is zero, return
 	if limit == 0 {
 		return []models.Order{}, nil
@@ -64,37 +213,106 @@ func (ou *OrderUsecase) FetchByRange(page int, limit int) ([]models.Order, error
 		limit = pageSize
 	}
 	//Call repository layer to fetch orders in the range
-	return ou.orderRepository.FetchByRange((page-1)*pageSize, limit)
+	return ou.orderRepository.FetchByRange(ctx, (page-1)*pageSize, limit)
+}
+
+//FetchByFilter returns a list of orders matching the filter based on paging parameters
+func (ou *OrderUsecase) FetchByFilter(ctx context.Context, filter *models.OrderFilter, page int, limit int) (res []models.Order, err error) {
+	ctx, span := tracing.Start(ctx, "OrderUsecase.FetchByFilter")
+	defer func() { tracing.End(span, err) }()
+	pageSize := ou.config.PageSize
+	//If limit is zero, return
+	if limit == 0 {
+		return []models.Order{}, nil
+	}
+	//If limit is more than page size, change it to page size
+	if limit > pageSize {
+		limit = pageSize
+	}
+	//Call repository layer to fetch the matching orders in the range
+	return ou.orderRepository.FetchByFilter(ctx, filter, (page-1)*pageSize, limit)
+}
+
+//FetchByFilterAfter returns the orders matching the filter which come after the order with the given id, or from
+//the first when it is empty, in the order of their ids. Walking the ids pages through every order exactly once.
+func (ou *OrderUsecase) FetchByFilterAfter(ctx context.Context, filter *models.OrderFilter, after string, limit int) (res []models.Order, err error) {
+	ctx, span := tracing.Start(ctx, "OrderUsecase.FetchByFilterAfter")
+	defer func() { tracing.End(span, err) }()
+	//If limit is zero, return
+	if limit == 0 {
+		return []models.Order{}, nil
+	}
+	//If limit is more than page size, change it to page size
+	if limit > ou.config.PageSize {
+		limit = ou.config.PageSize
+	}
+	//Call repository layer to fetch the matching orders following the id
+	return ou.orderRepository.FetchByFilterAfter(ctx, filter, after, limit)
 }
 
-//Store calculates distance and stores the order record
-func (ou *OrderUsecase) Store(orderReq *models.OrderRequest) (*models.Order, error) {
-	distance, err := getDistanceFromExternalService(orderReq.Origin, orderReq.Destination)
+//Store calculates distance and stores the order record. End users need to be merchants to place orders.
+func (ou *OrderUsecase) Store(ctx context.Context, orderReq *models.OrderRequest) (res *models.Order, err error) {
+	ctx, span := tracing.Start(ctx, "OrderUsecase.Store")
+	defer func() {
+		metrics.ObserveOrderOperation(metrics.OperationCreate, err)
+		tracing.End(span, err)
+	}()
+	if err := order.AuthorizeRole(ctx, order.RoleMerchant); err != nil {
+		return nil, err
+	}
+	distance, err := getDistanceFromExternalService(ctx, ou.config.Google, orderReq.Origin, orderReq.Destination)
 	if err != nil {
 		return nil, err
 	}
-	//Create Order record
-	order := models.Order{
+	//Create Order record, owned by the client placing it so its webhooks receive the events of the order
+	o := models.Order{
 		Distance: distance,
 		Status:   StatusUnassigned,
 	}
-	//Call repository layer to store the order
-	return ou.orderRepository.Store(&order)
+	if identity, ok := order.IdentityFrom(ctx); ok {
+		o.Owner = identity.Subject
+	}
+	//Call repository layer to store the order along with its event
+	res, err = ou.orderRepository.Store(ctx, &o, newOrderEvent(models.EventOrderCreated, &o, ""))
+	if err != nil {
+		return nil, err
+	}
+	logging.SetOrderID(ctx, res.ID.Hex())
+	span.SetAttributes(attribute.String("order.id", res.ID.Hex()))
+	ou.logger.InfoContext(ctx, "Order created", "distance", res.Distance)
+	return res, nil
+}
+
+//newOrderEvent builds an event describing the current state of the order
+func newOrderEvent(eventType string, order *models.Order, previousStatus string) models.OrderEvent {
+	return models.OrderEvent{
+		ID:         bson.NewObjectId().Hex(),
+		Type:       eventType,
+		Version:    models.EventSchemaVersion,
+		OccurredAt: time.Now().UTC(),
+		Data: models.OrderEventData{
+			OrderID:        order.ID.Hex(),
+			Status:         order.Status,
+			PreviousStatus: previousStatus,
+			Distance:       order.Distance,
+			Owner:          order.Owner,
+		},
+	}
 }
 
 //getDistanceFromExternalService calls google maps library functions to calculate distance between coordinates
-func getDistanceFromExternalService(origin []string, destination []string) (distance int, err error) {
+func getDistanceFromExternalService(ctx context.Context, google config.Google, origin []string, destination []string) (distance int, err error) {
+	ctx, span := tracing.Start(ctx, "google.distance_matrix", trace.WithSpanKind(trace.SpanKindClient))
 	defer func() {
 		// recover from panic if one occured.
 		if recover() != nil {
-			err = errors.New("Unable to fetch distance from Google APIs. Please ensure data is in correct format")
+			err = errInvalidLocation
 		}
+		tracing.End(span, err)
 	}()
-	apiKey := os.Getenv("GOOGLE_API_KEY")
-	serverURL := os.Getenv("GOOGLE_SERVER_URL")
-
-	c, err := maps.NewClient(maps.WithAPIKey(apiKey), maps.WithBaseURL(serverURL))
+	c, err := maps.NewClient(maps.WithAPIKey(google.GoogleAPIKey), maps.WithBaseURL(google.GoogleServerURL))
 	if err != nil {
+		err = order.NewUnavailable("distance_unavailable", "Unable to fetch distance from Google APIs", err)
 		return
 	}
 	r := &maps.DistanceMatrixRequest{
@@ -102,23 +320,23 @@ func getDistanceFromExternalService(origin []string, destination []string) (dist
 		Destinations: []string{destination[0] + "," + destination[1]},
 	}
 
-	resp, err := c.DistanceMatrix(context.Background(), r)
+	start := time.Now()
+	resp, err := c.DistanceMatrix(ctx, r)
+	metrics.ObserveDistanceRequest(time.Since(start), err)
+	//The request was abandoned by the client or ran out of time, Google did not fail
+	if err != nil && ctx.Err() != nil {
+		err = order.NewCanceled(ctx.Err())
+		return
+	}
 	if err != nil {
+		err = order.NewUnavailable("distance_unavailable", "Unable to fetch distance from Google APIs", err)
 		return
 	}
 	//Return error if status is other than OK, like ZERO_RESULTS
 	if resp.Rows[0].Elements[0].Status != "OK" {
-		err = errors.New("Unable to fetch distance from Google APIs, Status : " + resp.Rows[0].Elements[0].Status)
+		err = order.NewInvalidArgument("distance_not_found", "Unable to fetch distance from Google APIs, Status : "+resp.Rows[0].Elements[0].Status)
 		return
 	}
 	distance = resp.Rows[0].Elements[0].Distance.Meters
 	return
 }
-
-func pageSize() string {
-	pageSize := os.Getenv("PAGE_SIZE")
-	if len(pageSize) == 0 {
-		pageSize = "10"
-	}
-	return pageSize
-}
@@ -1,40 +1,73 @@
 package usecase
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"log/slog"
 	"net/http"
 	"net/http/httptest"
-	"os"
 	"testing"
+	"time"
 
+	"github.com/karanbhomiagit/order-service/config"
 	"github.com/karanbhomiagit/order-service/models"
+	"github.com/karanbhomiagit/order-service/order"
+	"github.com/karanbhomiagit/order-service/order/metrics"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
 	"gopkg.in/mgo.v2/bson"
 )
 
+//testLogger discards the lines logged by the usecases under test
+var testLogger = slog.New(slog.DiscardHandler)
+
+//testOrders configures the order usecase under test to request distances from the server at url
+func testOrders(url string) config.Orders {
+	return config.Orders{PageSize: 10, Google: config.Google{GoogleAPIKey: apiKey, GoogleServerURL: url}}
+}
+
 type MockedOrderRepository struct {
 	mock.Mock
 }
 
-func (or *MockedOrderRepository) FetchByID(id string) (*models.Order, error) {
+func (or *MockedOrderRepository) FetchByID(ctx context.Context, id string) (*models.Order, error) {
 	args := or.Called(id)
 	return args.Get(0).(*models.Order), args.Error(1)
 }
 
-func (or *MockedOrderRepository) UpdateByID(order *models.Order) error {
-	args := or.Called(order)
-	return args.Error(0)
+func (or *MockedOrderRepository) FetchByRange(ctx context.Context, skip int, limit int) ([]models.Order, error) {
+	args := or.Called(skip, limit)
+	return args.Get(0).([]models.Order), args.Error(1)
+}
+
+func (or *MockedOrderRepository) FetchByFilter(ctx context.Context, filter *models.OrderFilter, skip int, limit int) ([]models.Order, error) {
+	args := or.Called(filter, skip, limit)
+	return args.Get(0).([]models.Order), args.Error(1)
 }
 
-func (or *MockedOrderRepository) FetchByRange(skip int, limit int) ([]models.Order, error) {
-	args := or.Called(skip, limit)
+func (or *MockedOrderRepository) FetchByFilterAfter(ctx context.Context, filter *models.OrderFilter, after string, limit int) ([]models.Order, error) {
+	args := or.Called(filter, after, limit)
+	return args.Get(0).([]models.Order), args.Error(1)
+}
+
+func (or *MockedOrderRepository) FetchByStatusBefore(ctx context.Context, status string, before time.Time, limit int) ([]models.Order, error) {
+	args := or.Called(status, before, limit)
 	return args.Get(0).([]models.Order), args.Error(1)
 }
 
-func (or *MockedOrderRepository) Store(order *models.Order) (*models.Order, error) {
-	args := or.Called(order)
+func (or *MockedOrderRepository) UpdateStatusByID(ctx context.Context, id string, from string, to string, event models.OrderEvent) (time.Time, error) {
+	args := or.Called(id, from, to, event)
+	return args.Get(0).(time.Time), args.Error(1)
+}
+
+func (or *MockedOrderRepository) Store(ctx context.Context, order *models.Order, event models.OrderEvent) (*models.Order, error) {
+	args := or.Called(order, event)
 	return args.Get(0).(*models.Order), args.Error(1)
 }
 
@@ -52,15 +85,13 @@ func TestAssignByID(t *testing.T) {
 			Status:   "UNASSIGNED",
 		}
 		testObj.On("FetchByID", "5c2b2aaf4530558539f91859").Return(&testOrder, nil)
-		changedTestOrder := models.Order{
-			ID:       "5c2b2aaf4530558539f91859",
-			Distance: 12345,
-			Status:   "TAKEN",
-		}
-		testObj.On("UpdateByID", &changedTestOrder).Return(nil)
+		testObj.On("UpdateStatusByID", "5c2b2aaf4530558539f91859", "UNASSIGNED", "TAKEN", mock.MatchedBy(func(e models.OrderEvent) bool {
+			return e.Type == "order.assigned" && e.Data.OrderID == testOrder.ID.Hex() &&
+				e.Data.Status == "TAKEN" && e.Data.PreviousStatus == "UNASSIGNED"
+		})).Return(time.Now(), nil)
 
-		orderUsecase := NewOrderUsecase(testObj)
-		response, err := orderUsecase.AssignByID("5c2b2aaf4530558539f91859", "TAKEN")
+		orderUsecase := NewOrderUsecase(testObj, testOrders(""), testLogger)
+		response, err := orderUsecase.AssignByID(context.Background(), "5c2b2aaf4530558539f91859", "TAKEN")
 		assert := assert.New(t)
 		assert.Nil(err)
 		assert.Equal(response, &map[string]string{"status": "SUCCESS"})
@@ -69,11 +100,12 @@ func TestAssignByID(t *testing.T) {
 
 	t.Run("Return error for wrong status request", func(t *testing.T) {
 		testObj := new(MockedOrderRepository)
-		orderUsecase := NewOrderUsecase(testObj)
-		_, err := orderUsecase.AssignByID("5c2b2aaf4530558539f91859", "RELEIVE")
+		orderUsecase := NewOrderUsecase(testObj, testOrders(""), testLogger)
+		_, err := orderUsecase.AssignByID(context.Background(), "5c2b2aaf4530558539f91859", "RELEIVE")
 		assert := assert.New(t)
 		if assert.NotNil(err) {
 			assert.Equal("This API route only supports assigning of orders. Please provide requested status as TAKEN", err.Error())
+			assert.Equal(order.KindInvalidArgument, order.KindOf(err))
 		}
 		testObj.AssertExpectations(t)
 	})
@@ -87,11 +119,49 @@ func TestAssignByID(t *testing.T) {
 		}
 		testObj.On("FetchByID", "5c2b2aaf4530558539f91859").Return(&testOrder, nil)
 
-		orderUsecase := NewOrderUsecase(testObj)
-		_, err := orderUsecase.AssignByID("5c2b2aaf4530558539f91859", "TAKEN")
+		orderUsecase := NewOrderUsecase(testObj, testOrders(""), testLogger)
+		_, err := orderUsecase.AssignByID(context.Background(), "5c2b2aaf4530558539f91859", "TAKEN")
 		assert := assert.New(t)
 		if assert.NotNil(err) {
 			assert.Equal("Order is already assigned", err.Error())
+			assert.Equal(order.KindConflict, order.KindOf(err))
+			assert.Equal("order_already_assigned", order.CodeOf(err))
+		}
+		testObj.AssertExpectations(t)
+	})
+
+	t.Run("Return error if order has expired", func(t *testing.T) {
+		testObj := new(MockedOrderRepository)
+		testOrder := models.Order{
+			ID:       "5c2b2aaf4530558539f91859",
+			Distance: 12345,
+			Status:   "EXPIRED",
+		}
+		testObj.On("FetchByID", "5c2b2aaf4530558539f91859").Return(&testOrder, nil)
+
+		orderUsecase := NewOrderUsecase(testObj, testOrders(""), testLogger)
+		_, err := orderUsecase.AssignByID(context.Background(), "5c2b2aaf4530558539f91859", "TAKEN")
+		assert := assert.New(t)
+		if assert.NotNil(err) {
+			assert.Equal("Order has expired", err.Error())
+		}
+		testObj.AssertExpectations(t)
+	})
+
+	t.Run("Return error if order has been cancelled", func(t *testing.T) {
+		testObj := new(MockedOrderRepository)
+		testOrder := models.Order{
+			ID:       "5c2b2aaf4530558539f91859",
+			Distance: 12345,
+			Status:   "CANCELLED",
+		}
+		testObj.On("FetchByID", "5c2b2aaf4530558539f91859").Return(&testOrder, nil)
+
+		orderUsecase := NewOrderUsecase(testObj, testOrders(""), testLogger)
+		_, err := orderUsecase.AssignByID(context.Background(), "5c2b2aaf4530558539f91859", "TAKEN")
+		assert := assert.New(t)
+		if assert.NotNil(err) {
+			assert.Equal("order_cancelled", order.CodeOf(err))
 		}
 		testObj.AssertExpectations(t)
 	})
@@ -100,8 +170,8 @@ func TestAssignByID(t *testing.T) {
 		testObj := new(MockedOrderRepository)
 		testObj.On("FetchByID", "5c2b2aaf4530558539f91859").Return(&models.Order{}, errors.New("not found"))
 
-		orderUsecase := NewOrderUsecase(testObj)
-		_, err := orderUsecase.AssignByID("5c2b2aaf4530558539f91859", "TAKEN")
+		orderUsecase := NewOrderUsecase(testObj, testOrders(""), testLogger)
+		_, err := orderUsecase.AssignByID(context.Background(), "5c2b2aaf4530558539f91859", "TAKEN")
 		assert := assert.New(t)
 		if assert.NotNil(err) {
 			assert.Equal("not found", err.Error())
@@ -117,15 +187,10 @@ func TestAssignByID(t *testing.T) {
 			Status:   "UNASSIGNED",
 		}
 		testObj.On("FetchByID", "5c2b2aaf4530558539f91859").Return(&testOrder, nil)
-		changedTestOrder := models.Order{
-			ID:       "5c2b2aaf4530558539f91859",
-			Distance: 12345,
-			Status:   "TAKEN",
-		}
-		testObj.On("UpdateByID", &changedTestOrder).Return(errors.New("connection lost"))
+		testObj.On("UpdateStatusByID", "5c2b2aaf4530558539f91859", "UNASSIGNED", "TAKEN", mock.Anything).Return(time.Time{}, errors.New("connection lost"))
 
-		orderUsecase := NewOrderUsecase(testObj)
-		_, err := orderUsecase.AssignByID("5c2b2aaf4530558539f91859", "TAKEN")
+		orderUsecase := NewOrderUsecase(testObj, testOrders(""), testLogger)
+		_, err := orderUsecase.AssignByID(context.Background(), "5c2b2aaf4530558539f91859", "TAKEN")
 		assert := assert.New(t)
 		if assert.NotNil(err) {
 			assert.Equal("connection lost", err.Error())
@@ -133,6 +198,76 @@ func TestAssignByID(t *testing.T) {
 		testObj.AssertExpectations(t)
 	})
 
+	t.Run("Return error if the order expired while being assigned", func(t *testing.T) {
+		testObj := new(MockedOrderRepository)
+		testObj.On("FetchByID", "5c2b2aaf4530558539f91859").Return(&models.Order{ID: "5c2b2aaf4530558539f91859", Status: "UNASSIGNED"}, nil).Once()
+		testObj.On("UpdateStatusByID", "5c2b2aaf4530558539f91859", "UNASSIGNED", "TAKEN", mock.Anything).Return(time.Time{}, order.NewConflict("order_status_conflict", "Order is no longer UNASSIGNED"))
+		testObj.On("FetchByID", "5c2b2aaf4530558539f91859").Return(&models.Order{ID: "5c2b2aaf4530558539f91859", Status: "EXPIRED"}, nil).Once()
+
+		orderUsecase := NewOrderUsecase(testObj, testOrders(""), testLogger)
+		_, err := orderUsecase.AssignByID(context.Background(), "5c2b2aaf4530558539f91859", "TAKEN")
+		assert := assert.New(t)
+		assert.Equal(order.KindConflict, order.KindOf(err))
+		assert.Equal("order_expired", order.CodeOf(err))
+		testObj.AssertExpectations(t)
+	})
+
+	t.Run("Return error if another courier assigned the order first", func(t *testing.T) {
+		testObj := new(MockedOrderRepository)
+		testObj.On("FetchByID", "5c2b2aaf4530558539f91859").Return(&models.Order{ID: "5c2b2aaf4530558539f91859", Status: "UNASSIGNED"}, nil).Once()
+		testObj.On("UpdateStatusByID", "5c2b2aaf4530558539f91859", "UNASSIGNED", "TAKEN", mock.Anything).Return(time.Time{}, order.NewConflict("order_status_conflict", "Order is no longer UNASSIGNED"))
+		testObj.On("FetchByID", "5c2b2aaf4530558539f91859").Return(&models.Order{ID: "5c2b2aaf4530558539f91859", Status: "TAKEN"}, nil).Once()
+
+		orderUsecase := NewOrderUsecase(testObj, testOrders(""), testLogger)
+		_, err := orderUsecase.AssignByID(context.Background(), "5c2b2aaf4530558539f91859", "TAKEN")
+		assert := assert.New(t)
+		assert.Equal("order_already_assigned", order.CodeOf(err))
+		testObj.AssertExpectations(t)
+	})
+
+	t.Run("Return error when an end user who is not a courier assigns an order", func(t *testing.T) {
+		testObj := new(MockedOrderRepository)
+		merchant := &order.Identity{Subject: "m1", Roles: []string{order.RoleMerchant}}
+
+		orderUsecase := NewOrderUsecase(testObj, testOrders(""), testLogger)
+		_, err := orderUsecase.AssignByID(order.NewContext(context.Background(), merchant), "5c2b2aaf4530558539f91859", "TAKEN")
+		assert := assert.New(t)
+		assert.Equal(order.KindPermissionDenied, order.KindOf(err))
+		assert.Equal("forbidden_role", order.CodeOf(err))
+		testObj.AssertExpectations(t)
+	})
+
+	t.Run("Successfully assign an order as a courier", func(t *testing.T) {
+		testObj := new(MockedOrderRepository)
+		testOrder := models.Order{ID: "5c2b2aaf4530558539f91859", Distance: 12345, Status: "UNASSIGNED"}
+		testObj.On("FetchByID", "5c2b2aaf4530558539f91859").Return(&testOrder, nil)
+		testObj.On("UpdateStatusByID", "5c2b2aaf4530558539f91859", "UNASSIGNED", "TAKEN", mock.Anything).Return(time.Now(), nil)
+		courier := &order.Identity{Subject: "c1", Roles: []string{order.RoleCourier}}
+
+		orderUsecase := NewOrderUsecase(testObj, testOrders(""), testLogger)
+		_, err := orderUsecase.AssignByID(order.NewContext(context.Background(), courier), "5c2b2aaf4530558539f91859", "TAKEN")
+		assert := assert.New(t)
+		assert.Nil(err)
+		testObj.AssertExpectations(t)
+	})
+
+	t.Run("Count assigned orders and failures by error code", func(t *testing.T) {
+		testObj := new(MockedOrderRepository)
+		testObj.On("FetchByID", "5c2b2aaf4530558539f91859").Return(&models.Order{ID: "5c2b2aaf4530558539f91859", Status: "UNASSIGNED"}, nil).Once()
+		testObj.On("UpdateStatusByID", "5c2b2aaf4530558539f91859", "UNASSIGNED", "TAKEN", mock.Anything).Return(time.Now(), nil)
+		testObj.On("FetchByID", "5c2b2aaf4530558539f91859").Return(&models.Order{ID: "5c2b2aaf4530558539f91859", Status: "TAKEN"}, nil).Once()
+		assigned := testutil.ToFloat64(metrics.OrdersAssigned)
+		failures := testutil.ToFloat64(metrics.OrderFailures.WithLabelValues(metrics.OperationAssign, "order_already_assigned"))
+
+		orderUsecase := NewOrderUsecase(testObj, testOrders(""), testLogger)
+		orderUsecase.AssignByID(context.Background(), "5c2b2aaf4530558539f91859", "TAKEN")
+		orderUsecase.AssignByID(context.Background(), "5c2b2aaf4530558539f91859", "TAKEN")
+		assert := assert.New(t)
+		assert.Equal(assigned+1, testutil.ToFloat64(metrics.OrdersAssigned))
+		assert.Equal(failures+1, testutil.ToFloat64(metrics.OrderFailures.WithLabelValues(metrics.OperationAssign, "order_already_assigned")))
+		testObj.AssertExpectations(t)
+	})
+
 }
 
 func TestFetchByRange(t *testing.T) {
@@ -151,9 +286,8 @@ func TestFetchByRange(t *testing.T) {
 		}
 		testObj.On("FetchByRange", 0, 10).Return([]models.Order{testOrder1, testOrder2}, nil)
 
-		orderUsecase := NewOrderUsecase(testObj)
-		os.Setenv("PAGE_SIZE", "10")
-		res, err := orderUsecase.FetchByRange(1, 10)
+		orderUsecase := NewOrderUsecase(testObj, testOrders(""), testLogger)
+		res, err := orderUsecase.FetchByRange(context.Background(), 1, 10)
 		assert := assert.New(t)
 		assert.Nil(err)
 		if assert.NotNil(res) {
@@ -176,9 +310,8 @@ func TestFetchByRange(t *testing.T) {
 		}
 		testObj.On("FetchByRange", 10, 10).Return([]models.Order{testOrder1, testOrder2}, nil)
 
-		orderUsecase := NewOrderUsecase(testObj)
-		os.Setenv("PAGE_SIZE", "10")
-		res, err := orderUsecase.FetchByRange(2, 11)
+		orderUsecase := NewOrderUsecase(testObj, testOrders(""), testLogger)
+		res, err := orderUsecase.FetchByRange(context.Background(), 2, 11)
 		assert := assert.New(t)
 		assert.Nil(err)
 		if assert.NotNil(res) {
@@ -189,9 +322,8 @@ func TestFetchByRange(t *testing.T) {
 
 	t.Run("Successfully return empty list if limit is 0", func(t *testing.T) {
 		testObj := new(MockedOrderRepository)
-		orderUsecase := NewOrderUsecase(testObj)
-		os.Setenv("PAGE_SIZE", "10")
-		res, err := orderUsecase.FetchByRange(2, 0)
+		orderUsecase := NewOrderUsecase(testObj, testOrders(""), testLogger)
+		res, err := orderUsecase.FetchByRange(context.Background(), 2, 0)
 		assert := assert.New(t)
 		assert.Nil(err)
 		if assert.NotNil(res) {
@@ -202,8 +334,304 @@ func TestFetchByRange(t *testing.T) {
 
 }
 
+func TestFetchByFilter(t *testing.T) {
+
+	t.Run("Successfully fetch orders matching the filter in range", func(t *testing.T) {
+		testObj := new(MockedOrderRepository)
+		filter := &models.OrderFilter{Statuses: []string{"TAKEN"}, MinDistance: 1000}
+		testOrder := models.Order{
+			ID:       "5c2b2aaf4530558539f91858",
+			Distance: 52345,
+			Status:   "TAKEN",
+		}
+		testObj.On("FetchByFilter", filter, 10, 10).Return([]models.Order{testOrder}, nil)
+
+		orderUsecase := NewOrderUsecase(testObj, testOrders(""), testLogger)
+		res, err := orderUsecase.FetchByFilter(context.Background(), filter, 2, 11)
+		assert := assert.New(t)
+		assert.Nil(err)
+		assert.Equal([]models.Order{testOrder}, res)
+		testObj.AssertExpectations(t)
+	})
+
+	t.Run("Successfully return empty list if limit is 0", func(t *testing.T) {
+		testObj := new(MockedOrderRepository)
+		orderUsecase := NewOrderUsecase(testObj, testOrders(""), testLogger)
+		res, err := orderUsecase.FetchByFilter(context.Background(), &models.OrderFilter{}, 1, 0)
+		assert := assert.New(t)
+		assert.Nil(err)
+		assert.Equal(0, len(res))
+		testObj.AssertExpectations(t)
+	})
+
+}
+
+func TestFetchByFilterAfter(t *testing.T) {
+
+	t.Run("Successfully fetch orders matching the filter after the given one", func(t *testing.T) {
+		testObj := new(MockedOrderRepository)
+		filter := &models.OrderFilter{Statuses: []string{"TAKEN"}}
+		testOrder := models.Order{
+			ID:       "5c2b2aaf4530558539f91859",
+			Distance: 52345,
+			Status:   "TAKEN",
+		}
+		testObj.On("FetchByFilterAfter", filter, "5c2b2aaf4530558539f91858", 10).Return([]models.Order{testOrder}, nil)
+
+		orderUsecase := NewOrderUsecase(testObj, testOrders(""), testLogger)
+		res, err := orderUsecase.FetchByFilterAfter(context.Background(), filter, "5c2b2aaf4530558539f91858", 11)
+		assert := assert.New(t)
+		assert.Nil(err)
+		assert.Equal([]models.Order{testOrder}, res)
+		testObj.AssertExpectations(t)
+	})
+
+	t.Run("Successfully return empty list if limit is 0", func(t *testing.T) {
+		testObj := new(MockedOrderRepository)
+		orderUsecase := NewOrderUsecase(testObj, testOrders(""), testLogger)
+		res, err := orderUsecase.FetchByFilterAfter(context.Background(), &models.OrderFilter{}, "", 0)
+		assert := assert.New(t)
+		assert.Nil(err)
+		assert.Equal(0, len(res))
+		testObj.AssertExpectations(t)
+	})
+
+}
+
+func TestCancelByID(t *testing.T) {
+
+	t.Run("Successfully cancel an assigned order", func(t *testing.T) {
+		testObj := new(MockedOrderRepository)
+		updatedAt := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+		testOrder := models.Order{
+			ID:       "5c2b2aaf4530558539f91859",
+			Distance: 12345,
+			Status:   "TAKEN",
+		}
+		testObj.On("FetchByID", "5c2b2aaf4530558539f91859").Return(&testOrder, nil)
+		testObj.On("UpdateStatusByID", "5c2b2aaf4530558539f91859", "TAKEN", "CANCELLED", mock.MatchedBy(func(e models.OrderEvent) bool {
+			return e.Type == "order.status_changed" && e.Data.Status == "CANCELLED" && e.Data.PreviousStatus == "TAKEN"
+		})).Return(updatedAt, nil)
+
+		orderUsecase := NewOrderUsecase(testObj, testOrders(""), testLogger)
+		res, err := orderUsecase.CancelByID(context.Background(), "5c2b2aaf4530558539f91859")
+		assert := assert.New(t)
+		assert.Nil(err)
+		if assert.NotNil(res) {
+			assert.Equal("CANCELLED", res.Status)
+			assert.Equal(updatedAt, res.UpdatedAt)
+		}
+		testObj.AssertExpectations(t)
+	})
+
+	t.Run("Successfully cancel an order of the merchant", func(t *testing.T) {
+		testObj := new(MockedOrderRepository)
+		testOrder := models.Order{
+			ID:       "5c2b2aaf4530558539f91859",
+			Distance: 12345,
+			Status:   "UNASSIGNED",
+			Owner:    "merchant-a",
+		}
+		testObj.On("FetchByID", "5c2b2aaf4530558539f91859").Return(&testOrder, nil)
+		testObj.On("UpdateStatusByID", "5c2b2aaf4530558539f91859", "UNASSIGNED", "CANCELLED", mock.Anything).Return(time.Now(), nil)
+
+		orderUsecase := NewOrderUsecase(testObj, testOrders(""), testLogger)
+		ctx := order.NewContext(context.Background(), &order.Identity{Subject: "merchant-a", Roles: []string{order.RoleMerchant}})
+		res, err := orderUsecase.CancelByID(ctx, "5c2b2aaf4530558539f91859")
+		assert := assert.New(t)
+		assert.Nil(err)
+		if assert.NotNil(res) {
+			assert.Equal("CANCELLED", res.Status)
+		}
+		testObj.AssertExpectations(t)
+	})
+
+	t.Run("Return not found when a merchant cancels the order of another merchant", func(t *testing.T) {
+		testObj := new(MockedOrderRepository)
+		testOrder := models.Order{
+			ID:       "5c2b2aaf4530558539f91859",
+			Distance: 12345,
+			Status:   "UNASSIGNED",
+			Owner:    "merchant-b",
+		}
+		testObj.On("FetchByID", "5c2b2aaf4530558539f91859").Return(&testOrder, nil)
+
+		orderUsecase := NewOrderUsecase(testObj, testOrders(""), testLogger)
+		ctx := order.NewContext(context.Background(), &order.Identity{Subject: "merchant-a", Roles: []string{order.RoleMerchant}})
+		res, err := orderUsecase.CancelByID(ctx, "5c2b2aaf4530558539f91859")
+		assert := assert.New(t)
+		assert.Nil(res)
+		if assert.NotNil(err) {
+			assert.Equal(order.KindNotFound, order.KindOf(err))
+			assert.Equal("order_not_found", order.CodeOf(err))
+		}
+		testObj.AssertNotCalled(t, "UpdateStatusByID", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+		testObj.AssertExpectations(t)
+	})
+
+	t.Run("Return error if order has expired", func(t *testing.T) {
+		testObj := new(MockedOrderRepository)
+		testOrder := models.Order{
+			ID:       "5c2b2aaf4530558539f91859",
+			Distance: 12345,
+			Status:   "EXPIRED",
+		}
+		testObj.On("FetchByID", "5c2b2aaf4530558539f91859").Return(&testOrder, nil)
+
+		orderUsecase := NewOrderUsecase(testObj, testOrders(""), testLogger)
+		_, err := orderUsecase.CancelByID(context.Background(), "5c2b2aaf4530558539f91859")
+		assert := assert.New(t)
+		if assert.NotNil(err) {
+			assert.Equal("order_expired", order.CodeOf(err))
+		}
+		testObj.AssertExpectations(t)
+	})
+
+	t.Run("Return error if order is already cancelled", func(t *testing.T) {
+		testObj := new(MockedOrderRepository)
+		testOrder := models.Order{
+			ID:       "5c2b2aaf4530558539f91859",
+			Distance: 12345,
+			Status:   "CANCELLED",
+		}
+		testObj.On("FetchByID", "5c2b2aaf4530558539f91859").Return(&testOrder, nil)
+
+		orderUsecase := NewOrderUsecase(testObj, testOrders(""), testLogger)
+		_, err := orderUsecase.CancelByID(context.Background(), "5c2b2aaf4530558539f91859")
+		assert := assert.New(t)
+		if assert.NotNil(err) {
+			assert.Equal(order.KindConflict, order.KindOf(err))
+			assert.Equal("order_cancelled", order.CodeOf(err))
+		}
+		testObj.AssertExpectations(t)
+	})
+
+	t.Run("Return error if the status changed concurrently", func(t *testing.T) {
+		testObj := new(MockedOrderRepository)
+		testOrder := models.Order{
+			ID:       "5c2b2aaf4530558539f91859",
+			Distance: 12345,
+			Status:   "UNASSIGNED",
+		}
+		testObj.On("FetchByID", "5c2b2aaf4530558539f91859").Return(&testOrder, nil)
+		testObj.On("UpdateStatusByID", "5c2b2aaf4530558539f91859", "UNASSIGNED", "CANCELLED", mock.Anything).Return(time.Time{}, order.NewConflict("order_status_conflict", "Order is no longer UNASSIGNED"))
+
+		orderUsecase := NewOrderUsecase(testObj, testOrders(""), testLogger)
+		_, err := orderUsecase.CancelByID(context.Background(), "5c2b2aaf4530558539f91859")
+		assert := assert.New(t)
+		if assert.NotNil(err) {
+			assert.Equal("order_status_conflict", order.CodeOf(err))
+		}
+		testObj.AssertExpectations(t)
+	})
+
+}
+
+func TestTransitionByID(t *testing.T) {
+
+	t.Run("Successfully release an order taken by a courier", func(t *testing.T) {
+		testObj := new(MockedOrderRepository)
+		updatedAt := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+		testOrder := models.Order{
+			ID:       "5c2b2aaf4530558539f91859",
+			Distance: 12345,
+			Status:   "TAKEN",
+		}
+		testObj.On("FetchByID", "5c2b2aaf4530558539f91859").Return(&testOrder, nil)
+		testObj.On("UpdateStatusByID", "5c2b2aaf4530558539f91859", "TAKEN", "UNASSIGNED", mock.MatchedBy(func(e models.OrderEvent) bool {
+			return e.Type == "order.status_changed" && e.Data.Status == "UNASSIGNED" && e.Data.PreviousStatus == "TAKEN"
+		})).Return(updatedAt, nil)
+
+		orderUsecase := NewOrderUsecase(testObj, testOrders(""), testLogger)
+		res, err := orderUsecase.TransitionByID(context.Background(), "5c2b2aaf4530558539f91859", "UNASSIGNED")
+		assert := assert.New(t)
+		assert.Nil(err)
+		if assert.NotNil(res) {
+			assert.Equal("UNASSIGNED", res.Status)
+			assert.Equal(updatedAt, res.UpdatedAt)
+		}
+		testObj.AssertExpectations(t)
+	})
+
+	t.Run("Record a forced assignment as an assignment", func(t *testing.T) {
+		testObj := new(MockedOrderRepository)
+		testOrder := models.Order{
+			ID:       "5c2b2aaf4530558539f91859",
+			Distance: 12345,
+			Status:   "UNASSIGNED",
+		}
+		testObj.On("FetchByID", "5c2b2aaf4530558539f91859").Return(&testOrder, nil)
+		testObj.On("UpdateStatusByID", "5c2b2aaf4530558539f91859", "UNASSIGNED", "TAKEN", mock.MatchedBy(func(e models.OrderEvent) bool {
+			return e.Type == "order.assigned" && e.Data.Status == "TAKEN"
+		})).Return(time.Now(), nil)
+
+		orderUsecase := NewOrderUsecase(testObj, testOrders(""), testLogger)
+		_, err := orderUsecase.TransitionByID(context.Background(), "5c2b2aaf4530558539f91859", "TAKEN")
+		assert := assert.New(t)
+		assert.Nil(err)
+		testObj.AssertExpectations(t)
+	})
+
+	t.Run("Return error when the transition is not allowed", func(t *testing.T) {
+		testObj := new(MockedOrderRepository)
+		testOrder := models.Order{
+			ID:       "5c2b2aaf4530558539f91859",
+			Distance: 12345,
+			Status:   "EXPIRED",
+		}
+		testObj.On("FetchByID", "5c2b2aaf4530558539f91859").Return(&testOrder, nil)
+
+		orderUsecase := NewOrderUsecase(testObj, testOrders(""), testLogger)
+		_, err := orderUsecase.TransitionByID(context.Background(), "5c2b2aaf4530558539f91859", "UNASSIGNED")
+		assert := assert.New(t)
+		if assert.NotNil(err) {
+			assert.Equal(order.KindConflict, order.KindOf(err))
+			assert.Equal("invalid_transition", order.CodeOf(err))
+		}
+		testObj.AssertExpectations(t)
+	})
+
+	t.Run("Return error when the status is unknown", func(t *testing.T) {
+		testObj := new(MockedOrderRepository)
+
+		orderUsecase := NewOrderUsecase(testObj, testOrders(""), testLogger)
+		_, err := orderUsecase.TransitionByID(context.Background(), "5c2b2aaf4530558539f91859", "DELIVERED")
+		assert := assert.New(t)
+		if assert.NotNil(err) {
+			assert.Equal("invalid_status", order.CodeOf(err))
+		}
+		testObj.AssertExpectations(t)
+	})
+
+	t.Run("Return error when an end user forces an order", func(t *testing.T) {
+		testObj := new(MockedOrderRepository)
+		courier := &order.Identity{Subject: "c1", Roles: []string{order.RoleCourier}}
+
+		orderUsecase := NewOrderUsecase(testObj, testOrders(""), testLogger)
+		_, err := orderUsecase.TransitionByID(order.NewContext(context.Background(), courier), "5c2b2aaf4530558539f91859", "UNASSIGNED")
+		assert := assert.New(t)
+		if assert.NotNil(err) {
+			assert.Equal("forbidden_role", order.CodeOf(err))
+		}
+		testObj.AssertExpectations(t)
+	})
+
+}
+
 func TestStore(t *testing.T) {
 
+	t.Run("Return error when an end user who is not a merchant places an order", func(t *testing.T) {
+		testObj := new(MockedOrderRepository)
+		courier := &order.Identity{Subject: "c1", Roles: []string{order.RoleCourier}}
+
+		orderUsecase := NewOrderUsecase(testObj, testOrders(""), testLogger)
+		orderReq := models.OrderRequest{Origin: []string{"1", "2"}, Destination: []string{"3", "4"}}
+		_, err := orderUsecase.Store(order.NewContext(context.Background(), courier), &orderReq)
+		assert := assert.New(t)
+		assert.Equal(order.KindPermissionDenied, order.KindOf(err))
+		testObj.AssertExpectations(t)
+	})
+
 	t.Run("Successfully save order", func(t *testing.T) {
 		response := `{
 			"destination_addresses" : [
@@ -237,8 +665,6 @@ func TestStore(t *testing.T) {
 		}`
 		server := mockServer(200, response)
 		fmt.Println("server ", server.URL)
-		os.Setenv("GOOGLE_SERVER_URL", server.URL)
-		os.Setenv("GOOGLE_API_KEY", apiKey)
 		defer server.Close()
 
 		testObj := new(MockedOrderRepository)
@@ -251,14 +677,16 @@ func TestStore(t *testing.T) {
 			Distance: 30539,
 			Status:   "UNASSIGNED",
 		}
-		testObj.On("Store", &testOrder).Return(&testOrderResponse, nil)
+		testObj.On("Store", &testOrder, mock.MatchedBy(func(e models.OrderEvent) bool {
+			return e.Type == "order.created" && e.Version == 1 && e.Data.Status == "UNASSIGNED" && e.Data.Distance == 30539
+		})).Return(&testOrderResponse, nil)
 
-		orderUsecase := NewOrderUsecase(testObj)
+		orderUsecase := NewOrderUsecase(testObj, testOrders(server.URL), testLogger)
 		orderReq := models.OrderRequest{
 			Origin:      []string{"1", "2"},
 			Destination: []string{"3", "4"},
 		}
-		resp, err := orderUsecase.Store(&orderReq)
+		resp, err := orderUsecase.Store(context.Background(), &orderReq)
 		assert := assert.New(t)
 		assert.Nil(err)
 		if assert.NotNil(resp) {
@@ -269,6 +697,32 @@ func TestStore(t *testing.T) {
 		testObj.AssertExpectations(t)
 	})
 
+	t.Run("Trace the distance lookup within the operation", func(t *testing.T) {
+		recorder := tracetest.NewSpanRecorder()
+		previous := otel.GetTracerProvider()
+		otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+		defer otel.SetTracerProvider(previous)
+		server := mockServer(200, `{"origin_addresses":["a"],"destination_addresses":["b"],"rows":[{"elements":[{"distance":{"text":"30.5 km","value":30539},"duration":{"text":"1 min","value":60},"status":"OK"}]}],"status":"OK"}`)
+		defer server.Close()
+
+		testObj := new(MockedOrderRepository)
+		testObj.On("Store", mock.Anything, mock.Anything).Return(&models.Order{ID: "5c2b2aaf4530558539f91858", Distance: 30539, Status: "UNASSIGNED"}, nil)
+
+		orderUsecase := NewOrderUsecase(testObj, testOrders(server.URL), testLogger)
+		_, err := orderUsecase.Store(context.Background(), &models.OrderRequest{Origin: []string{"1", "2"}, Destination: []string{"3", "4"}})
+		assert := assert.New(t)
+		assert.Nil(err)
+		spans := recorder.Ended()
+		if assert.Len(spans, 2) {
+			distance, operation := spans[0], spans[1]
+			assert.Equal("google.distance_matrix", distance.Name())
+			assert.Equal("OrderUsecase.Store", operation.Name())
+			assert.Equal(operation.SpanContext().SpanID(), distance.Parent().SpanID())
+			assert.Contains(operation.Attributes(), attribute.String("order.id", bson.ObjectId("5c2b2aaf4530558539f91858").Hex()))
+		}
+		testObj.AssertExpectations(t)
+	})
+
 	t.Run("Return error when origin coordinates in wrong format", func(t *testing.T) {
 		response := `{
 			"destination_addresses" : [
@@ -302,18 +756,16 @@ func TestStore(t *testing.T) {
 		}`
 		server := mockServer(200, response)
 		fmt.Println("server ", server.URL)
-		os.Setenv("GOOGLE_SERVER_URL", server.URL)
-		os.Setenv("GOOGLE_API_KEY", apiKey)
 		defer server.Close()
 
 		testObj := new(MockedOrderRepository)
 
-		orderUsecase := NewOrderUsecase(testObj)
+		orderUsecase := NewOrderUsecase(testObj, testOrders(server.URL), testLogger)
 		orderReq := models.OrderRequest{
 			Origin:      []string{"1"},
 			Destination: []string{"3", "4"},
 		}
-		_, err := orderUsecase.Store(&orderReq)
+		_, err := orderUsecase.Store(context.Background(), &orderReq)
 		assert := assert.New(t)
 		if assert.NotNil(err) {
 			assert.Equal("Unable to fetch distance from Google APIs. Please ensure data is in correct format", err.Error())
@@ -342,21 +794,20 @@ func TestStore(t *testing.T) {
 		}`
 		server := mockServer(200, response)
 		fmt.Println("server ", server.URL)
-		os.Setenv("GOOGLE_SERVER_URL", server.URL)
-		os.Setenv("GOOGLE_API_KEY", apiKey)
 		defer server.Close()
 
 		testObj := new(MockedOrderRepository)
 
-		orderUsecase := NewOrderUsecase(testObj)
+		orderUsecase := NewOrderUsecase(testObj, testOrders(server.URL), testLogger)
 		orderReq := models.OrderRequest{
 			Origin:      []string{"1", "2"},
 			Destination: []string{"3", "4"},
 		}
-		_, err := orderUsecase.Store(&orderReq)
+		_, err := orderUsecase.Store(context.Background(), &orderReq)
 		assert := assert.New(t)
 		if assert.NotNil(err) {
 			assert.Equal("Unable to fetch distance from Google APIs, Status : ZERO_RESULTS", err.Error())
+			assert.Equal(order.KindInvalidArgument, order.KindOf(err))
 		}
 		testObj.AssertExpectations(t)
 	})
@@ -394,8 +845,6 @@ func TestStore(t *testing.T) {
 		}`
 		server := mockServer(200, response)
 		fmt.Println("server ", server.URL)
-		os.Setenv("GOOGLE_SERVER_URL", server.URL)
-		os.Setenv("GOOGLE_API_KEY", apiKey)
 		defer server.Close()
 
 		testObj := new(MockedOrderRepository)
@@ -403,20 +852,66 @@ func TestStore(t *testing.T) {
 			Distance: 30539,
 			Status:   "UNASSIGNED",
 		}
-		testObj.On("Store", &testOrder).Return(&models.Order{}, errors.New("connection lost"))
+		testObj.On("Store", &testOrder, mock.Anything).Return(&models.Order{}, errors.New("connection lost"))
 
-		orderUsecase := NewOrderUsecase(testObj)
+		orderUsecase := NewOrderUsecase(testObj, testOrders(server.URL), testLogger)
 		orderReq := models.OrderRequest{
 			Origin:      []string{"1", "2"},
 			Destination: []string{"3", "4"},
 		}
-		_, err := orderUsecase.Store(&orderReq)
+		_, err := orderUsecase.Store(context.Background(), &orderReq)
 		assert := assert.New(t)
 		if assert.NotNil(err) {
 			assert.Equal("connection lost", err.Error())
 		}
 		testObj.AssertExpectations(t)
 	})
+
+	t.Run("Abort the distance lookup when the request is cancelled", func(t *testing.T) {
+		server := mockBlockingServer()
+		defer server.s.Close()
+
+		testObj := new(MockedOrderRepository)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		go func() {
+			<-server.received
+			cancel()
+		}()
+		orderUsecase := NewOrderUsecase(testObj, testOrders(server.s.URL), testLogger)
+		_, err := orderUsecase.Store(ctx, &models.OrderRequest{Origin: []string{"1", "2"}, Destination: []string{"3", "4"}})
+		assert := assert.New(t)
+		if assert.NotNil(err) {
+			assert.Equal("request_cancelled", order.CodeOf(err))
+			assert.ErrorIs(err, context.Canceled)
+		}
+		select {
+		case <-server.aborted:
+		case <-time.After(5 * time.Second):
+			t.Error("the distance lookup was not aborted")
+		}
+		testObj.AssertNotCalled(t, "Store", mock.Anything, mock.Anything)
+		testObj.AssertExpectations(t)
+	})
+
+	t.Run("Return error when the request runs out of time", func(t *testing.T) {
+		server := mockBlockingServer()
+		defer server.s.Close()
+
+		testObj := new(MockedOrderRepository)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		orderUsecase := NewOrderUsecase(testObj, testOrders(server.s.URL), testLogger)
+		_, err := orderUsecase.Store(ctx, &models.OrderRequest{Origin: []string{"1", "2"}, Destination: []string{"3", "4"}})
+		assert := assert.New(t)
+		if assert.NotNil(err) {
+			assert.Equal("deadline_exceeded", order.CodeOf(err))
+			assert.Equal(order.KindUnavailable, order.KindOf(err))
+		}
+		testObj.AssertNotCalled(t, "Store", mock.Anything, mock.Anything)
+		testObj.AssertExpectations(t)
+	})
 }
 
 const apiKey = "AIzaNotReallyAnAPIKey"
@@ -441,3 +936,23 @@ func mockServerForQuery(query string, code int, body string) *countingServer {
 	}))
 	return server
 }
+
+//blockingServer answers no request, holding each one until the client gives up on it
+type blockingServer struct {
+	s        *httptest.Server
+	received chan struct{}
+	aborted  chan struct{}
+}
+
+func mockBlockingServer() *blockingServer {
+	server := &blockingServer{received: make(chan struct{}, 1), aborted: make(chan struct{}, 1)}
+	server.s = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		server.received <- struct{}{}
+		select {
+		case <-r.Context().Done():
+			server.aborted <- struct{}{}
+		case <-time.After(5 * time.Second):
+		}
+	}))
+	return server
+}
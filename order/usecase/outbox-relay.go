@@ -0,0 +1,144 @@
+package usecase
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/karanbhomiagit/order-service/config"
+	"github.com/karanbhomiagit/order-service/models"
+	"github.com/karanbhomiagit/order-service/order"
+)
+
+const (
+	RelayLeaseName   = "outbox-relay"
+	RelayBaseBackoff = time.Second
+	RelayMaxBackoff  = 5 * time.Minute
+	//Transactions over for longer than this are pruned
+	RelayTxnRetention = time.Hour
+)
+
+//OutboxRelay drains the outbox to the event publisher. Every event is published at least once:
+//failed attempts are retried with exponential backoff until the entry is dead-lettered.
+type OutboxRelay struct {
+	outboxRepository order.OutboxRepository
+	leaseRepository  order.LeaseRepository
+	eventPublisher   order.EventPublisher
+	owner            string
+	interval         time.Duration
+	batchSize        int
+	maxAttempts      int
+	logger           *slog.Logger
+}
+
+func NewOutboxRelay(outr order.OutboxRepository, lr order.LeaseRepository, ep order.EventPublisher, cfg config.Outbox, logger *slog.Logger) *OutboxRelay {
+	return &OutboxRelay{
+		outboxRepository: outr,
+		leaseRepository:  lr,
+		eventPublisher:   ep,
+		owner:            workerOwner(),
+		interval:         cfg.OutboxRelayInterval,
+		batchSize:        cfg.OutboxBatchSize,
+		maxAttempts:      cfg.OutboxMaxAttempts,
+		logger:           logger,
+	}
+}
+
+//Run relays the outbox and prunes the transactions which are over every interval until the context is cancelled
+func (r *OutboxRelay) Run(ctx context.Context) {
+	//Complete any transaction a crashed process left half applied, so its entry is relayed
+	if err := r.outboxRepository.ResumeTransactions(); err != nil {
+		r.logger.Error("Unable to resume the transactions", "error", err)
+	}
+	runEvery(ctx, r.interval, func() {
+		now := time.Now()
+		if _, err := r.Relay(now); err != nil {
+			r.logger.Error("Unable to relay the outbox", "error", err)
+		}
+		if _, err := r.Prune(now); err != nil {
+			r.logger.Error("Unable to prune the transactions", "error", err)
+		}
+	})
+	//Let another replica take over straight away
+	r.leaseRepository.Release(RelayLeaseName, r.owner)
+}
+
+//Relay publishes every entry which is due, in batches. Only the replica holding the relay lease
+//does any work; it returns the number of events published. An entry which is backed off does not
+//hold back later events of the same order, so consumers must not rely on the publishing order.
+func (r *OutboxRelay) Relay(now time.Time) (int, error) {
+	published := 0
+	for {
+		//The lease is renewed before every batch so it does not run out while a backlog drains,
+		//and the work stops as soon as another replica holds it
+		acquired, err := r.leaseRepository.Acquire(RelayLeaseName, r.owner, 2*r.interval)
+		if err != nil || !acquired {
+			return published, err
+		}
+		//Call repository function to fetch the next batch of due entries
+		entries, err := r.outboxRepository.FetchPending(now, r.batchSize)
+		if err != nil {
+			return published, err
+		}
+		for i := range entries {
+			entry := &entries[i]
+			if err := r.eventPublisher.Publish(entry.Event); err != nil {
+				//Failed entries are no longer due, so they are not fetched again in this run
+				if err := r.outboxRepository.UpdateByID(failedAttempt(entry, now, err, r.maxAttempts)); err != nil {
+					return published, err
+				}
+				continue
+			}
+			published++
+			//If this fails the entry stays pending and is published again, which is allowed
+			if err := r.outboxRepository.RemoveByID(entry.ID.Hex()); err != nil {
+				return published, err
+			}
+		}
+		if len(entries) == 0 || len(entries) < r.batchSize {
+			return published, nil
+		}
+	}
+}
+
+//Prune removes the transactions which were over before now minus the retention, in batches. Only the replica
+//holding the relay lease does any work; it returns the number of transactions removed.
+func (r *OutboxRelay) Prune(now time.Time) (int, error) {
+	pruned := 0
+	for {
+		acquired, err := r.leaseRepository.Acquire(RelayLeaseName, r.owner, 2*r.interval)
+		if err != nil || !acquired {
+			return pruned, err
+		}
+		//Call repository function to remove the next batch of transactions
+		n, err := r.outboxRepository.PruneTransactions(now.Add(-RelayTxnRetention), r.batchSize)
+		pruned += n
+		if err != nil || n < r.batchSize {
+			return pruned, err
+		}
+	}
+}
+
+//failedAttempt records the failure on the entry and schedules its next attempt, or dead-letters it
+func failedAttempt(entry *models.OutboxEntry, now time.Time, err error, maxAttempts int) *models.OutboxEntry {
+	entry.Attempts++
+	entry.LastError = err.Error()
+	if entry.Attempts >= maxAttempts {
+		entry.Status = models.OutboxDead
+		return entry
+	}
+	entry.NextAttemptAt = now.Add(backoff(entry.Attempts))
+	return entry
+}
+
+//backoff doubles the wait after every attempt, up to the maximum
+func backoff(attempts int) time.Duration {
+	wait := RelayBaseBackoff
+	for i := 1; i < attempts; i++ {
+		wait *= 2
+		if wait >= RelayMaxBackoff {
+			return RelayMaxBackoff
+		}
+	}
+	return wait
+}
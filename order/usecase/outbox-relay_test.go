@@ -0,0 +1,228 @@
+package usecase
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/karanbhomiagit/order-service/config"
+	"github.com/karanbhomiagit/order-service/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"gopkg.in/mgo.v2/bson"
+)
+
+type MockedOutboxRepository struct {
+	mock.Mock
+}
+
+func (outr *MockedOutboxRepository) FetchPending(now time.Time, limit int) ([]models.OutboxEntry, error) {
+	args := outr.Called(now, limit)
+	return args.Get(0).([]models.OutboxEntry), args.Error(1)
+}
+
+func (outr *MockedOutboxRepository) UpdateByID(entry *models.OutboxEntry) error {
+	args := outr.Called(entry)
+	return args.Error(0)
+}
+
+func (outr *MockedOutboxRepository) RemoveByID(id string) error {
+	args := outr.Called(id)
+	return args.Error(0)
+}
+
+func (outr *MockedOutboxRepository) ResumeTransactions() error {
+	args := outr.Called()
+	return args.Error(0)
+}
+
+func (outr *MockedOutboxRepository) PruneTransactions(before time.Time, limit int) (int, error) {
+	args := outr.Called(before, limit)
+	return args.Int(0), args.Error(1)
+}
+
+type MockedEventPublisher struct {
+	mock.Mock
+}
+
+func (ep *MockedEventPublisher) Publish(event models.OrderEvent) error {
+	args := ep.Called(event)
+	return args.Error(0)
+}
+
+func testOutboxEntry(id string, attempts int) models.OutboxEntry {
+	return models.OutboxEntry{
+		ID: bson.ObjectIdHex(id),
+		Event: models.OrderEvent{
+			ID:   "event-" + id,
+			Type: models.EventOrderCreated,
+		},
+		Status:   models.OutboxPending,
+		Attempts: attempts,
+	}
+}
+
+/*
+	Actual test functions
+*/
+
+func TestRelay(t *testing.T) {
+
+	now := time.Date(2019, 1, 2, 12, 0, 0, 0, time.UTC)
+
+	t.Run("Successfully publish and remove due entries", func(t *testing.T) {
+		outboxObj := new(MockedOutboxRepository)
+		leaseObj := new(MockedLeaseRepository)
+		pubObj := new(MockedEventPublisher)
+		leaseObj.On("Acquire", RelayLeaseName, mock.Anything, 2*time.Second).Return(true, nil)
+		entry1 := testOutboxEntry("5c2b2aaf4530558539f91859", 0)
+		entry2 := testOutboxEntry("5c2b2aaf4530558539f91858", 0)
+		outboxObj.On("FetchPending", now, 10).Return([]models.OutboxEntry{entry1, entry2}, nil)
+		pubObj.On("Publish", entry1.Event).Return(nil)
+		pubObj.On("Publish", entry2.Event).Return(nil)
+		outboxObj.On("RemoveByID", "5c2b2aaf4530558539f91859").Return(nil)
+		outboxObj.On("RemoveByID", "5c2b2aaf4530558539f91858").Return(nil)
+
+		relay := NewOutboxRelay(outboxObj, leaseObj, pubObj, config.Outbox{OutboxRelayInterval: time.Second, OutboxBatchSize: 10, OutboxMaxAttempts: 3}, testLogger)
+		published, err := relay.Relay(now)
+		assert := assert.New(t)
+		assert.Nil(err)
+		assert.Equal(2, published)
+		outboxObj.AssertExpectations(t)
+		pubObj.AssertExpectations(t)
+	})
+
+	t.Run("Schedule a retry with backoff when publishing fails", func(t *testing.T) {
+		outboxObj := new(MockedOutboxRepository)
+		leaseObj := new(MockedLeaseRepository)
+		pubObj := new(MockedEventPublisher)
+		leaseObj.On("Acquire", RelayLeaseName, mock.Anything, 2*time.Second).Return(true, nil)
+		entry := testOutboxEntry("5c2b2aaf4530558539f91859", 2)
+		outboxObj.On("FetchPending", now, 10).Return([]models.OutboxEntry{entry}, nil)
+		pubObj.On("Publish", entry.Event).Return(errors.New("broker unavailable"))
+		outboxObj.On("UpdateByID", mock.MatchedBy(func(e *models.OutboxEntry) bool {
+			return e.Status == models.OutboxPending && e.Attempts == 3 &&
+				e.NextAttemptAt.Equal(now.Add(4*time.Second)) && e.LastError == "broker unavailable"
+		})).Return(nil)
+
+		relay := NewOutboxRelay(outboxObj, leaseObj, pubObj, config.Outbox{OutboxRelayInterval: time.Second, OutboxBatchSize: 10, OutboxMaxAttempts: 5}, testLogger)
+		published, err := relay.Relay(now)
+		assert := assert.New(t)
+		assert.Nil(err)
+		assert.Equal(0, published)
+		outboxObj.AssertExpectations(t)
+		pubObj.AssertExpectations(t)
+	})
+
+	t.Run("Dead-letter an entry after the last attempt fails", func(t *testing.T) {
+		outboxObj := new(MockedOutboxRepository)
+		leaseObj := new(MockedLeaseRepository)
+		pubObj := new(MockedEventPublisher)
+		leaseObj.On("Acquire", RelayLeaseName, mock.Anything, 2*time.Second).Return(true, nil)
+		entry := testOutboxEntry("5c2b2aaf4530558539f91859", 4)
+		outboxObj.On("FetchPending", now, 10).Return([]models.OutboxEntry{entry}, nil)
+		pubObj.On("Publish", entry.Event).Return(errors.New("broker unavailable"))
+		outboxObj.On("UpdateByID", mock.MatchedBy(func(e *models.OutboxEntry) bool {
+			return e.Status == models.OutboxDead && e.Attempts == 5
+		})).Return(nil)
+
+		relay := NewOutboxRelay(outboxObj, leaseObj, pubObj, config.Outbox{OutboxRelayInterval: time.Second, OutboxBatchSize: 10, OutboxMaxAttempts: 5}, testLogger)
+		_, err := relay.Relay(now)
+		assert.Nil(t, err)
+		outboxObj.AssertExpectations(t)
+	})
+
+	t.Run("Stop between batches once another replica holds the lease", func(t *testing.T) {
+		outboxObj := new(MockedOutboxRepository)
+		leaseObj := new(MockedLeaseRepository)
+		pubObj := new(MockedEventPublisher)
+		leaseObj.On("Acquire", RelayLeaseName, mock.Anything, 2*time.Second).Return(true, nil).Once()
+		leaseObj.On("Acquire", RelayLeaseName, mock.Anything, 2*time.Second).Return(false, nil).Once()
+		entry1 := testOutboxEntry("5c2b2aaf4530558539f91859", 0)
+		entry2 := testOutboxEntry("5c2b2aaf4530558539f91858", 0)
+		outboxObj.On("FetchPending", now, 2).Return([]models.OutboxEntry{entry1, entry2}, nil).Once()
+		pubObj.On("Publish", entry1.Event).Return(nil)
+		pubObj.On("Publish", entry2.Event).Return(nil)
+		outboxObj.On("RemoveByID", "5c2b2aaf4530558539f91859").Return(nil)
+		outboxObj.On("RemoveByID", "5c2b2aaf4530558539f91858").Return(nil)
+
+		relay := NewOutboxRelay(outboxObj, leaseObj, pubObj, config.Outbox{OutboxRelayInterval: time.Second, OutboxBatchSize: 2, OutboxMaxAttempts: 3}, testLogger)
+		published, err := relay.Relay(now)
+		assert := assert.New(t)
+		assert.Nil(err)
+		assert.Equal(2, published)
+		outboxObj.AssertExpectations(t)
+		leaseObj.AssertExpectations(t)
+	})
+
+	t.Run("Do nothing if another replica holds the lease", func(t *testing.T) {
+		outboxObj := new(MockedOutboxRepository)
+		leaseObj := new(MockedLeaseRepository)
+		pubObj := new(MockedEventPublisher)
+		leaseObj.On("Acquire", RelayLeaseName, mock.Anything, 2*time.Second).Return(false, nil)
+
+		relay := NewOutboxRelay(outboxObj, leaseObj, pubObj, config.Outbox{OutboxRelayInterval: time.Second, OutboxBatchSize: 10, OutboxMaxAttempts: 5}, testLogger)
+		published, err := relay.Relay(now)
+		assert := assert.New(t)
+		assert.Nil(err)
+		assert.Equal(0, published)
+		outboxObj.AssertExpectations(t)
+	})
+}
+
+func TestPrune(t *testing.T) {
+
+	now := time.Date(2019, 1, 2, 12, 0, 0, 0, time.UTC)
+
+	t.Run("Successfully prune the transactions over before the retention, a batch at a time", func(t *testing.T) {
+		outboxObj := new(MockedOutboxRepository)
+		leaseObj := new(MockedLeaseRepository)
+		leaseObj.On("Acquire", RelayLeaseName, mock.Anything, 2*time.Second).Return(true, nil)
+		outboxObj.On("PruneTransactions", now.Add(-time.Hour), 10).Return(10, nil).Once()
+		outboxObj.On("PruneTransactions", now.Add(-time.Hour), 10).Return(3, nil).Once()
+
+		relay := NewOutboxRelay(outboxObj, leaseObj, new(MockedEventPublisher), config.Outbox{OutboxRelayInterval: time.Second, OutboxBatchSize: 10, OutboxMaxAttempts: 5}, testLogger)
+		pruned, err := relay.Prune(now)
+		assert := assert.New(t)
+		assert.Nil(err)
+		assert.Equal(13, pruned)
+		outboxObj.AssertExpectations(t)
+	})
+
+	t.Run("Do nothing if another replica holds the lease", func(t *testing.T) {
+		outboxObj := new(MockedOutboxRepository)
+		leaseObj := new(MockedLeaseRepository)
+		leaseObj.On("Acquire", RelayLeaseName, mock.Anything, 2*time.Second).Return(false, nil)
+
+		relay := NewOutboxRelay(outboxObj, leaseObj, new(MockedEventPublisher), config.Outbox{OutboxRelayInterval: time.Second, OutboxBatchSize: 10, OutboxMaxAttempts: 5}, testLogger)
+		pruned, err := relay.Prune(now)
+		assert := assert.New(t)
+		assert.Nil(err)
+		assert.Equal(0, pruned)
+		outboxObj.AssertNotCalled(t, "PruneTransactions", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Return error if pruning fails", func(t *testing.T) {
+		outboxObj := new(MockedOutboxRepository)
+		leaseObj := new(MockedLeaseRepository)
+		leaseObj.On("Acquire", RelayLeaseName, mock.Anything, 2*time.Second).Return(true, nil)
+		outboxObj.On("PruneTransactions", now.Add(-time.Hour), 10).Return(0, errors.New("connection lost"))
+
+		relay := NewOutboxRelay(outboxObj, leaseObj, new(MockedEventPublisher), config.Outbox{OutboxRelayInterval: time.Second, OutboxBatchSize: 10, OutboxMaxAttempts: 5}, testLogger)
+		_, err := relay.Prune(now)
+		if assert.NotNil(t, err) {
+			assert.Equal(t, "connection lost", err.Error())
+		}
+	})
+}
+
+func TestBackoff(t *testing.T) {
+
+	t.Run("Successfully double the wait up to the maximum", func(t *testing.T) {
+		assert := assert.New(t)
+		assert.Equal(time.Second, backoff(1))
+		assert.Equal(2*time.Second, backoff(2))
+		assert.Equal(8*time.Second, backoff(4))
+		assert.Equal(RelayMaxBackoff, backoff(20))
+	})
+}
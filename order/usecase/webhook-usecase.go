@@ -0,0 +1,431 @@
+package usecase
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/karanbhomiagit/order-service/config"
+	"github.com/karanbhomiagit/order-service/models"
+	"github.com/karanbhomiagit/order-service/order"
+	"gopkg.in/mgo.v2/bson"
+)
+
+const (
+	WebhookDeliveryLogSize = 50
+	WebhookTimeout         = 10 * time.Second
+	WebhookLeaseName       = "webhook-deliveries"
+
+	SignatureHeader = "X-Webhook-Signature"
+	TimestampHeader = "X-Webhook-Timestamp"
+	EventHeader     = "X-Webhook-Event"
+	DeliveryHeader  = "X-Webhook-Delivery"
+)
+
+var (
+	errWebhookNotFound = order.NewNotFound("webhook_not_found", "not found")
+	errPrivateAddress  = errors.New("Webhooks are not delivered to private addresses")
+)
+
+type WebhookUsecase struct {
+	webhookRepository order.WebhookRepository
+	leaseRepository   order.LeaseRepository
+	client            *http.Client
+	allowPrivate      bool
+	owner             string
+	interval          time.Duration
+	batchSize         int
+	maxAttempts       int
+	baseBackoff       time.Duration
+	logger            *slog.Logger
+}
+
+func NewWebhookUsecase(wr order.WebhookRepository, lr order.LeaseRepository, cfg config.Webhooks, logger *slog.Logger) order.WebhookUsecase {
+	return &WebhookUsecase{
+		webhookRepository: wr,
+		leaseRepository:   lr,
+		client:            webhookClient(cfg.WebhookAllowPrivate),
+		allowPrivate:      cfg.WebhookAllowPrivate,
+		owner:             workerOwner(),
+		interval:          cfg.WebhookInterval,
+		batchSize:         cfg.WebhookBatchSize,
+		maxAttempts:       cfg.WebhookMaxAttempts,
+		baseBackoff:       cfg.WebhookBackoff,
+		logger:            logger,
+	}
+}
+
+//FetchByID returns a webhook of the caller without its secret
+func (wu *WebhookUsecase) FetchByID(ctx context.Context, id string) (*models.Webhook, error) {
+	webhook, err := wu.fetchOwned(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	(*webhook).Secret = ""
+	return webhook, nil
+}
+
+//FetchAll returns every webhook of the caller without its secret
+func (wu *WebhookUsecase) FetchAll(ctx context.Context) ([]models.Webhook, error) {
+	webhooks, err := wu.webhookRepository.FetchAll()
+	if err != nil {
+		return nil, err
+	}
+	owned := make([]models.Webhook, 0, len(webhooks))
+	for _, webhook := range webhooks {
+		if manages(ctx, &webhook) {
+			webhook.Secret = ""
+			owned = append(owned, webhook)
+		}
+	}
+	return owned, nil
+}
+
+//Store validates the request and stores a new webhook of the caller with a generated signing secret.
+//This is the only time the secret is returned.
+func (wu *WebhookUsecase) Store(ctx context.Context, webhookReq *models.WebhookRequest) (*models.Webhook, error) {
+	if err := wu.validateWebhookRequest(ctx, webhookReq); err != nil {
+		return nil, err
+	}
+	secret, err := newWebhookSecret()
+	if err != nil {
+		return nil, err
+	}
+	webhook := models.Webhook{
+		URL:       webhookReq.URL,
+		Events:    webhookEvents(webhookReq.Events),
+		Secret:    secret,
+		CreatedAt: time.Now().UTC(),
+		Owner:     order.AdminSubject,
+	}
+	if identity, ok := order.IdentityFrom(ctx); ok {
+		webhook.Owner = identity.Subject
+	}
+	return wu.webhookRepository.Store(&webhook)
+}
+
+//UpdateByID changes the URL and event filter of an existing webhook of the caller, keeping its secret
+func (wu *WebhookUsecase) UpdateByID(ctx context.Context, id string, webhookReq *models.WebhookRequest) (*models.Webhook, error) {
+	if err := wu.validateWebhookRequest(ctx, webhookReq); err != nil {
+		return nil, err
+	}
+	webhook, err := wu.fetchOwned(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	(*webhook).URL = webhookReq.URL
+	(*webhook).Events = webhookEvents(webhookReq.Events)
+	if err := wu.webhookRepository.UpdateByID(webhook); err != nil {
+		return nil, err
+	}
+	(*webhook).Secret = ""
+	return webhook, nil
+}
+
+//RemoveByID deletes a webhook of the caller
+func (wu *WebhookUsecase) RemoveByID(ctx context.Context, id string) error {
+	if _, err := wu.fetchOwned(ctx, id); err != nil {
+		return err
+	}
+	return wu.webhookRepository.RemoveByID(id)
+}
+
+//FetchDeliveries returns the latest delivery attempts of an existing webhook of the caller
+func (wu *WebhookUsecase) FetchDeliveries(ctx context.Context, id string) ([]models.WebhookDelivery, error) {
+	if _, err := wu.fetchOwned(ctx, id); err != nil {
+		return nil, err
+	}
+	return wu.webhookRepository.FetchDeliveries(id, WebhookDeliveryLogSize)
+}
+
+//Test sends a sample event to the webhook of the caller once and returns the outcome
+func (wu *WebhookUsecase) Test(ctx context.Context, id string) (*models.WebhookDelivery, error) {
+	webhook, err := wu.fetchOwned(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	event := models.OrderEvent{
+		ID:         bson.NewObjectId().Hex(),
+		Type:       models.EventWebhookTest,
+		Version:    models.EventSchemaVersion,
+		OccurredAt: time.Now().UTC(),
+		Data: models.OrderEventData{
+			OrderID:        bson.NewObjectId().Hex(),
+			Status:         StatusTaken,
+			PreviousStatus: StatusUnassigned,
+			Distance:       12345,
+		},
+	}
+	return wu.attempt(webhook, event, 1), nil
+}
+
+//Dispatch queues the event for every webhook subscribed to it, which is delivered by Run. The event is
+//relayed again if it could not be queued for every webhook.
+func (wu *WebhookUsecase) Dispatch(event models.OrderEvent) error {
+	//The admin's webhooks receive the events of every order, the others those of the orders of their owner
+	webhooks, err := wu.webhookRepository.FetchSubscribed(event.Type, event.Data.Owner)
+	if err != nil {
+		return err
+	}
+	for i := range webhooks {
+		delivery := &models.PendingDelivery{
+			ID:            webhooks[i].ID.Hex() + "-" + event.ID,
+			WebhookID:     webhooks[i].ID,
+			Event:         event,
+			NextAttemptAt: time.Now().UTC(),
+		}
+		if err := wu.webhookRepository.StorePending(delivery); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//Run delivers the queued events every interval until the context is cancelled. The deliveries in flight
+//are completed, the others are left queued for the next run, possibly of another replica.
+func (wu *WebhookUsecase) Run(ctx context.Context) {
+	runEvery(ctx, wu.interval, func() {
+		delivered, err := wu.Deliver(ctx, time.Now())
+		if err != nil {
+			wu.logger.Error("Unable to deliver the webhooks", "error", err)
+		}
+		if delivered > 0 {
+			wu.logger.Info("Webhooks delivered", "count", delivered)
+		}
+	})
+	//Let another replica take over straight away
+	wu.leaseRepository.Release(WebhookLeaseName, wu.owner)
+}
+
+//Deliver attempts every queued delivery which is due, a batch at a time. Only the replica holding the webhook
+//lease does any work; it returns the number of events delivered.
+func (wu *WebhookUsecase) Deliver(ctx context.Context, now time.Time) (int, error) {
+	delivered := 0
+	for ctx.Err() == nil {
+		//The lease is renewed before every batch and outlives a batch whose attempts all time out,
+		//and the work stops as soon as another replica holds it
+		acquired, err := wu.leaseRepository.Acquire(WebhookLeaseName, wu.owner, 2*wu.interval+WebhookTimeout)
+		if err != nil || !acquired {
+			return delivered, err
+		}
+		//Call repository function to fetch the next batch of due deliveries
+		deliveries, err := wu.webhookRepository.FetchPending(now, wu.batchSize)
+		if err != nil {
+			return delivered, err
+		}
+		//Slow receivers do not hold up the others
+		results := make([]bool, len(deliveries))
+		errs := make([]error, len(deliveries))
+		var wg sync.WaitGroup
+		for i := range deliveries {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				results[i], errs[i] = wu.deliver(&deliveries[i], now)
+			}()
+		}
+		wg.Wait()
+		for i := range deliveries {
+			if results[i] {
+				delivered++
+			}
+		}
+		if err := errors.Join(errs...); err != nil {
+			return delivered, err
+		}
+		if len(deliveries) < wu.batchSize {
+			break
+		}
+	}
+	return delivered, nil
+}
+
+//deliver attempts the queued delivery, then removes it from the queue once the webhook accepted the event
+//or the last attempt failed, and otherwise doubles the wait before the next attempt
+func (wu *WebhookUsecase) deliver(delivery *models.PendingDelivery, now time.Time) (bool, error) {
+	webhook, err := wu.webhookRepository.FetchByID(delivery.WebhookID.Hex())
+	//The webhook was removed in the meantime
+	if order.KindOf(err) == order.KindNotFound {
+		return false, wu.webhookRepository.RemovePending(delivery.ID)
+	}
+	if err != nil {
+		return false, err
+	}
+	delivery.Attempts++
+	if wu.attempt(webhook, delivery.Event, delivery.Attempts).Success {
+		return true, wu.webhookRepository.RemovePending(delivery.ID)
+	}
+	if delivery.Attempts >= wu.maxAttempts {
+		wu.logger.Warn("Giving up on the delivery", "webhook_id", webhook.ID.Hex(), "event_id", delivery.Event.ID, "attempts", delivery.Attempts)
+		return false, wu.webhookRepository.RemovePending(delivery.ID)
+	}
+	delivery.NextAttemptAt = now.Add(wu.baseBackoff << (delivery.Attempts - 1))
+	return false, wu.webhookRepository.UpdatePending(delivery)
+}
+
+//fetchOwned returns the webhook, unless the caller does not manage it
+func (wu *WebhookUsecase) fetchOwned(ctx context.Context, id string) (*models.Webhook, error) {
+	webhook, err := wu.webhookRepository.FetchByID(id)
+	if err != nil {
+		return nil, err
+	}
+	//Webhooks of other clients are not disclosed
+	if !manages(ctx, webhook) {
+		return nil, errWebhookNotFound
+	}
+	return webhook, nil
+}
+
+//manages reports whether the caller carried by ctx manages the webhook: its owner, the admin, or an
+//internal caller without an identity
+func manages(ctx context.Context, webhook *models.Webhook) bool {
+	identity, ok := order.IdentityFrom(ctx)
+	return !ok || identity.Subject == order.AdminSubject || identity.Subject == webhook.Owner
+}
+
+//attempt makes a single signed delivery and records it in the webhook's delivery log
+func (wu *WebhookUsecase) attempt(webhook *models.Webhook, event models.OrderEvent, attempt int) *models.WebhookDelivery {
+	delivery := &models.WebhookDelivery{
+		WebhookID: webhook.ID,
+		EventID:   event.ID,
+		EventType: event.Type,
+		Attempt:   attempt,
+	}
+	statusCode, err := wu.post(webhook, event)
+	delivery.DeliveredAt = time.Now().UTC()
+	delivery.StatusCode = statusCode
+	if err != nil {
+		delivery.Error = err.Error()
+	} else if statusCode < 200 || statusCode > 299 {
+		delivery.Error = "Unexpected response status " + strconv.Itoa(statusCode)
+	} else {
+		delivery.Success = true
+	}
+	if err := wu.webhookRepository.StoreDelivery(delivery); err != nil {
+		wu.logger.Error("Unable to store the delivery", "webhook_id", webhook.ID.Hex(), "event_id", event.ID, "error", err)
+	}
+	return delivery
+}
+
+//post sends the event as JSON, signed with the webhook's secret
+func (wu *WebhookUsecase) post(webhook *models.Webhook, event models.OrderEvent) (int, error) {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return 0, err
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req, err := http.NewRequest(http.MethodPost, webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	req.Header.Set(SignatureHeader, Sign(webhook.Secret, timestamp, body))
+	req.Header.Set(TimestampHeader, timestamp)
+	req.Header.Set(EventHeader, event.Type)
+	req.Header.Set(DeliveryHeader, event.ID)
+	resp, err := wu.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	resp.Body.Close()
+	return resp.StatusCode, nil
+}
+
+//Sign returns the signature receivers use to verify a delivery: the hex HMAC-SHA256 of
+//the timestamp and body joined by a dot, keyed with the webhook's secret
+func Sign(secret string, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+//validateWebhookRequest checks the url and events of the request. Unless private networks are allowed, the
+//host must only resolve to public addresses, so webhooks cannot reach the internal services.
+func (wu *WebhookUsecase) validateWebhookRequest(ctx context.Context, webhookReq *models.WebhookRequest) error {
+	u, err := url.Parse(webhookReq.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return order.NewInvalidArgument("invalid_url", "Please provide an absolute http or https url")
+	}
+	if !wu.allowPrivate {
+		addrs, err := net.DefaultResolver.LookupIPAddr(ctx, u.Hostname())
+		if err != nil {
+			return order.NewInvalidArgument("invalid_url", "Unable to resolve the host of the url")
+		}
+		for _, addr := range addrs {
+			if !publicIP(addr.IP) {
+				return order.NewInvalidArgument("invalid_url", "Please provide the url of a public host")
+			}
+		}
+	}
+	for _, e := range webhookReq.Events {
+		switch e {
+		case models.EventOrderCreated, models.EventOrderAssigned, models.EventOrderStatusChanged:
+		default:
+			return order.NewInvalidArgument("invalid_event_type", "Unknown event type "+e)
+		}
+	}
+	return nil
+}
+
+//webhookClient returns the client delivering the webhooks. Unless private networks are allowed, the address
+//is checked again when connecting, as the host may resolve to another one than when the webhook was stored.
+func webhookClient(allowPrivate bool) *http.Client {
+	if allowPrivate {
+		return &http.Client{Timeout: WebhookTimeout}
+	}
+	dialer := &net.Dialer{
+		Timeout: WebhookTimeout,
+		Control: func(network string, address string, c syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || !publicIP(ip) {
+				return errPrivateAddress
+			}
+			return nil
+		},
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	//A proxy would connect to the webhook in place of the checked dialer
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return &http.Client{Timeout: WebhookTimeout, Transport: transport}
+}
+
+//publicIP reports whether the address is reachable from the internet, refusing the loopback, private,
+//link-local (such as the metadata endpoints of the cloud providers), multicast and unspecified ones
+func publicIP(ip net.IP) bool {
+	return !ip.IsLoopback() && !ip.IsPrivate() && !ip.IsLinkLocalUnicast() && !ip.IsLinkLocalMulticast() &&
+		!ip.IsInterfaceLocalMulticast() && !ip.IsMulticast() && !ip.IsUnspecified()
+}
+
+//webhookEvents stores an empty filter as an empty list rather than null
+func webhookEvents(events []string) []string {
+	if events == nil {
+		return []string{}
+	}
+	return events
+}
+
+func newWebhookSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
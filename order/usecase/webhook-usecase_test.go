@@ -0,0 +1,491 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/karanbhomiagit/order-service/config"
+	"github.com/karanbhomiagit/order-service/models"
+	"github.com/karanbhomiagit/order-service/order"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"gopkg.in/mgo.v2/bson"
+)
+
+type MockedWebhookRepository struct {
+	mock.Mock
+}
+
+func (wr *MockedWebhookRepository) FetchByID(id string) (*models.Webhook, error) {
+	args := wr.Called(id)
+	return args.Get(0).(*models.Webhook), args.Error(1)
+}
+
+func (wr *MockedWebhookRepository) FetchAll() ([]models.Webhook, error) {
+	args := wr.Called()
+	return args.Get(0).([]models.Webhook), args.Error(1)
+}
+
+func (wr *MockedWebhookRepository) FetchSubscribed(eventType string, owner string) ([]models.Webhook, error) {
+	args := wr.Called(eventType, owner)
+	return args.Get(0).([]models.Webhook), args.Error(1)
+}
+
+func (wr *MockedWebhookRepository) Store(webhook *models.Webhook) (*models.Webhook, error) {
+	args := wr.Called(webhook)
+	return args.Get(0).(*models.Webhook), args.Error(1)
+}
+
+func (wr *MockedWebhookRepository) UpdateByID(webhook *models.Webhook) error {
+	args := wr.Called(webhook)
+	return args.Error(0)
+}
+
+func (wr *MockedWebhookRepository) RemoveByID(id string) error {
+	args := wr.Called(id)
+	return args.Error(0)
+}
+
+func (wr *MockedWebhookRepository) StoreDelivery(delivery *models.WebhookDelivery) error {
+	args := wr.Called(delivery)
+	return args.Error(0)
+}
+
+func (wr *MockedWebhookRepository) FetchDeliveries(id string, limit int) ([]models.WebhookDelivery, error) {
+	args := wr.Called(id, limit)
+	return args.Get(0).([]models.WebhookDelivery), args.Error(1)
+}
+
+func (wr *MockedWebhookRepository) StorePending(delivery *models.PendingDelivery) error {
+	args := wr.Called(delivery)
+	return args.Error(0)
+}
+
+func (wr *MockedWebhookRepository) FetchPending(now time.Time, limit int) ([]models.PendingDelivery, error) {
+	args := wr.Called(now, limit)
+	return args.Get(0).([]models.PendingDelivery), args.Error(1)
+}
+
+func (wr *MockedWebhookRepository) UpdatePending(delivery *models.PendingDelivery) error {
+	args := wr.Called(delivery)
+	return args.Error(0)
+}
+
+func (wr *MockedWebhookRepository) RemovePending(id string) error {
+	args := wr.Called(id)
+	return args.Error(0)
+}
+
+//receivedRequest is what the test receiver captured from a delivery
+type receivedRequest struct {
+	header http.Header
+	body   []byte
+}
+
+//webhookReceiver answers with the given status codes in turn, repeating the last one
+func webhookReceiver(codes ...int) (*httptest.Server, chan receivedRequest) {
+	received := make(chan receivedRequest, 10)
+	var mu sync.Mutex
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		mu.Lock()
+		code := codes[len(codes)-1]
+		if calls < len(codes) {
+			code = codes[calls]
+		}
+		calls++
+		mu.Unlock()
+		received <- receivedRequest{r.Header, body}
+		w.WriteHeader(code)
+	}))
+	return server, received
+}
+
+func testWebhook(url string) *models.Webhook {
+	return &models.Webhook{
+		ID:     bson.ObjectIdHex("5c2b2aaf4530558539f91859"),
+		URL:    url,
+		Events: []string{},
+		Secret: "s3cr3t",
+		Owner:  "merchant-1",
+	}
+}
+
+func testEventForWebhook() models.OrderEvent {
+	return models.OrderEvent{
+		ID:      "5c2b2aaf4530558539f91860",
+		Type:    models.EventOrderAssigned,
+		Version: models.EventSchemaVersion,
+		Data:    models.OrderEventData{OrderID: "5c2b2aaf4530558539f91858", Status: "TAKEN", PreviousStatus: "UNASSIGNED", Owner: "merchant-1"},
+	}
+}
+
+func testPendingDelivery(attempts int) *models.PendingDelivery {
+	return &models.PendingDelivery{
+		ID:        "5c2b2aaf4530558539f91859-5c2b2aaf4530558539f91860",
+		WebhookID: bson.ObjectIdHex("5c2b2aaf4530558539f91859"),
+		Event:     testEventForWebhook(),
+		Attempts:  attempts,
+	}
+}
+
+//testWebhooks deliver to the test receivers, which listen on the loopback address
+var testWebhooks = config.Webhooks{WebhookInterval: time.Second, WebhookBatchSize: 2, WebhookMaxAttempts: 3, WebhookBackoff: time.Millisecond, WebhookAllowPrivate: true}
+
+var publicWebhooks = config.Webhooks{WebhookInterval: time.Second, WebhookBatchSize: 2, WebhookMaxAttempts: 3, WebhookBackoff: time.Millisecond}
+
+//asClient returns a context carrying the identity of the client
+func asClient(subject string) context.Context {
+	return order.NewContext(context.Background(), &order.Identity{Subject: subject, Scopes: []string{order.ScopeWebhooksManage}})
+}
+
+/*
+	Actual test functions
+*/
+
+func TestWebhookStore(t *testing.T) {
+
+	t.Run("Successfully store a webhook of the caller with a generated secret", func(t *testing.T) {
+		testObj := new(MockedWebhookRepository)
+		testObj.On("Store", mock.MatchedBy(func(w *models.Webhook) bool {
+			return w.URL == "https://merchant.example/hooks" && len(w.Secret) == 64 && len(w.Events) == 0 && w.Owner == "merchant-1"
+		})).Return(testWebhook("https://merchant.example/hooks"), nil)
+
+		webhookUsecase := NewWebhookUsecase(testObj, new(MockedLeaseRepository), testWebhooks, testLogger)
+		res, err := webhookUsecase.Store(asClient("merchant-1"), &models.WebhookRequest{URL: "https://merchant.example/hooks"})
+		assert := assert.New(t)
+		assert.Nil(err)
+		if assert.NotNil(res) {
+			assert.Equal("s3cr3t", res.Secret)
+		}
+		testObj.AssertExpectations(t)
+	})
+
+	t.Run("Return error for a relative url", func(t *testing.T) {
+		testObj := new(MockedWebhookRepository)
+		webhookUsecase := NewWebhookUsecase(testObj, new(MockedLeaseRepository), testWebhooks, testLogger)
+		_, err := webhookUsecase.Store(context.Background(), &models.WebhookRequest{URL: "/hooks"})
+		assert := assert.New(t)
+		if assert.NotNil(err) {
+			assert.Equal("Please provide an absolute http or https url", err.Error())
+		}
+		testObj.AssertExpectations(t)
+	})
+
+	t.Run("Return error for a url of a private address", func(t *testing.T) {
+		testObj := new(MockedWebhookRepository)
+		webhookUsecase := NewWebhookUsecase(testObj, new(MockedLeaseRepository), publicWebhooks, testLogger)
+		for _, u := range []string{"http://127.0.0.1:8080/hooks", "http://10.0.0.1/hooks", "http://192.168.1.1/hooks", "http://169.254.169.254/latest/meta-data", "http://[::1]/hooks", "http://0.0.0.0/hooks"} {
+			_, err := webhookUsecase.Store(asClient("merchant-1"), &models.WebhookRequest{URL: u})
+			if assert.NotNil(t, err, u) {
+				assert.Equal(t, "Please provide the url of a public host", err.Error())
+			}
+		}
+		testObj.AssertNotCalled(t, "Store", mock.Anything)
+	})
+
+	t.Run("Successfully store a webhook of a public address", func(t *testing.T) {
+		testObj := new(MockedWebhookRepository)
+		testObj.On("Store", mock.Anything).Return(testWebhook("http://203.0.113.10/hooks"), nil)
+		webhookUsecase := NewWebhookUsecase(testObj, new(MockedLeaseRepository), publicWebhooks, testLogger)
+		_, err := webhookUsecase.Store(asClient("merchant-1"), &models.WebhookRequest{URL: "http://203.0.113.10/hooks"})
+		assert.Nil(t, err)
+		testObj.AssertExpectations(t)
+	})
+
+	t.Run("Return error for an unknown event type", func(t *testing.T) {
+		testObj := new(MockedWebhookRepository)
+		webhookUsecase := NewWebhookUsecase(testObj, new(MockedLeaseRepository), testWebhooks, testLogger)
+		_, err := webhookUsecase.Store(context.Background(), &models.WebhookRequest{URL: "https://merchant.example/hooks", Events: []string{"order.deleted"}})
+		assert := assert.New(t)
+		if assert.NotNil(err) {
+			assert.Equal("Unknown event type order.deleted", err.Error())
+		}
+		testObj.AssertExpectations(t)
+	})
+}
+
+func TestWebhookFetch(t *testing.T) {
+
+	t.Run("Successfully hide secrets when fetching webhooks", func(t *testing.T) {
+		testObj := new(MockedWebhookRepository)
+		testObj.On("FetchAll").Return([]models.Webhook{*testWebhook("https://merchant.example/hooks")}, nil)
+		testObj.On("FetchByID", "5c2b2aaf4530558539f91859").Return(testWebhook("https://merchant.example/hooks"), nil)
+
+		webhookUsecase := NewWebhookUsecase(testObj, new(MockedLeaseRepository), testWebhooks, testLogger)
+		all, err := webhookUsecase.FetchAll(context.Background())
+		assert := assert.New(t)
+		assert.Nil(err)
+		if assert.Equal(1, len(all)) {
+			assert.Equal("", all[0].Secret)
+		}
+		one, err := webhookUsecase.FetchByID(context.Background(), "5c2b2aaf4530558539f91859")
+		assert.Nil(err)
+		assert.Equal("", one.Secret)
+		testObj.AssertExpectations(t)
+	})
+
+	t.Run("Only disclose the webhooks of the caller, unless the admin", func(t *testing.T) {
+		otherWebhook := *testWebhook("https://other.example/hooks")
+		otherWebhook.ID = bson.ObjectIdHex("5c2b2aaf4530558539f91857")
+		otherWebhook.Owner = "merchant-2"
+		testObj := new(MockedWebhookRepository)
+		testObj.On("FetchAll").Return([]models.Webhook{*testWebhook("https://merchant.example/hooks"), otherWebhook}, nil)
+		testObj.On("FetchByID", "5c2b2aaf4530558539f91857").Return(&otherWebhook, nil)
+
+		webhookUsecase := NewWebhookUsecase(testObj, new(MockedLeaseRepository), testWebhooks, testLogger)
+		assert := assert.New(t)
+		all, err := webhookUsecase.FetchAll(asClient("merchant-1"))
+		assert.Nil(err)
+		if assert.Equal(1, len(all)) {
+			assert.Equal("merchant-1", all[0].Owner)
+		}
+		all, err = webhookUsecase.FetchAll(asClient(order.AdminSubject))
+		assert.Nil(err)
+		assert.Equal(2, len(all))
+		_, err = webhookUsecase.FetchByID(asClient("merchant-1"), "5c2b2aaf4530558539f91857")
+		assert.Equal(order.KindNotFound, order.KindOf(err))
+		err = webhookUsecase.RemoveByID(asClient("merchant-1"), "5c2b2aaf4530558539f91857")
+		assert.Equal(order.KindNotFound, order.KindOf(err))
+		testObj.AssertNotCalled(t, "RemoveByID", mock.Anything)
+		testObj.AssertExpectations(t)
+	})
+
+	t.Run("Return error when fetching deliveries of an unknown webhook", func(t *testing.T) {
+		testObj := new(MockedWebhookRepository)
+		testObj.On("FetchByID", "5c2b2aaf4530558539f91859").Return(&models.Webhook{}, errors.New("not found"))
+
+		webhookUsecase := NewWebhookUsecase(testObj, new(MockedLeaseRepository), testWebhooks, testLogger)
+		_, err := webhookUsecase.FetchDeliveries(context.Background(), "5c2b2aaf4530558539f91859")
+		assert := assert.New(t)
+		if assert.NotNil(err) {
+			assert.Equal("not found", err.Error())
+		}
+		testObj.AssertExpectations(t)
+	})
+}
+
+func TestWebhookDispatch(t *testing.T) {
+
+	t.Run("Successfully queue the event for the subscribed webhooks of the owner of the order and of the admin", func(t *testing.T) {
+		subscribedWebhook := *testWebhook("https://merchant.example/hooks")
+		subscribedWebhook.Events = []string{"order.assigned"}
+		adminWebhook := *testWebhook("https://ops.example/hooks")
+		adminWebhook.ID = bson.ObjectIdHex("5c2b2aaf4530558539f91855")
+		adminWebhook.Owner = order.AdminSubject
+		testObj := new(MockedWebhookRepository)
+		testObj.On("FetchSubscribed", "order.assigned", "merchant-1").Return([]models.Webhook{subscribedWebhook, adminWebhook}, nil)
+		testObj.On("StorePending", mock.MatchedBy(func(d *models.PendingDelivery) bool {
+			return d.ID == "5c2b2aaf4530558539f91859-5c2b2aaf4530558539f91860" && d.WebhookID == subscribedWebhook.ID && d.Attempts == 0
+		})).Return(nil).Once()
+		testObj.On("StorePending", mock.MatchedBy(func(d *models.PendingDelivery) bool {
+			return d.WebhookID == adminWebhook.ID
+		})).Return(nil).Once()
+
+		webhookUsecase := NewWebhookUsecase(testObj, new(MockedLeaseRepository), testWebhooks, testLogger)
+		err := webhookUsecase.Dispatch(testEventForWebhook())
+		assert := assert.New(t)
+		assert.Nil(err)
+		testObj.AssertExpectations(t)
+	})
+
+	t.Run("Return error so the event is relayed again when it cannot be queued", func(t *testing.T) {
+		testObj := new(MockedWebhookRepository)
+		testObj.On("FetchSubscribed", "order.assigned", "merchant-1").Return([]models.Webhook{*testWebhook("https://merchant.example/hooks")}, nil)
+		testObj.On("StorePending", mock.Anything).Return(errors.New("connection lost"))
+
+		webhookUsecase := NewWebhookUsecase(testObj, new(MockedLeaseRepository), testWebhooks, testLogger)
+		err := webhookUsecase.Dispatch(testEventForWebhook())
+		assert := assert.New(t)
+		if assert.NotNil(err) {
+			assert.Equal("connection lost", err.Error())
+		}
+		testObj.AssertExpectations(t)
+	})
+}
+
+func TestWebhookDelivery(t *testing.T) {
+	now := time.Date(2019, 1, 2, 12, 0, 0, 0, time.UTC)
+
+	t.Run("Successfully deliver a signed event and remove it from the queue", func(t *testing.T) {
+		server, received := webhookReceiver(http.StatusOK)
+		defer server.Close()
+		testObj := new(MockedWebhookRepository)
+		testObj.On("FetchByID", "5c2b2aaf4530558539f91859").Return(testWebhook(server.URL), nil)
+		testObj.On("StoreDelivery", mock.MatchedBy(func(d *models.WebhookDelivery) bool {
+			return d.Success && d.Attempt == 1 && d.StatusCode == 200 && d.EventID == "5c2b2aaf4530558539f91860"
+		})).Return(nil)
+		testObj.On("RemovePending", "5c2b2aaf4530558539f91859-5c2b2aaf4530558539f91860").Return(nil)
+
+		webhookUsecase := NewWebhookUsecase(testObj, new(MockedLeaseRepository), testWebhooks, testLogger).(*WebhookUsecase)
+		ok, err := webhookUsecase.deliver(testPendingDelivery(0), now)
+		assert := assert.New(t)
+		assert.Nil(err)
+		assert.True(ok)
+		req := <-received
+		assert.Equal("order.assigned", req.header.Get(EventHeader))
+		assert.Equal("5c2b2aaf4530558539f91860", req.header.Get(DeliveryHeader))
+		assert.Equal(Sign("s3cr3t", req.header.Get(TimestampHeader), req.body), req.header.Get(SignatureHeader))
+		assert.Contains(string(req.body), `"type":"order.assigned"`)
+		assert.NotContains(string(req.body), "merchant-1")
+		testObj.AssertExpectations(t)
+	})
+
+	t.Run("Successfully schedule the next attempt, doubling the wait", func(t *testing.T) {
+		server, _ := webhookReceiver(http.StatusBadGateway)
+		defer server.Close()
+		testObj := new(MockedWebhookRepository)
+		testObj.On("FetchByID", "5c2b2aaf4530558539f91859").Return(testWebhook(server.URL), nil)
+		testObj.On("StoreDelivery", mock.MatchedBy(func(d *models.WebhookDelivery) bool {
+			return !d.Success && d.Attempt == 2 && d.Error == "Unexpected response status 502"
+		})).Return(nil)
+		testObj.On("UpdatePending", mock.MatchedBy(func(d *models.PendingDelivery) bool {
+			return d.Attempts == 2 && d.NextAttemptAt.Equal(now.Add(2*time.Millisecond))
+		})).Return(nil)
+
+		webhookUsecase := NewWebhookUsecase(testObj, new(MockedLeaseRepository), testWebhooks, testLogger).(*WebhookUsecase)
+		ok, err := webhookUsecase.deliver(testPendingDelivery(1), now)
+		assert := assert.New(t)
+		assert.Nil(err)
+		assert.False(ok)
+		testObj.AssertExpectations(t)
+	})
+
+	t.Run("Give up after the last attempt", func(t *testing.T) {
+		server, _ := webhookReceiver(http.StatusServiceUnavailable)
+		defer server.Close()
+		testObj := new(MockedWebhookRepository)
+		testObj.On("FetchByID", "5c2b2aaf4530558539f91859").Return(testWebhook(server.URL), nil)
+		testObj.On("StoreDelivery", mock.Anything).Return(nil)
+		testObj.On("RemovePending", "5c2b2aaf4530558539f91859-5c2b2aaf4530558539f91860").Return(nil)
+
+		webhookUsecase := NewWebhookUsecase(testObj, new(MockedLeaseRepository), testWebhooks, testLogger).(*WebhookUsecase)
+		ok, err := webhookUsecase.deliver(testPendingDelivery(2), now)
+		assert := assert.New(t)
+		assert.Nil(err)
+		assert.False(ok)
+		testObj.AssertNotCalled(t, "UpdatePending", mock.Anything)
+		testObj.AssertExpectations(t)
+	})
+
+	t.Run("Drop the deliveries of removed webhooks", func(t *testing.T) {
+		testObj := new(MockedWebhookRepository)
+		testObj.On("FetchByID", "5c2b2aaf4530558539f91859").Return(&models.Webhook{}, order.NewNotFound("webhook_not_found", "not found"))
+		testObj.On("RemovePending", "5c2b2aaf4530558539f91859-5c2b2aaf4530558539f91860").Return(nil)
+
+		webhookUsecase := NewWebhookUsecase(testObj, new(MockedLeaseRepository), testWebhooks, testLogger).(*WebhookUsecase)
+		ok, err := webhookUsecase.deliver(testPendingDelivery(0), now)
+		assert := assert.New(t)
+		assert.Nil(err)
+		assert.False(ok)
+		testObj.AssertExpectations(t)
+	})
+
+	t.Run("Successfully deliver every due event, a batch at a time", func(t *testing.T) {
+		server, received := webhookReceiver(http.StatusOK)
+		defer server.Close()
+		first, second, third := testPendingDelivery(0), testPendingDelivery(0), testPendingDelivery(0)
+		second.ID, third.ID = "second", "third"
+		testObj := new(MockedWebhookRepository)
+		testObj.On("FetchPending", now, 2).Return([]models.PendingDelivery{*first, *second}, nil).Once()
+		testObj.On("FetchPending", now, 2).Return([]models.PendingDelivery{*third}, nil).Once()
+		testObj.On("FetchByID", "5c2b2aaf4530558539f91859").Return(testWebhook(server.URL), nil)
+		testObj.On("StoreDelivery", mock.Anything).Return(nil)
+		testObj.On("RemovePending", mock.Anything).Return(nil).Times(3)
+		leaseObj := new(MockedLeaseRepository)
+		leaseObj.On("Acquire", WebhookLeaseName, mock.Anything, 2*time.Second+WebhookTimeout).Return(true, nil)
+
+		webhookUsecase := NewWebhookUsecase(testObj, leaseObj, testWebhooks, testLogger)
+		delivered, err := webhookUsecase.(*WebhookUsecase).Deliver(context.Background(), now)
+		assert := assert.New(t)
+		assert.Nil(err)
+		assert.Equal(3, delivered)
+		assert.Equal(3, len(received))
+		testObj.AssertExpectations(t)
+		leaseObj.AssertExpectations(t)
+	})
+
+	t.Run("Stop between batches once another replica holds the lease", func(t *testing.T) {
+		server, received := webhookReceiver(http.StatusOK)
+		defer server.Close()
+		first, second := testPendingDelivery(0), testPendingDelivery(0)
+		second.ID = "second"
+		testObj := new(MockedWebhookRepository)
+		testObj.On("FetchPending", now, 2).Return([]models.PendingDelivery{*first, *second}, nil).Once()
+		testObj.On("FetchByID", "5c2b2aaf4530558539f91859").Return(testWebhook(server.URL), nil)
+		testObj.On("StoreDelivery", mock.Anything).Return(nil)
+		testObj.On("RemovePending", mock.Anything).Return(nil).Times(2)
+		leaseObj := new(MockedLeaseRepository)
+		leaseObj.On("Acquire", WebhookLeaseName, mock.Anything, 2*time.Second+WebhookTimeout).Return(true, nil).Once()
+		leaseObj.On("Acquire", WebhookLeaseName, mock.Anything, 2*time.Second+WebhookTimeout).Return(false, nil).Once()
+
+		webhookUsecase := NewWebhookUsecase(testObj, leaseObj, testWebhooks, testLogger)
+		delivered, err := webhookUsecase.(*WebhookUsecase).Deliver(context.Background(), now)
+		assert := assert.New(t)
+		assert.Nil(err)
+		assert.Equal(2, delivered)
+		assert.Equal(2, len(received))
+		testObj.AssertExpectations(t)
+		leaseObj.AssertExpectations(t)
+	})
+
+	t.Run("Do nothing without the lease", func(t *testing.T) {
+		testObj := new(MockedWebhookRepository)
+		leaseObj := new(MockedLeaseRepository)
+		leaseObj.On("Acquire", WebhookLeaseName, mock.Anything, mock.Anything).Return(false, nil)
+
+		webhookUsecase := NewWebhookUsecase(testObj, leaseObj, testWebhooks, testLogger)
+		delivered, err := webhookUsecase.(*WebhookUsecase).Deliver(context.Background(), now)
+		assert := assert.New(t)
+		assert.Nil(err)
+		assert.Equal(0, delivered)
+		testObj.AssertNotCalled(t, "FetchPending", mock.Anything, mock.Anything)
+		leaseObj.AssertExpectations(t)
+	})
+
+	t.Run("Refuse to connect to a private address when delivering", func(t *testing.T) {
+		server, received := webhookReceiver(http.StatusOK)
+		defer server.Close()
+		testObj := new(MockedWebhookRepository)
+		testObj.On("FetchByID", "5c2b2aaf4530558539f91859").Return(testWebhook(server.URL), nil)
+		testObj.On("StoreDelivery", mock.Anything).Return(nil)
+
+		webhookUsecase := NewWebhookUsecase(testObj, new(MockedLeaseRepository), publicWebhooks, testLogger)
+		res, err := webhookUsecase.Test(asClient("merchant-1"), "5c2b2aaf4530558539f91859")
+		assert := assert.New(t)
+		assert.Nil(err)
+		if assert.NotNil(res) {
+			assert.False(res.Success)
+			assert.Contains(res.Error, "Webhooks are not delivered to private addresses")
+		}
+		assert.Equal(0, len(received))
+		testObj.AssertExpectations(t)
+	})
+
+	t.Run("Successfully send a sample event to test a webhook", func(t *testing.T) {
+		server, received := webhookReceiver(http.StatusNoContent)
+		defer server.Close()
+		testObj := new(MockedWebhookRepository)
+		testObj.On("FetchByID", "5c2b2aaf4530558539f91859").Return(testWebhook(server.URL), nil)
+		testObj.On("StoreDelivery", mock.Anything).Return(nil)
+
+		webhookUsecase := NewWebhookUsecase(testObj, new(MockedLeaseRepository), testWebhooks, testLogger)
+		res, err := webhookUsecase.Test(asClient("merchant-1"), "5c2b2aaf4530558539f91859")
+		assert := assert.New(t)
+		assert.Nil(err)
+		if assert.NotNil(res) {
+			assert.True(res.Success)
+			assert.Equal("webhook.test", res.EventType)
+		}
+		req := <-received
+		assert.Equal("webhook.test", req.header.Get(EventHeader))
+		testObj.AssertExpectations(t)
+	})
+}
@@ -0,0 +1,29 @@
+package usecase
+
+import (
+	"context"
+	"os"
+	"time"
+
+	"gopkg.in/mgo.v2/bson"
+)
+
+//runEvery calls fn straight away and then every interval until the context is cancelled
+func runEvery(ctx context.Context, interval time.Duration, fn func()) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		fn()
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+//workerOwner identifies this process when holding leases
+func workerOwner() string {
+	hostname, _ := os.Hostname()
+	return hostname + "-" + bson.NewObjectId().Hex()
+}
@@ -0,0 +1,38 @@
+package order
+
+import (
+	"context"
+	"time"
+
+	"github.com/karanbhomiagit/order-service/models"
+)
+
+// WebhookRepository represents the webhook subscriptions' storage/retrieval as an interface
+type WebhookRepository interface {
+	FetchByID(string) (*models.Webhook, error)
+	FetchAll() ([]models.Webhook, error)
+	FetchSubscribed(string, string) ([]models.Webhook, error)
+	Store(*models.Webhook) (*models.Webhook, error)
+	UpdateByID(*models.Webhook) error
+	RemoveByID(string) error
+	StoreDelivery(*models.WebhookDelivery) error
+	FetchDeliveries(string, int) ([]models.WebhookDelivery, error)
+	StorePending(*models.PendingDelivery) error
+	FetchPending(time.Time, int) ([]models.PendingDelivery, error)
+	UpdatePending(*models.PendingDelivery) error
+	RemovePending(string) error
+}
+
+// WebhookUsecase represents the management and delivery of webhooks as an interface.
+// The context carries the identity of the client managing its webhooks.
+type WebhookUsecase interface {
+	FetchByID(context.Context, string) (*models.Webhook, error)
+	FetchAll(context.Context) ([]models.Webhook, error)
+	Store(context.Context, *models.WebhookRequest) (*models.Webhook, error)
+	UpdateByID(context.Context, string, *models.WebhookRequest) (*models.Webhook, error)
+	RemoveByID(context.Context, string) error
+	FetchDeliveries(context.Context, string) ([]models.WebhookDelivery, error)
+	Test(context.Context, string) (*models.WebhookDelivery, error)
+	Dispatch(models.OrderEvent) error
+	Run(context.Context)
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/karanbhomiagit/order-service/config"
+	cliDeliver "github.com/karanbhomiagit/order-service/order/delivery/cli"
+	orderRepo "github.com/karanbhomiagit/order-service/order/repository"
+	orderUsecase "github.com/karanbhomiagit/order-service/order/usecase"
+)
+
+//orders gets, lists, filters, transitions, cancels and exports orders for the support staff. The events of the
+//changes are recorded in the outbox and published by the running service.
+func orders(cfg *config.Config, args []string, logger *slog.Logger) int {
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+	defer stop()
+	//Report mistakes in the usage at once, rather than once connected to the database
+	if err := cliDeliver.CheckOrderArgs(args, os.Stderr); err != nil {
+		return exitCode(err)
+	}
+	session, ok := connect(ctx, cfg, logger)
+	if !ok {
+		return 1
+	}
+	defer session.Close()
+	or := orderRepo.NewMongoOrderRepository(session)
+	ou := orderUsecase.NewOrderUsecase(or, cfg.Orders, logger)
+	oc := cliDeliver.NewOrderCommand(ou, cfg.PageSize, os.Stdout, os.Stderr, logger)
+
+	err := oc.Run(ctx, args)
+	if err != nil && !errors.Is(err, cliDeliver.ErrUsage) && !errors.Is(err, flag.ErrHelp) {
+		logger.Error("Unable to run the command", "error", err)
+	}
+	return exitCode(err)
+}
+
+//exitCode returns 2 for usage errors and 1 for other errors, like the other commands
+func exitCode(err error) int {
+	switch {
+	case err == nil, errors.Is(err, flag.ErrHelp):
+		return 0
+	case errors.Is(err, cliDeliver.ErrUsage):
+		return 2
+	default:
+		return 1
+	}
+}